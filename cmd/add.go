@@ -0,0 +1,50 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// addCmd represents the add command
+var addCmd = &cobra.Command{
+	Use:   "add <path>...",
+	Short: "stage the contents of files in the index",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		lock, err := lockRepo()
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		idx, err := loadIndex()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		attrs, err := data.ReadAttributes(data.AttributesFile)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		for _, path := range args {
+			if err := idx.Add(ObjDir, path, attrs); err != nil {
+				return err
+			}
+		}
+		if err := idx.Write(data.IndexPath); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(addCmd)
+}
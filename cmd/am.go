@@ -0,0 +1,120 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// amCmd represents the am command
+var amCmd = &cobra.Command{
+	Use:   "am [-3] [<mbox>...]",
+	Short: "apply patches from mailboxes and commit them",
+	Long: `apply the patches in the mailboxes (e.g. written by format-patch) to the working tree in order,
+and commit each of them with the author and the message of its email.
+The mailboxes are read from the files, or from the standard input without any file.
+It stops at the first patch that does not apply, keeping the commits made so far.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		lock, err := lockRepo()
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		mails, err := readMailboxes(cmd.Flags().Args(), cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		opts := data.NewApplyOptions(ObjDir)
+		opts.ThreeWay, _ = cmd.Flags().GetBool("3way")
+		for i, m := range mails {
+			fmt.Printf("Applying: %s\n", m.Subject)
+			if err := applyMail(m, opts); err != nil {
+				return fmt.Errorf("patch failed at %04d %s: %w", i+1, m.Subject, err)
+			}
+		}
+		return nil
+	},
+}
+
+// reads and parses the mailbox files, or the standard input if there is no file
+func readMailboxes(paths []string, stdin io.Reader) ([]*data.Mail, error) {
+	var contents [][]byte
+	if len(paths) == 0 {
+		c, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("readMailboxes: %w", err)
+		}
+		contents = append(contents, c)
+	}
+	for _, path := range paths {
+		c, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("readMailboxes: %w", err)
+		}
+		contents = append(contents, c)
+	}
+	var mails []*data.Mail
+	for _, c := range contents {
+		ms, err := data.ParseMailbox(c)
+		if err != nil {
+			return nil, err
+		}
+		mails = append(mails, ms...)
+	}
+	return mails, nil
+}
+
+// applies the patches of the mail to the working tree, and commits it as the author of the mail.
+// The caller must hold the repository lock.
+func applyMail(m *data.Mail, opts *data.ApplyOptions) error {
+	patches, err := data.ParsePatch([]byte(m.Patch))
+	if err != nil {
+		return err
+	}
+	if len(patches) == 0 {
+		return errors.New("no valid patches in the mail")
+	}
+	results, err := opts.ApplyPatches(patches, readWorkingFile)
+	if err != nil {
+		return err
+	}
+	if err := data.WritePatchResults(".", results); err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	for _, r := range results {
+		if r.Conflict {
+			return fmt.Errorf("%w: resolve the conflicts in %s and commit it", data.ErrPatchConflict, r.Path)
+		}
+	}
+	//with changes staged, the index is what is committed, so the patches have to be recorded in it too
+	idx, err := data.ReadIndex(data.IndexPath)
+	if err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	if idx != nil {
+		if err := writeIndexResults(idx, results); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+	}
+	if _, err := writeCommit(m.Message(), &m.Author, nil); err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(amCmd)
+
+	amCmd.Flags().BoolP("3way", "3", false, "fall back on a three-way merge with the blobs recorded in the patches")
+}
@@ -0,0 +1,176 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply [--check] [--cached] [-R] [-3] [<patch>...]",
+	Short: "apply patches to the working tree or the index",
+	Long: `apply patches to the working tree (or the index with --cached).
+The patches are read from the files, or from the standard input without any file.
+Either all of the patches apply, or nothing is changed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		lock, err := lockRepo()
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		patches, err := readPatches(cmd.Flags().Args(), cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		if reverse, _ := cmd.Flags().GetBool("reverse"); reverse {
+			for _, p := range patches {
+				p.Reverse()
+			}
+		}
+		opts := data.NewApplyOptions(ObjDir)
+		opts.Fuzz, _ = cmd.Flags().GetInt("fuzz")
+		opts.ThreeWay, _ = cmd.Flags().GetBool("3way")
+		cached, _ := cmd.Flags().GetBool("cached")
+
+		var idx data.Index
+		src := readWorkingFile
+		if cached {
+			idx, err = loadIndex()
+			if err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			src = indexSource(idx)
+		}
+		results, err := opts.ApplyPatches(patches, src)
+		if err != nil {
+			return err
+		}
+		if check, _ := cmd.Flags().GetBool("check"); check {
+			return nil
+		}
+
+		conflicts := 0
+		for _, r := range results {
+			if r.Conflict {
+				conflicts++
+				fmt.Printf("Applied patch to '%s' with conflicts.\n", r.Path)
+			}
+		}
+		if cached {
+			if conflicts > 0 {
+				return fmt.Errorf("%w: conflicts cannot be recorded in the index", data.ErrPatchConflict)
+			}
+			if err := writeIndexResults(idx, results); err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			return nil
+		}
+		if err := data.WritePatchResults(".", results); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		if conflicts > 0 {
+			return data.ErrPatchConflict
+		}
+		return nil
+	},
+}
+
+// reads and parses the patch files, or the standard input if there is no file
+func readPatches(paths []string, stdin io.Reader) ([]*data.FilePatch, error) {
+	var contents [][]byte
+	if len(paths) == 0 {
+		c, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("readPatches: %w", err)
+		}
+		contents = append(contents, c)
+	}
+	for _, path := range paths {
+		c, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("readPatches: %w", err)
+		}
+		contents = append(contents, c)
+	}
+	var patches []*data.FilePatch
+	for _, c := range contents {
+		ps, err := data.ParsePatch(c)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, ps...)
+	}
+	if len(patches) == 0 {
+		return nil, errors.New("no valid patches in input")
+	}
+	return patches, nil
+}
+
+// reads a file in the working tree
+func readWorkingFile(path string) ([]byte, bool, error) {
+	c, err := os.ReadFile(filepath.FromSlash(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return c, true, nil
+}
+
+// reads the blobs of the paths in the index
+func indexSource(idx data.Index) data.PatchSource {
+	return func(path string) ([]byte, bool, error) {
+		oid, ok := idx[path]
+		if !ok {
+			return nil, false, nil
+		}
+		c, err := data.ReadAllFileContent(filepath.Join(ObjDir, oid))
+		if err != nil {
+			return nil, false, err
+		}
+		return c, true, nil
+	}
+}
+
+// saves the results as blobs and records them in the index
+func writeIndexResults(idx data.Index, results []*data.PatchResult) error {
+	for _, r := range results {
+		if r.Deleted {
+			delete(idx, r.Path)
+			continue
+		}
+		oid, err := data.SaveBlobObj(ObjDir, r.Content)
+		if err != nil {
+			return fmt.Errorf("writeIndexResults: %w", err)
+		}
+		idx[r.Path] = oid
+	}
+	if err := idx.Write(data.IndexPath); err != nil {
+		return fmt.Errorf("writeIndexResults: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().Bool("check", false, "only check if the patches apply, without changing anything")
+	applyCmd.Flags().Bool("cached", false, "apply the patches to the index instead of the working tree")
+	applyCmd.Flags().BoolP("reverse", "R", false, "apply the patches in reverse")
+	applyCmd.Flags().BoolP("3way", "3", false, "fall back on a three-way merge with the blobs recorded in the patches")
+	applyCmd.Flags().Int("fuzz", data.DefaultFuzz, "the number of context lines at each end of a hunk that may be ignored")
+}
@@ -0,0 +1,434 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// the exit code of a script for bisect run telling that the commit cannot be tested
+const bisectRunSkipCode = 125
+
+// bisectCmd represents the bisect command
+var bisectCmd = &cobra.Command{
+	Use:   "bisect",
+	Short: "find the commit that introduced a regression by binary search",
+	Long: `find the first bad commit between a bad commit and good ones by checking out the commits in between,
+halving the commits left to test at each step.
+  pgit bisect start [<bad> [<good>...]]   start a bisection
+  pgit bisect bad|good [<revision>...]     mark the revisions (default: HEAD) as bad or good
+  pgit bisect skip [<revision>...]         leave the revisions out, when they cannot be tested
+  pgit bisect run <command> [<arg>...]     mark the commits by the exit code of the command
+  pgit bisect log                          print the marks so far
+  pgit bisect replay <file>                mark the commits again following a log
+  pgit bisect reset                        finish the bisection, getting back to the first HEAD`,
+}
+
+// bisectStartCmd represents the bisect start command
+var bisectStartCmd = &cobra.Command{
+	Use:   "start [<bad> [<good>...]]",
+	Short: "start a bisection, optionally with a bad commit and good ones",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		err := bisectStart(&buf, cmd.Flags().Args())
+		fmt.Print(buf.String())
+		return err
+	},
+}
+
+// bisectBadCmd represents the bisect bad command
+var bisectBadCmd = &cobra.Command{
+	Use:   "bad [<revision>]",
+	Short: "mark the revision (default: HEAD) as bad",
+	RunE:  bisectMarkRunE("bad"),
+}
+
+// bisectGoodCmd represents the bisect good command
+var bisectGoodCmd = &cobra.Command{
+	Use:   "good [<revision>...]",
+	Short: "mark the revisions (default: HEAD) as good",
+	RunE:  bisectMarkRunE("good"),
+}
+
+// bisectSkipCmd represents the bisect skip command
+var bisectSkipCmd = &cobra.Command{
+	Use:   "skip [<revision>...]",
+	Short: "leave the revisions (default: HEAD) out of the bisection",
+	RunE:  bisectMarkRunE("skip"),
+}
+
+// returns the RunE of the commands marking the revisions with the term
+func bisectMarkRunE(term string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		_, err := bisectMark(&buf, term, cmd.Flags().Args())
+		fmt.Print(buf.String())
+		return err
+	}
+}
+
+// bisectResetCmd represents the bisect reset command
+var bisectResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "finish the bisection, getting back to HEAD before it started",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		return bisectReset()
+	},
+}
+
+// bisectLogCmd represents the bisect log command
+var bisectLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "print the log of the bisection, which replay accepts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		if _, err := readBisect(); err != nil {
+			return err
+		}
+		content, err := os.ReadFile(data.BisectLogPath)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		fmt.Print(string(content))
+		return nil
+	},
+}
+
+// bisectReplayCmd represents the bisect replay command
+var bisectReplayCmd = &cobra.Command{
+	Use:   "replay <file>",
+	Short: "start the bisection again, marking the commits in the log",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		content, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := data.ReadBisect(); err == nil {
+			if err := bisectReset(); err != nil {
+				return err
+			}
+		}
+		var buf bytes.Buffer
+		defer func() { fmt.Print(buf.String()) }()
+		sc := bufio.NewScanner(bytes.NewReader(content))
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			if len(fields) < 3 || fields[0] != "pgit" || fields[1] != "bisect" {
+				return fmt.Errorf("invalid line in the bisect log: %s", sc.Text())
+			}
+			//only the last step is printed
+			buf.Reset()
+			switch term := fields[2]; term {
+			case "start":
+				err = bisectStart(&buf, fields[3:])
+			case "bad", "good", "skip":
+				_, err = bisectMark(&buf, term, fields[3:])
+			default:
+				return fmt.Errorf("invalid line in the bisect log: %s", sc.Text())
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// bisectRunCmd represents the bisect run command
+var bisectRunCmd = &cobra.Command{
+	Use:   "run <command> [<arg>...]",
+	Short: "mark the commits by the exit code of the command until the first bad commit is found",
+	Long: `run the command on each commit to test, which is marked by the exit code of the command:
+0 as good, 125 as skipped, 1 to 127 otherwise as bad. An exit code of 128 or over stops the bisection.`,
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return errors.New("bisect run needs a command")
+		}
+		b, err := readBisect()
+		if err != nil {
+			return err
+		}
+		if b.Bad == "" || len(b.Good) == 0 {
+			return errors.New("bisect run needs both a bad and a good commit")
+		}
+		for {
+			fmt.Printf("running %s\n", strings.Join(args, " "))
+			script := exec.Command(args[0], args[1:]...)
+			script.Stdout, script.Stderr = os.Stdout, os.Stderr
+			code := 0
+			if err := script.Run(); err != nil {
+				var exitErr *exec.ExitError
+				if !errors.As(err, &exitErr) {
+					return fmt.Errorf("bisect run failed: %w", err)
+				}
+				code = exitErr.ExitCode()
+			}
+			term := "good"
+			switch {
+			case code == bisectRunSkipCode:
+				term = "skip"
+			case code < 0 || code >= 128:
+				return fmt.Errorf("bisect run failed: exit code %d from %s", code, args[0])
+			case code > 0:
+				term = "bad"
+			}
+			var buf bytes.Buffer
+			step, err := bisectMark(&buf, term, nil)
+			fmt.Print(buf.String())
+			if err != nil {
+				return err
+			}
+			if step == nil || step.Next == "" {
+				return nil
+			}
+		}
+	},
+}
+
+// reads the bisection in progress, telling the user to start one if there is none
+func readBisect() (*data.Bisect, error) {
+	b, err := data.ReadBisect()
+	if errors.Is(err, data.ErrNotBisecting) {
+		return nil, errors.New("not bisecting: start with pgit bisect start")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	return b, nil
+}
+
+// starts a bisection from HEAD, ending the one in progress if any, and marks the first revision
+// as bad and the rest as good
+func bisectStart(buf *bytes.Buffer, revs []string) error {
+	if _, err := data.ReadBisect(); err == nil {
+		if err := bisectReset(); err != nil {
+			return err
+		}
+	}
+	head, err := os.ReadFile(data.RefHEADPath)
+	if err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	oids := make([]string, len(revs))
+	for i, rev := range revs {
+		if oids[i], err = resolveRev(rev); err != nil {
+			return err
+		}
+	}
+	b := &data.Bisect{Start: string(head)}
+	if err := b.Write(); err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	if err := data.AppendBisectLog("pgit bisect start"); err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	if len(oids) == 0 {
+		return bisectNext(buf, b)
+	}
+	if _, err := bisectMark(buf, "bad", oids[:1]); err != nil {
+		return err
+	}
+	if len(oids) == 1 {
+		return nil
+	}
+	_, err = bisectMark(buf, "good", oids[1:])
+	return err
+}
+
+// marks the revisions (default: HEAD) with the term (bad, good or skip), and checks out the commit
+// to test next, if any
+func bisectMark(buf *bytes.Buffer, term string, revs []string) (*data.BisectStep, error) {
+	b, err := readBisect()
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) == 0 {
+		revs = []string{HEAD}
+	}
+	if term == "bad" && len(revs) > 1 {
+		return nil, errors.New("bisect bad takes only one revision")
+	}
+	var lines []string
+	for _, rev := range revs {
+		oid, err := resolveRev(rev)
+		if err != nil {
+			return nil, err
+		}
+		c, err := data.GetCommit(oid)
+		if err != nil {
+			return nil, fmt.Errorf("internal error: %w", err)
+		}
+		switch term {
+		case "bad":
+			b.Bad = oid
+		case "good":
+			b.Good = append(b.Good, oid)
+		case "skip":
+			b.Skip = append(b.Skip, oid)
+		}
+		lines = append(lines, fmt.Sprintf("# %s: [%s] %s", term, oid, c.Subject()), fmt.Sprintf("pgit bisect %s %s", term, oid))
+	}
+	if err := b.Write(); err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	if err := data.AppendBisectLog(lines...); err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	step, err := b.Next()
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	return step, writeBisectStep(buf, b, step)
+}
+
+// finds the next step of the bisection and checks out the commit to test, if any
+func bisectNext(buf *bytes.Buffer, b *data.Bisect) error {
+	step, err := b.Next()
+	if err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	return writeBisectStep(buf, b, step)
+}
+
+// writes the step of the bisection, checking out the commit to test next
+func writeBisectStep(buf *bytes.Buffer, b *data.Bisect, step *data.BisectStep) error {
+	switch {
+	case step == nil && b.Bad == "" && len(b.Good) == 0:
+		buf.WriteString("status: waiting for both good and bad commits\n")
+	case step == nil && b.Bad == "":
+		fmt.Fprintf(buf, "status: waiting for bad commit, %d good commit(s) known\n", len(b.Good))
+	case step == nil:
+		buf.WriteString("status: waiting for good commit(s), bad commit known\n")
+	case step.Found != "":
+		c, err := data.GetCommit(step.Found)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		fmt.Fprintf(buf, "%s is the first bad commit\n", step.Found)
+		e := &data.LogEntry{Oid: step.Found, Commit: c}
+		if err := e.Write(buf, data.FormatMedium, false); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+	case len(step.Skipped) > 0:
+		buf.WriteString("There are only 'skip'ped commits left to test.\nThe first bad commit could be any of:\n")
+		for _, oid := range step.Skipped {
+			fmt.Fprintln(buf, oid)
+		}
+		buf.WriteString("We cannot bisect more!\n")
+	default:
+		c, err := data.GetCommit(step.Next)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		if err := switchHEAD(step.Next, step.Next); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		fmt.Fprintf(buf, "Bisecting: %d revision(s) left to test after this (roughly %d step(s))\n", step.Remaining, step.Steps)
+		fmt.Fprintf(buf, "[%s] %s\n", step.Next, c.Subject())
+	}
+	return nil
+}
+
+// ends the bisection, checking out what HEAD pointed to before it started
+func bisectReset() error {
+	b, err := readBisect()
+	if err != nil {
+		return err
+	}
+	oid, err := b.StartOid()
+	if err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	if err := switchHEAD(oid, b.Start); err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	if err := data.RemoveBisect(); err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	return nil
+}
+
+// checks out the commit, replacing the files of the commit HEAD points to with its files, and
+// writes the content of HEAD, which is either the oid (= detached HEAD) or a symbolic ref
+func switchHEAD(oid string, head string) error {
+	current, err := data.ResolveRefOid(data.RefHEADPath)
+	if err != nil {
+		return fmt.Errorf("switchHEAD: %w", err)
+	}
+	c, err := data.GetCommit(oid)
+	if err != nil {
+		return fmt.Errorf("switchHEAD: %w", err)
+	}
+	if current != "" {
+		from, err := data.GetCommit(current)
+		if err != nil {
+			return fmt.Errorf("switchHEAD: %w", err)
+		}
+		fromTree, err := data.ParseTreeFile(filepath.Join(ObjDir, from.TreeOid))
+		if err != nil {
+			return fmt.Errorf("switchHEAD: %w", err)
+		}
+		toTree, err := data.ParseTreeFile(filepath.Join(ObjDir, c.TreeOid))
+		if err != nil {
+			return fmt.Errorf("switchHEAD: %w", err)
+		}
+		for _, e := range data.DiffTreeEntries(fromTree, toTree) {
+			if e.Status == data.StatusDeleted {
+				if err := os.Remove(filepath.FromSlash(e.Path)); err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("switchHEAD: %w", err)
+				}
+			}
+		}
+	}
+	if err := data.ReadTree(c.TreeOid, ObjDir, "."); err != nil {
+		return fmt.Errorf("switchHEAD: %w", err)
+	}
+	if err := resetIndex(); err != nil {
+		return fmt.Errorf("switchHEAD: %w", err)
+	}
+	if err := data.WriteFile(data.RefHEADPath, []byte(head)); err != nil {
+		return fmt.Errorf("switchHEAD: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(bisectCmd)
+	bisectCmd.AddCommand(bisectStartCmd, bisectBadCmd, bisectGoodCmd, bisectSkipCmd, bisectResetCmd,
+		bisectLogCmd, bisectReplayCmd, bisectRunCmd)
+}
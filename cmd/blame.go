@@ -0,0 +1,106 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// blameCmd represents the blame command
+var blameCmd = &cobra.Command{
+	Use:   "blame [-L <start>,<end>] [--porcelain] [-M] <file> [<revision>]",
+	Short: "print each line of a file with the commit that last changed it",
+	Long: `print each line of the file in the revision (default: HEAD) with the abbreviated oid, the author,
+the date and the line number of the commit introducing the line, following renames of the file.
+-L limits the lines to a range like 10,20, 10,+5 (5 lines from the 10th) or 10 (to the end).
+With -M, lines moved or copied within the file are attributed to the commits they come from.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		args = cmd.Flags().Args()
+		if len(args) < 1 || len(args) > 2 {
+			return errors.New("blame needs a file and optionally a revision")
+		}
+		rev := HEAD
+		if len(args) == 2 {
+			rev = args[1]
+		}
+		oid, err := resolveRev(rev)
+		if err != nil {
+			return err
+		}
+		opts := data.NewBlameOptions()
+		if err := setDiffAlgorithmOptions(cmd, opts.Diff); err != nil {
+			return err
+		}
+		if lines, _ := cmd.Flags().GetString("lines"); lines != "" {
+			if opts.Start, opts.End, err = parseLineRange(lines); err != nil {
+				return err
+			}
+		}
+		opts.Moves, _ = cmd.Flags().GetBool("moves")
+
+		path := filepath.ToSlash(filepath.Clean(args[0]))
+		blame, err := data.BlameFile(oid, path, opts)
+		switch {
+		case errors.Is(err, data.ErrNoSuchPath):
+			return fmt.Errorf("no such path %s in %s", path, rev)
+		case errors.Is(err, data.ErrInvalidLineRange):
+			return fmt.Errorf("invalid -L: %w", err)
+		case err != nil:
+			return fmt.Errorf("internal error: %w", err)
+		}
+		var buf bytes.Buffer
+		if porcelain, _ := cmd.Flags().GetBool("porcelain"); porcelain {
+			blame.WritePorcelain(&buf)
+		} else {
+			blame.Write(&buf)
+		}
+		fmt.Print(buf.String())
+		return nil
+	},
+}
+
+// parses the range of -L like "10,20", "10,+5" and "10" into the first and the last line numbers,
+// where zero stands for the last line
+func parseLineRange(s string) (start int, end int, err error) {
+	first, last, found := strings.Cut(s, ",")
+	start, err = strconv.Atoi(first)
+	if err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid -L: %s", s)
+	}
+	switch {
+	case !found || last == "":
+		return start, 0, nil
+	case strings.HasPrefix(last, "+"):
+		n, err := strconv.Atoi(last[1:])
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("invalid -L: %s", s)
+		}
+		return start, start + n - 1, nil
+	}
+	end, err = strconv.Atoi(last)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid -L: %s", s)
+	}
+	return start, end, nil
+}
+
+func init() {
+	rootCmd.AddCommand(blameCmd)
+
+	blameCmd.Flags().StringP("lines", "L", "", "blame only the lines in the range, like 10,20 or 10,+5")
+	blameCmd.Flags().Bool("porcelain", false, "print the lines in the format for machines")
+	blameCmd.Flags().BoolP("moves", "M", false, "detect lines moved or copied within the file")
+	addDiffAlgorithmFlags(blameCmd)
+}
@@ -2,7 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"path/filepath"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/taimats/pgit/data"
@@ -19,11 +19,9 @@ var catFileCmd = &cobra.Command{
 			return err
 		}
 		oid := args[0]
-		content, err := data.ReadAllFileContent(filepath.Join(ObjDir, oid))
-		if err != nil {
+		if _, err := data.CopyObject(os.Stdout, ObjDir, oid); err != nil {
 			return fmt.Errorf("failed to fetch file content: (error: %w)", err)
 		}
-		fmt.Println(string(content))
 		return nil
 	},
 }
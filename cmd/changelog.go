@@ -0,0 +1,66 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// changelogCmd represents the changelog command
+var changelogCmd = &cobra.Command{
+	Use:   "changelog <from>..<to>",
+	Short: "write a Markdown changelog of the commits in the range",
+	Long: `write a Markdown changelog of the commits in <from>..<to> (or <from>..HEAD if only <from> is given),
+grouped by their Conventional Commit types: breaking changes ("type!:" or a "BREAKING CHANGE:" footer),
+features ("feat:"), bug fixes ("fix:") and the others. Merge commits are left out. The contributors
+are listed by the names mapped by the mailmap file (default: .mailmap, or the config "mailmap.file").`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		args = cmd.Flags().Args()
+		if len(args) != 1 {
+			return errors.New("changelog requires a range like <from>..<to>")
+		}
+		oids, err := rangeCommits(args[0])
+		if err != nil {
+			return err
+		}
+		mailmap, err := readMailmap()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		var entries []*data.ChangelogEntry
+		for _, oid := range oids {
+			c, err := data.GetCommit(oid)
+			if err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			if len(c.Parents()) > 1 {
+				continue
+			}
+			entries = append(entries, data.NewChangelogEntry(oid, c, mailmap))
+		}
+		title, _ := cmd.Flags().GetString("title")
+		if title == "" {
+			title = args[0]
+		}
+
+		var buf bytes.Buffer
+		data.WriteChangelog(&buf, title, entries)
+		fmt.Print(buf.String())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+
+	changelogCmd.Flags().String("title", "", "the heading of the changelog (default: the range)")
+}
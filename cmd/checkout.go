@@ -35,6 +35,9 @@ var checkoutCmd = &cobra.Command{
 		if err := data.ReadTree(string(treeOid), ObjDir, "."); err != nil {
 			return fmt.Errorf("internal error: %w", err)
 		}
+		if err := resetIndex(); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
 		head, err := data.NewRef(data.RefHEADPath)
 		if err != nil {
 			return fmt.Errorf("internal error: %w", err)
@@ -2,16 +2,26 @@ package cmd_test
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/taimats/pgit/cmd"
 	"github.com/taimats/pgit/data"
 )
@@ -105,7 +115,8 @@ type testCase struct {
 	out  wantOutput
 }
 
-// the main test for examining the common behavior of each command
+// the main test for examining the common behavior of each command.
+// The flags changed by args (or by hand before) are reset afterwards, so that they never leak into the next run.
 func execCmd(t *testing.T, cmd *cobra.Command, args []string) (stdout string, err error) {
 	old := os.Stdout
 	r, w, err := os.Pipe()
@@ -115,6 +126,7 @@ func execCmd(t *testing.T, cmd *cobra.Command, args []string) (stdout string, er
 	defer r.Close()
 	os.Stdout = w
 
+	defer resetFlags(cmd)
 	if err := cmd.ParseFlags(args); err != nil {
 		t.Fatal(err)
 	}
@@ -129,6 +141,84 @@ func execCmd(t *testing.T, cmd *cobra.Command, args []string) (stdout string, er
 	return buf.String(), err
 }
 
+// sets the changed flags of the command back to their defaults, forgetting the position of "--" as well
+func resetFlags(c *cobra.Command) {
+	c.Flags().Visit(func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	//the position of "--" is kept until the next "--" is parsed, which Init forgets
+	c.Flags().Init(c.Name(), pflag.ContinueOnError)
+}
+
+// a commit made by commitFilesForTest
+type testCommit struct {
+	content string //the content of file_01
+	msg     string //the message of the commit, or the content if empty
+	author  string //"{name} <{email}>" of the author written in the config, or the config as it is if empty
+}
+
+// commits the contents of file_01 one by one, and returns the commit oids from the oldest
+func commitFilesForTest(t *testing.T, commits ...testCommit) []string {
+	t.Helper()
+
+	oids := make([]string, 0, len(commits))
+	for _, c := range commits {
+		if c.author != "" {
+			name, email, _ := strings.Cut(strings.TrimSuffix(c.author, ">"), " <")
+			conf := data.Config{data.ConfigUserName: name, data.ConfigUserEmail: email}
+			if err := conf.Write(data.ConfigPath); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := data.WriteFile("file_01", []byte(c.content)); err != nil {
+			t.Fatal(err)
+		}
+		msg := c.msg
+		if msg == "" {
+			msg = c.content
+		}
+		oid, err := cmd.NewCommit(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oids = append(oids, oid)
+	}
+	return oids
+}
+
+var oidPlaceholder = regexp.MustCompile(`\{(\d+)(?::(\d+))?\}`)
+
+// replaces the placeholders of the oids in s: "{n}" stands for the n-th oid (counted from 1),
+// and "{n:k}" for its first k characters
+func expandOids(s string, oids []string) string {
+	return oidPlaceholder.ReplaceAllStringFunc(s, func(m string) string {
+		sub := oidPlaceholder.FindStringSubmatch(m)
+		n, _ := strconv.Atoi(sub[1])
+		if n < 1 || n > len(oids) {
+			return m
+		}
+		oid := oids[n-1]
+		if k, err := strconv.Atoi(sub[2]); err == nil && k < len(oid) {
+			return oid[:k]
+		}
+		return oid
+	})
+}
+
+// expandOids for each of the arguments
+func expandOidArgs(args []string, oids []string) []string {
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = expandOids(arg, oids)
+	}
+	return expanded
+}
+
 type output struct {
 	fileType string //"file" or "dir"
 	path     string
@@ -262,7 +352,7 @@ func TestCatFile(t *testing.T) {
 			{
 				desc: "01_all well done",
 				args: []string{oid},
-				out: newWantOutput("", []output{
+				out: newWantOutput(content, []output{
 					{"file", filepath.Join(cmd.ObjDir, oid)},
 				}),
 			},
@@ -455,23 +545,77 @@ func loadAndSetFiles(srcDir string, pattern string, targetDir string) (paths []s
 }
 
 func TestLog(t *testing.T) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		t.Fatal(err)
+	tests := []struct {
+		desc string
+		args []string
+		want string //the oids of the commits are written as placeholders (see expandOids)
+	}{
+		{desc: "01_oneline", args: []string{"--oneline"}, want: "{2:7} (HEAD -> master) second commit\n{1:7} (tag: v1) first commit\n"},
+		{desc: "02_format", args: []string{"--format", "%H %an <%ae>%n%s|%b"}, want: "{2} Taro Pgit <taro@example.com>\nsecond commit|the body\n{1} Taro Pgit <taro@example.com>\nfirst commit|\n"},
+		{desc: "03_max count", args: []string{"-n", "1", "--oneline", "--decorate=false"}, want: "{2:7} second commit\n"},
+		{desc: "04_with @ ailias", args: []string{"--format", "%h", "@"}, want: "{2:7}\n{1:7}\n"},
+		{desc: "05_stat", args: []string{"-n", "1", "--format", "%s", "--stat"}, want: "second commit\n file_01 | 2 +-\n 1 file changed, 1 insertion(+), 1 deletion(-)\n"},
+		{desc: "06_graph", args: []string{"--graph", "--format", "%s"}, want: "* second commit\n* first commit\n"},
 	}
-	cmdDir := filepath.Dir(cwd)
-	t.Run("success", func(t *testing.T) {
-		tests := []testCase{
-			{
-				desc: "01_all well done",
-				args: []string{},
-				out:  newWantOutput("", []output{}),
-			},
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rootPath := joinTestDir(t, "log")
+			initPgitForTest(t)
+			t.Cleanup(func() {
+				leaveTestDir(t, rootPath)
+			})
+			oids := commitFilesForTest(t,
+				testCommit{content: "1\n", msg: "first commit", author: "Taro Pgit <taro@example.com>"},
+				testCommit{content: "one\n", msg: "second commit\n\nthe body"},
+			)
+			if err := data.WriteFile(filepath.Join(cmd.TagDir, "v1"), []byte(oids[0])); err != nil {
+				t.Fatal(err)
+			}
+
+			stdout, err := execCmd(t, cmd.LogCmd, tt.args)
+
+			if err != nil {
+				t.Errorf("error should be emtpy: (error: %s)", err)
+			}
+			if want := expandOids(tt.want, oids); stdout != want {
+				t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+			}
+		})
+	}
+	t.Run("medium", func(t *testing.T) {
+		rootPath := joinTestDir(t, "log")
+		initPgitForTest(t)
+		t.Cleanup(func() {
+			leaveTestDir(t, rootPath)
+		})
+		oid, err := cmd.NewCommit("test message")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stdout, err := execCmd(t, cmd.LogCmd, []string{})
+
+		if err != nil {
+			t.Errorf("error should be emtpy: (error: %s)", err)
+		}
+		want := fmt.Sprintf("commit %s (HEAD -> master)\n", oid)
+		if !strings.HasPrefix(stdout, want) || !strings.HasSuffix(stdout, "\n\n    test message\n") {
+			t.Errorf("Stdout should be a commit with its message:\n{ got: %q }", stdout)
+		}
+	})
+	t.Run("paths", func(t *testing.T) {
+		tests := []struct {
+			desc string
+			args []string
+			want string
+		}{
+			{desc: "01_path", args: []string{"--format", "%s", "file_02"}, want: "modify 2\nadd\n"},
 			{
-				desc: "02_with @ ailias",
-				args: []string{"@"},
-				out:  newWantOutput("", []output{}),
+				desc: "02_follow",
+				args: []string{"--format", "%s", "--name-status", "--follow", "file_03"},
+				want: "modify 3\nM\tfile_03\nrename\nR100\tfile_01\tfile_03\nadd\nA\tfile_01\n",
 			},
+			{desc: "03_path of the new name", args: []string{"--format", "%s", "HEAD", "--", "file_03"}, want: "modify 3\nrename\n"},
 		}
 		for _, tt := range tests {
 			t.Run(tt.desc, func(t *testing.T) {
@@ -480,22 +624,37 @@ func TestLog(t *testing.T) {
 				t.Cleanup(func() {
 					leaveTestDir(t, rootPath)
 				})
-				_, err := loadAndSetFiles(cmdDir, "*.go", rootPath)
-				if err != nil {
-					t.Fatal(err)
+				steps := []struct {
+					msg  string
+					edit func() error
+				}{
+					{msg: "add", edit: func() error {
+						if err := data.WriteFile("file_01", []byte("1\n2\n3\n")); err != nil {
+							return err
+						}
+						return data.WriteFile("file_02", []byte("2\n"))
+					}},
+					{msg: "modify 2", edit: func() error { return data.WriteFile("file_02", []byte("two\n")) }},
+					{msg: "rename", edit: func() error { return os.Rename("file_01", "file_03") }},
+					{msg: "modify 3", edit: func() error { return data.WriteFile("file_03", []byte("1\n2\n3\n4\n")) }},
 				}
-				oid, err := cmd.NewCommit("test message")
-				if err != nil {
-					t.Fatal(err)
+				for _, s := range steps {
+					if err := s.edit(); err != nil {
+						t.Fatal(err)
+					}
+					if _, err := cmd.NewCommit(s.msg); err != nil {
+						t.Fatal(err)
+					}
 				}
-				tt.out = newWantOutput(fmt.Sprintf("%s\n", oid), []output{})
 
 				stdout, err := execCmd(t, cmd.LogCmd, tt.args)
 
 				if err != nil {
 					t.Errorf("error should be emtpy: (error: %s)", err)
 				}
-				assertOutput(t, stdout, tt.out)
+				if stdout != tt.want {
+					t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, tt.want)
+				}
 			})
 		}
 	})
@@ -597,37 +756,154 @@ func TestTag(t *testing.T) {
 			})
 		}
 	})
+	t.Run("annotated and signed", func(t *testing.T) {
+		rootPath := joinTestDir(t, "tag")
+		initPgitForTest(t)
+		t.Cleanup(func() {
+			leaveTestDir(t, rootPath)
+		})
+		key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := data.WriteFile("signing_key", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err != nil {
+			t.Fatal(err)
+		}
+		keyring := fmt.Sprintf("taro@example.com ed25519 %s\n", base64.StdEncoding.EncodeToString(pub))
+		if err := data.WriteFile(data.KeyringPath, []byte(keyring)); err != nil {
+			t.Fatal(err)
+		}
+		conf := data.Config{data.ConfigUserName: "Taro", data.ConfigUserEmail: "taro@example.com", data.ConfigSigningKey: "signing_key"}
+		if err := conf.Write(data.ConfigPath); err != nil {
+			t.Fatal(err)
+		}
+		oids := commitFilesForTest(t, testCommit{content: "first"}, testCommit{content: "second"})
+		fingerprint := data.Fingerprint(key.Public().(ed25519.PublicKey))
+		steps := []struct {
+			args    []string
+			want    string
+			wantErr bool
+		}{
+			{args: []string{"v1", "{1}"}, want: "created a tag!!\n"},
+			{args: []string{"-m", "release 2", "v2"}, want: "created a tag!!\n"},
+			{args: []string{"v1"}, wantErr: true},
+			{args: []string{"-a", "v3"}, wantErr: true},
+			{args: []string{"-s", "-m", "signed", "v3"}, want: "created a tag!!\n"},
+			{args: []string{}, want: "v1\nv2\nv3\n"},
+			{args: []string{"-l", "v[12]"}, want: "v1\nv2\n"},
+			{args: []string{"--contains", "{2}"}, want: "v2\nv3\n"},
+			{args: []string{"-v", "v3"}, want: "Good signature from \"taro@example.com\" with key " + fingerprint + "\n"},
+			{args: []string{"-v", "v2"}, wantErr: true},
+			{args: []string{"-v", "v1"}, wantErr: true},
+			{args: []string{"-d", "v1"}, want: "Deleted tag 'v1' (was {1:7})\n"},
+			{args: []string{"-f", "v3", "{1}"}, want: "created a tag!!\n"},
+			{args: []string{"-d", "v1"}, wantErr: true},
+		}
+		for _, step := range steps {
+			stdout, err := execCmd(t, cmd.TagCmd, expandOidArgs(step.args, oids))
+
+			if step.wantErr {
+				if err == nil {
+					t.Errorf("%v: error should not be nil: (stdout: %q)", step.args, stdout)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%v: error should be emtpy: (error: %s)", step.args, err)
+			}
+			if want := expandOids(step.want, oids); stdout != want {
+				t.Errorf("%v: Stdout should be equal:\n{ got: %q, want: %q }", step.args, stdout, want)
+			}
+		}
+		//the annotated tag names the commit it points to
+		stdout, err := execCmd(t, cmd.DescribeCmd, []string{})
+		if err != nil || stdout != "v2\n" {
+			t.Errorf("describe should use the annotated tag: (stdout: %q, error: %v)", stdout, err)
+		}
+	})
+	t.Run("locked repository", func(t *testing.T) {
+		rootPath := joinTestDir(t, "tag")
+		initPgitForTest(t)
+		t.Cleanup(func() {
+			leaveTestDir(t, rootPath)
+		})
+		if _, err := cmd.NewCommit("test message"); err != nil {
+			t.Fatal(err)
+		}
+		lock, err := data.AcquireLock(data.LockPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := len(allFileNames(t, cmd.ObjDir))
+
+		stdout, err := execCmd(t, cmd.TagCmd, []string{"-m", "release", "v1"})
+
+		if !errors.Is(err, data.ErrLocked) {
+			t.Errorf("error should be ErrLocked: (stdout: %q, error: %v)", stdout, err)
+		}
+		if after := len(allFileNames(t, cmd.ObjDir)); after != before {
+			t.Errorf("no tag object should be written: { before: %d, after: %d }", before, after)
+		}
+		lock.Release()
+		if _, err := execCmd(t, cmd.TagCmd, []string{"-m", "release", "v1"}); err != nil {
+			t.Errorf("error should be emtpy: (error: %s)", err)
+		}
+		if _, err := os.Stat(data.LockPath); err == nil {
+			t.Errorf("lock should be released")
+		}
+	})
 }
 
-// func TestK(t *testing.T) {
-// 	t.Run("success", func(t *testing.T) {
-// 		tests := []testCase{
-// 			{
-// 				desc: "01_printing all refs with no arg",
-// 				args: []string{"second"},
-// 				out:  newWantOutput("", []output{}),
-// 			},
-// 		}
-// 		for _, tt := range tests {
-// 			t.Run(tt.desc, func(t *testing.T) {
-// 				rootPath := joinTestDir(t, "k")
-// 				initPgitForTest(t)
-// 				t.Cleanup(func() {
-// 					leaveTestDir(t, rootPath)
-// 				})
-// 				var buf strings.Builder
-// 				oid, err := cmd.NewCommit()
-
-// 				stdout, err := execCmd(t, cmd.KCmd, tt.args)
-
-// 				if err != nil {
-// 					t.Errorf("error should be emtpy: (error: %s)", err)
-// 				}
-// 				assertOutput(t, stdout, tt.out)
-// 			})
-// 		}
-// 	})
-// }
+func TestK(t *testing.T) {
+	tests := []struct {
+		desc string
+		args []string
+		want string //the oids of the commits are written as placeholders (see expandOids)
+	}{
+		{
+			desc: "01_dot",
+			args: []string{},
+			want: "digraph pgit {\n\tnode [shape=box];\n" +
+				"\t\"{2}\" [label=\"{2:7}\\nsecond\"];\n\t\"{2}\" -> \"{1}\";\n\t\"{1}\" [label=\"{1:7}\\nfirst\"];\n" +
+				"\t\"HEAD\" [label=\"HEAD\", shape=ellipse];\n\t\"HEAD\" -> \"{2}\" [style=dashed];\n" +
+				"\t\"refs/heads/master\" [label=\"master\", shape=ellipse];\n\t\"refs/heads/master\" -> \"{2}\" [style=dashed];\n" +
+				"\t\"refs/tags/v1\" [label=\"tags/v1\", shape=ellipse];\n\t\"refs/tags/v1\" -> \"{1}\" [style=dashed];\n}\n",
+		},
+		{
+			desc: "02_mermaid",
+			args: []string{"--format", "mermaid"},
+			want: "flowchart TD\n\tc{2}[\"{2:7} second\"]\n\tc{2} --> c{1}\n\tc{1}[\"{1:7} first\"]\n" +
+				"\tref0([\"HEAD\"]) -.-> c{2}\n\tref1([\"master\"]) -.-> c{2}\n\tref2([\"tags/v1\"]) -.-> c{1}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rootPath := joinTestDir(t, "k")
+			initPgitForTest(t)
+			t.Cleanup(func() {
+				leaveTestDir(t, rootPath)
+			})
+			oids := commitFilesForTest(t, testCommit{content: "first"}, testCommit{content: "second"})
+			if err := data.WriteFile(filepath.Join(cmd.TagDir, "v1"), []byte(oids[0])); err != nil {
+				t.Fatal(err)
+			}
+
+			stdout, err := execCmd(t, cmd.KCmd, tt.args)
+
+			if err != nil {
+				t.Errorf("error should be emtpy: (error: %s)", err)
+			}
+			if want := expandOids(tt.want, oids); stdout != want {
+				t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+			}
+		})
+	}
+}
 
 func TestBranch(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
@@ -692,6 +968,116 @@ func TestStatus(t *testing.T) {
 	})
 }
 
+func TestStatusRename(t *testing.T) {
+	rootPath := joinTestDir(t, "statusRename")
+	initPgitForTest(t)
+	t.Cleanup(func() {
+		leaveTestDir(t, rootPath)
+	})
+	if err := data.WriteFile("file_01", []byte("rename me\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.NewCommit("test commit"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename("file_01", "file_02"); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.WriteFile("file_03", []byte("untracked\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execCmd(t, cmd.AddCmd, []string{"file_01", "file_02"}); err != nil {
+		t.Fatal(err)
+	}
+	want := newWantOutput("on branch master\nchanges to be committed:\n\tR100 file_01 -> file_02\nuntracked files:\n\tfile_03\n", []output{})
+
+	stdout, err := execCmd(t, cmd.StatusCmd, []string{})
+
+	if err != nil {
+		t.Errorf("error should be emtpy: (error: %s)", err)
+	}
+	assertOutput(t, stdout, want)
+}
+
+func TestAddDirectory(t *testing.T) {
+	rootPath := joinTestDir(t, "addDirectory")
+	initPgitForTest(t)
+	t.Cleanup(func() {
+		leaveTestDir(t, rootPath)
+	})
+	for name, content := range map[string]string{"d.txt": "delete me\n", "r.txt": "rename me\n", "k.txt": "keep me\n"} {
+		if err := data.WriteFile(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cmd.NewCommit("test commit"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove("d.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename("r.txt", "r2.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execCmd(t, cmd.AddCmd, []string{"."}); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, err := execCmd(t, cmd.DiffCmd, []string{"--cached", "--name-status"})
+
+	if err != nil {
+		t.Errorf("error should be emtpy: (error: %s)", err)
+	}
+	if want := "D\td.txt\nR100\tr.txt\tr2.txt\n"; stdout != want {
+		t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+	}
+}
+
+func TestCommitStaged(t *testing.T) {
+	rootPath := joinTestDir(t, "commitStaged")
+	initPgitForTest(t)
+	t.Cleanup(func() {
+		leaveTestDir(t, rootPath)
+	})
+	for name, content := range map[string]string{"file_01": "01\n", "file_02": "02\n"} {
+		if err := data.WriteFile(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, err := cmd.NewCommit("first commit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"file_01": "staged\n", "file_02": "not staged\n"} {
+		if err := data.WriteFile(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := execCmd(t, cmd.AddCmd, []string{"file_01"}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := cmd.NewCommit("second commit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	committed, err := execCmd(t, cmd.DiffCmd, []string{"--name-status", first, second})
+	if err != nil {
+		t.Errorf("error should be emtpy: (error: %s)", err)
+	}
+	left, err := execCmd(t, cmd.DiffCmd, []string{"--name-status"})
+	if err != nil {
+		t.Errorf("error should be emtpy: (error: %s)", err)
+	}
+
+	if want := "M\tfile_01\n"; committed != want {
+		t.Errorf("only the staged file should be committed:\n{ got: %q, want: %q }", committed, want)
+	}
+	if want := "M\tfile_02\n"; left != want {
+		t.Errorf("the file not staged should be left changed:\n{ got: %q, want: %q }", left, want)
+	}
+}
+
 func TestReset(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		tests := []testCase{
@@ -766,3 +1152,944 @@ func TestShow(t *testing.T) {
 		}
 	})
 }
+
+func TestConfig(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tests := []struct {
+			desc  string
+			setup [][]string
+			args  []string
+			out   wantOutput
+		}{
+			{
+				desc:  "01_get a value",
+				setup: [][]string{{"core.bigFileThreshold", "10m"}},
+				args:  []string{"core.bigFileThreshold"},
+				out:   newWantOutput("10m\n", []output{}),
+			},
+			{
+				desc:  "02_list all",
+				setup: [][]string{{"user.name", "Taro Pgit"}, {"core.bigFileThreshold", "1g"}},
+				args:  []string{},
+				out:   newWantOutput("core.bigFileThreshold 1g\nuser.name Taro Pgit\n", []output{}),
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				rootPath := joinTestDir(t, "config")
+				initPgitForTest(t)
+				t.Cleanup(func() {
+					leaveTestDir(t, rootPath)
+				})
+				for _, args := range tt.setup {
+					if _, err := execCmd(t, cmd.ConfigCmd, args); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				stdout, err := execCmd(t, cmd.ConfigCmd, tt.args)
+
+				if err != nil {
+					t.Errorf("error should be emtpy: (error: %s)", err)
+				}
+				assertOutput(t, stdout, tt.out)
+			})
+		}
+	})
+}
+
+func TestLFSLsFiles(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		content := []byte("large binary content")
+		sum := sha256.Sum256(content)
+		tests := []testCase{
+			{
+				desc: "01_all set",
+				args: []string{},
+				out:  newWantOutput(fmt.Sprintf("%s * image.bin\n", hex.EncodeToString(sum[:])[:10]), []output{}),
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				rootPath := joinTestDir(t, "lfs")
+				initPgitForTest(t)
+				t.Cleanup(func() {
+					leaveTestDir(t, rootPath)
+				})
+				if _, err := execCmd(t, cmd.LFSTrackCmd, []string{"*.bin"}); err != nil {
+					t.Fatal(err)
+				}
+				if err := data.WriteFile("image.bin", content); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := cmd.NewCommit("add an image"); err != nil {
+					t.Fatal(err)
+				}
+
+				stdout, err := execCmd(t, cmd.LFSLsFilesCmd, tt.args)
+
+				if err != nil {
+					t.Errorf("error should be emtpy: (error: %s)", err)
+				}
+				assertOutput(t, stdout, tt.out)
+			})
+		}
+	})
+}
+
+func TestLFSStatusAndDiff(t *testing.T) {
+	rootPath := joinTestDir(t, "lfsStatus")
+	initPgitForTest(t)
+	t.Cleanup(func() {
+		leaveTestDir(t, rootPath)
+	})
+	if _, err := execCmd(t, cmd.LFSTrackCmd, []string{"*.bin"}); err != nil {
+		t.Fatal(err)
+	}
+	content := bytes.Repeat([]byte{0, 1}, 2500)
+	if err := data.WriteFile("a.bin", content); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.NewCommit("add a binary"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("01_clean", func(t *testing.T) {
+		status, err := execCmd(t, cmd.StatusCmd, []string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		diff, err := execCmd(t, cmd.DiffCmd, []string{"--stat"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status != "on branch master\n" || diff != "" {
+			t.Errorf("nothing should be changed: (status: %q, diff: %q)", status, diff)
+		}
+	})
+	t.Run("02_modified", func(t *testing.T) {
+		if err := data.WriteFile("a.bin", append(content, 2)); err != nil {
+			t.Fatal(err)
+		}
+
+		status, err := execCmd(t, cmd.StatusCmd, []string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		diff, err := execCmd(t, cmd.DiffCmd, []string{"--stat"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(status, "M a.bin") {
+			t.Errorf("a.bin should be modified: (status: %q)", status)
+		}
+		if !strings.Contains(diff, "a.bin | Bin 5000 -> 5001 bytes") {
+			t.Errorf("the contents should be compared: (diff: %q)", diff)
+		}
+	})
+}
+
+func TestFsck(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tests := []testCase{
+			{
+				desc: "01_no problems",
+				args: []string{},
+				out:  newWantOutput("", []output{}),
+			},
+			{
+				desc: "02_connectivity only",
+				args: []string{"--connectivity-only"},
+				out:  newWantOutput("", []output{}),
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				rootPath := joinTestDir(t, "fsck")
+				initPgitForTest(t)
+				t.Cleanup(func() {
+					leaveTestDir(t, rootPath)
+				})
+				if err := data.WriteFile("file_01", []byte("fsck")); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := cmd.NewCommit("test commit"); err != nil {
+					t.Fatal(err)
+				}
+
+				stdout, err := execCmd(t, cmd.FsckCmd, tt.args)
+
+				if err != nil {
+					t.Errorf("error should be emtpy: (error: %s)", err)
+				}
+				if stdout != "" {
+					t.Errorf("Stdout should be empty: (got: %s)", stdout)
+				}
+				assertOutput(t, stdout, tt.out)
+			})
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		rootPath := joinTestDir(t, "fsck")
+		initPgitForTest(t)
+		t.Cleanup(func() {
+			leaveTestDir(t, rootPath)
+		})
+		if err := data.WriteFile("file_01", []byte("fsck")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cmd.NewCommit("test commit"); err != nil {
+			t.Fatal(err)
+		}
+		_, oid := newBlobObj(t, []byte("fsck"))
+		if err := os.Remove(filepath.Join(cmd.ObjDir, oid)); err != nil {
+			t.Fatal(err)
+		}
+
+		stdout, err := execCmd(t, cmd.FsckCmd, []string{"--connectivity-only=false"})
+
+		if !errors.Is(err, cmd.ErrFsckFailed) {
+			t.Errorf("error should be equal: (got: %v, want: %v)", err, cmd.ErrFsckFailed)
+		}
+		want := fmt.Sprintf("missing blob %s\n", oid)
+		if stdout != want {
+			t.Errorf("Stdout should be equal: (got=%s, want=%s)", stdout, want)
+		}
+	})
+}
+
+func TestPrune(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tests := []testCase{
+			{
+				desc: "01_prune everything unreachable",
+				args: []string{"--expire", "now", "-v"},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				rootPath := joinTestDir(t, "prune")
+				initPgitForTest(t)
+				t.Cleanup(func() {
+					leaveTestDir(t, rootPath)
+				})
+				if err := data.WriteFile("file_01", []byte("prune")); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := cmd.NewCommit("test commit"); err != nil {
+					t.Fatal(err)
+				}
+				garbage, err := cmd.SaveHashObj([]byte("unreachable content"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				tt.out = newWantOutput(fmt.Sprintf("%s\n", garbage), []output{})
+				before := len(allFileNames(t, cmd.ObjDir))
+
+				stdout, err := execCmd(t, cmd.PruneCmd, tt.args)
+
+				if err != nil {
+					t.Errorf("error should be emtpy: (error: %s)", err)
+				}
+				if after := len(allFileNames(t, cmd.ObjDir)); after != before-1 {
+					t.Errorf("fileNum should be equal:\n{ gotNum: %d, wantNum: %d }", after, before-1)
+				}
+				if _, err := os.Stat(data.LockPath); err == nil {
+					t.Errorf("lock should be released")
+				}
+				assertOutput(t, stdout, tt.out)
+			})
+		}
+	})
+}
+
+func TestCountObjects(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tests := []testCase{
+			{
+				desc: "01_no args",
+				args: []string{},
+				out:  newWantOutput("3 objects, 1 kilobytes\n", []output{}),
+			},
+			{
+				desc: "02_verbose",
+				args: []string{"-v"},
+				out:  newWantOutput("count: 3\nsize: 1\ngarbage: 0\nsize-garbage: 0\nlfs-count: 0\nsize-lfs: 0\n", []output{}),
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				rootPath := joinTestDir(t, "countObjects")
+				initPgitForTest(t)
+				t.Cleanup(func() {
+					leaveTestDir(t, rootPath)
+				})
+				if err := data.WriteFile("file_01", []byte("count")); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := cmd.NewCommit("test commit"); err != nil {
+					t.Fatal(err)
+				}
+
+				stdout, err := execCmd(t, cmd.CountObjectsCmd, tt.args)
+
+				if err != nil {
+					t.Errorf("error should be emtpy: (error: %s)", err)
+				}
+				assertOutput(t, stdout, tt.out)
+			})
+		}
+	})
+}
+
+func TestStats(t *testing.T) {
+	rootPath := joinTestDir(t, "stats")
+	initPgitForTest(t)
+	t.Cleanup(func() {
+		leaveTestDir(t, rootPath)
+	})
+	if err := data.WriteFile("file_01", []byte("stats")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.NewCommit("test commit"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execCmd(t, cmd.TagCmd, []string{"-m", "release", "v1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, err := execCmd(t, cmd.StatsCmd, []string{})
+
+	if err != nil {
+		t.Errorf("error should be emtpy: (error: %s)", err)
+	}
+	want := regexp.MustCompile(`(?m)^  commit +1 .*\n  tree +1 .*\n  blob +1 .*\n  tag +1 .*\n  unreachable +0 +0 bytes$`)
+	if !want.MatchString(stdout) {
+		t.Errorf("Stdout should match %s: (got: %q)", want, stdout)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		const patch = "diff --git a/file_01 b/file_01\nindex 2f69dae..59e0b30 100644\n" +
+			"--- a/file_01\n+++ b/file_01\n@@ -1 +1 @@\n-a\n+b\n"
+		tests := []struct {
+			desc  string
+			stage bool
+			args  []string
+			out   wantOutput
+		}{
+			{
+				desc:  "01_index vs working tree",
+				stage: false,
+				args:  []string{},
+				out:   newWantOutput(patch, []output{}),
+			},
+			{
+				desc:  "02_HEAD vs index",
+				stage: true,
+				args:  []string{"--cached"},
+				out:   newWantOutput(patch, []output{}),
+			},
+			{
+				desc:  "03_commit vs working tree with a pathspec",
+				stage: true,
+				args:  []string{"master", "--", "file_01"},
+				out:   newWantOutput(patch, []output{}),
+			},
+			{
+				desc:  "04_numstat",
+				stage: false,
+				args:  []string{"--numstat"},
+				out:   newWantOutput("1\t1\tfile_01\n", []output{}),
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				rootPath := joinTestDir(t, "diff")
+				initPgitForTest(t)
+				t.Cleanup(func() {
+					leaveTestDir(t, rootPath)
+				})
+				if err := data.WriteFile("file_01", []byte("a\n")); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := cmd.NewCommit("test commit"); err != nil {
+					t.Fatal(err)
+				}
+				if err := data.WriteFile("file_01", []byte("b\n")); err != nil {
+					t.Fatal(err)
+				}
+				if tt.stage {
+					if _, err := execCmd(t, cmd.AddCmd, []string{"file_01"}); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				stdout, err := execCmd(t, cmd.DiffCmd, tt.args)
+
+				if err != nil {
+					t.Errorf("error should be emtpy: (error: %s)", err)
+				}
+				assertOutput(t, stdout, tt.out)
+			})
+		}
+	})
+	t.Run("ambiguous argument", func(t *testing.T) {
+		tests := []struct {
+			desc string
+			cmd  *cobra.Command
+			args []string
+		}{
+			{desc: "01_unknown revision", cmd: cmd.DiffCmd, args: []string{"nosuchbranch", "HEAD"}},
+			{desc: "02_unknown path", cmd: cmd.DiffCmd, args: []string{"HEAD", "no_such_file"}},
+			{desc: "03_log", cmd: cmd.LogCmd, args: []string{"nosuchbranch"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				rootPath := joinTestDir(t, "diff")
+				initPgitForTest(t)
+				t.Cleanup(func() {
+					leaveTestDir(t, rootPath)
+				})
+				commitFilesForTest(t, testCommit{content: "a\n"})
+
+				stdout, err := execCmd(t, tt.cmd, tt.args)
+
+				if !errors.Is(err, cmd.ErrAmbiguousArg) {
+					t.Errorf("error should be ErrAmbiguousArg: (stdout: %q, error: %v)", stdout, err)
+				}
+			})
+		}
+	})
+	t.Run("abbreviated oid", func(t *testing.T) {
+		rootPath := joinTestDir(t, "diff")
+		initPgitForTest(t)
+		t.Cleanup(func() {
+			leaveTestDir(t, rootPath)
+		})
+		oids := commitFilesForTest(t, testCommit{content: "a\n"}, testCommit{content: "b\n"})
+
+		stdout, err := execCmd(t, cmd.DiffCmd, expandOidArgs([]string{"--name-only", "{1:7}", "{2:7}"}, oids))
+
+		if err != nil {
+			t.Errorf("error should be emtpy: (error: %s)", err)
+		}
+		if want := "file_01\n"; stdout != want {
+			t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+		}
+	})
+	t.Run("ambiguous oid", func(t *testing.T) {
+		rootPath := joinTestDir(t, "diff")
+		initPgitForTest(t)
+		t.Cleanup(func() {
+			leaveTestDir(t, rootPath)
+		})
+		commitFilesForTest(t, testCommit{content: "a\n"})
+		for _, oid := range []string{"abcd" + strings.Repeat("0", 36), "abcd" + strings.Repeat("1", 36)} {
+			if err := data.WriteFile(filepath.Join(cmd.ObjDir, oid), []byte("blob")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		stdout, err := execCmd(t, cmd.DiffCmd, []string{"abcd", "HEAD"})
+
+		if !errors.Is(err, data.ErrAmbiguousOid) {
+			t.Errorf("error should be ErrAmbiguousOid: (stdout: %q, error: %v)", stdout, err)
+		}
+	})
+}
+
+func TestApply(t *testing.T) {
+	const patch = "diff --git a/file_01 b/file_01\nindex %s..0000000 100644\n" +
+		"--- a/file_01\n+++ b/file_01\n@@ -1,3 +1,3 @@\n-a\n+b\n x\n c\n"
+	tests := []struct {
+		desc    string
+		content string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{desc: "01_apply", content: "a\nx\nc\n", args: []string{"patch"}, want: "b\nx\nc\n"},
+		{desc: "02_reverse", content: "b\nx\nc\n", args: []string{"-R", "patch"}, want: "a\nx\nc\n"},
+		{desc: "03_check changes nothing", content: "a\nx\nc\n", args: []string{"--check", "patch"}, want: "a\nx\nc\n"},
+		{desc: "04_does not apply", content: "a\nx\nC\n", args: []string{"--fuzz", "0", "patch"}, want: "a\nx\nC\n", wantErr: true},
+		{desc: "05_3way", content: "a\nx\nC\n", args: []string{"--fuzz", "0", "--3way", "patch"}, want: "b\nx\nC\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rootPath := joinTestDir(t, "apply")
+			initPgitForTest(t)
+			t.Cleanup(func() {
+				leaveTestDir(t, rootPath)
+			})
+			//the blob of the old side of the patch for the three-way merge
+			oid, err := cmd.SaveHashObj([]byte("a\nx\nc\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := data.WriteFile("patch", []byte(fmt.Sprintf(patch, oid[:7]))); err != nil {
+				t.Fatal(err)
+			}
+			if err := data.WriteFile("file_01", []byte(tt.content)); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = execCmd(t, cmd.ApplyCmd, tt.args)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error should be %t: (error: %v)", tt.wantErr, err)
+			}
+			got, err := os.ReadFile("file_01")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("file should be patched: (got=%q, want=%q)", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPatchAndAm(t *testing.T) {
+	rootPath := joinTestDir(t, "format-patch")
+	initPgitForTest(t)
+	t.Cleanup(func() {
+		leaveTestDir(t, rootPath)
+	})
+	conf := data.Config{data.ConfigUserName: "Taro Pgit", data.ConfigUserEmail: "taro@example.com"}
+	if err := conf.Write(data.ConfigPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.WriteFile("file_01", []byte("1\n2\n3\n")); err != nil {
+		t.Fatal(err)
+	}
+	base, err := cmd.NewCommit("first commit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.WriteFile("file_01", []byte("1\ntwo\n3\n")); err != nil {
+		t.Fatal(err)
+	}
+	oid, err := cmd.NewCommit("change file_01\n\nthe reason of the change")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := data.GetCommit(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patchDir := filepath.Join(rootPath, "patches")
+
+	stdout, err := execCmd(t, cmd.FormatPatchCmd, []string{"-o", patchDir, base})
+
+	if err != nil {
+		t.Errorf("error should be nil: (error: %s)", err)
+	}
+	patchPath := filepath.Join(patchDir, "0001-change-file_01.patch")
+	if stdout != patchPath+"\n" {
+		t.Errorf("Stdout should be equal: (got=%s, want=%s)", stdout, patchPath)
+	}
+
+	//another repository with the first commit, but without the identity of the author
+	amPath := filepath.Join(rootPath, "am")
+	if err := os.Mkdir(amPath, os.ModeDir|0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(amPath); err != nil {
+		t.Fatal(err)
+	}
+	initPgitForTest(t)
+	if err := data.WriteFile("file_01", []byte("1\n2\n3\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmd.NewCommit("first commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = execCmd(t, cmd.AmCmd, []string{patchPath})
+
+	if err != nil {
+		t.Errorf("error should be nil: (error: %s)", err)
+	}
+	got, err := os.ReadFile("file_01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "1\ntwo\n3\n" {
+		t.Errorf("file should be patched: (got=%q)", got)
+	}
+	head, err := data.ResolveRefOid(data.RefHEADPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := data.GetCommit(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Msg != want.Msg || c.Author.Ident() != want.Author.Ident() || !c.Author.When.Equal(want.Author.When) {
+		t.Errorf("commit should keep the message and the author:\n{ got: %+v, want: %+v }", c, want)
+	}
+	if err := os.Chdir(rootPath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevList(t *testing.T) {
+	tests := []struct {
+		desc string
+		args []string
+		want string //the oids of the commits are written as placeholders (see expandOids)
+	}{
+		{desc: "01_HEAD", args: []string{}, want: "{3}\n{2}\n{1}\n"},
+		{desc: "02_range", args: []string{"{1}..{3}"}, want: "{3}\n{2}\n"},
+		{desc: "03_exclude", args: []string{"^{2}", "HEAD"}, want: "{3}\n"},
+		{desc: "04_symmetric range", args: []string{"{3}...{1}"}, want: "{3}\n{2}\n"},
+		{desc: "05_reverse", args: []string{"--reverse"}, want: "{1}\n{2}\n{3}\n"},
+		{desc: "06_grep", args: []string{"--grep", "^sec"}, want: "{2}\n"},
+		{desc: "07_count", args: []string{"--count", "--no-merges"}, want: "3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rootPath := joinTestDir(t, "rev-list")
+			initPgitForTest(t)
+			t.Cleanup(func() {
+				leaveTestDir(t, rootPath)
+			})
+			oids := commitFilesForTest(t, testCommit{content: "first"}, testCommit{content: "second"}, testCommit{content: "third"})
+
+			stdout, err := execCmd(t, cmd.RevListCmd, expandOidArgs(tt.args, oids))
+
+			if err != nil {
+				t.Errorf("error should be emtpy: (error: %s)", err)
+			}
+			if want := expandOids(tt.want, oids); stdout != want {
+				t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+			}
+		})
+	}
+}
+
+func TestBlame(t *testing.T) {
+	tests := []struct {
+		desc string
+		args []string
+		want string //the oids of the commits are written as placeholders (see expandOids)
+	}{
+		{desc: "01_default", args: []string{"file_01"}, want: "{1:7} (Taro 1) one\n{2:7} (Taro 2) TWO\n{1:7} (Taro 3) three\n"},
+		{desc: "02_line range", args: []string{"-L", "2,+1", "file_01"}, want: "{2:7} (Taro 2) TWO\n"},
+		{desc: "03_revision", args: []string{"-L", "2", "file_01", "{1}"}, want: "{1:7} (Taro 2) two\n{1:7} (Taro 3) three\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rootPath := joinTestDir(t, "blame")
+			initPgitForTest(t)
+			t.Cleanup(func() {
+				leaveTestDir(t, rootPath)
+			})
+			oids := commitFilesForTest(t,
+				testCommit{content: "one\ntwo\nthree\n", msg: "change file_01", author: "Taro <taro@example.com>"},
+				testCommit{content: "one\nTWO\nthree\n", msg: "change file_01"},
+			)
+
+			stdout, err := execCmd(t, cmd.BlameCmd, expandOidArgs(tt.args, oids))
+
+			if err != nil {
+				t.Errorf("error should be emtpy: (error: %s)", err)
+			}
+			//the dates depend on the time the commits are made
+			stdout = regexp.MustCompile(` \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} [+-]\d{4}`).ReplaceAllString(stdout, "")
+			if want := expandOids(tt.want, oids); stdout != want {
+				t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+			}
+		})
+	}
+}
+
+func TestBisect(t *testing.T) {
+	rootPath := joinTestDir(t, "bisect")
+	initPgitForTest(t)
+	t.Cleanup(func() {
+		leaveTestDir(t, rootPath)
+	})
+	var commits []testCommit
+	for i, content := range []string{"ok", "ok", "bug", "bug", "bug"} {
+		commits = append(commits, testCommit{content: content, msg: fmt.Sprintf("change %d", i+1)})
+	}
+	oids := commitFilesForTest(t, commits...)
+	head, err := os.ReadFile(data.RefHEADPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(c *cobra.Command, args ...string) string {
+		t.Helper()
+		stdout, err := execCmd(t, c, expandOidArgs(args, oids))
+		if err != nil {
+			t.Fatalf("error should be emtpy: (error: %s)", err)
+		}
+		return stdout
+	}
+	checkFile := func(want string) {
+		t.Helper()
+		if content, err := os.ReadFile("file_01"); err != nil || string(content) != want {
+			t.Errorf("file_01 should be %q: (got: %q, error: %v)", want, content, err)
+		}
+	}
+
+	steps := []struct {
+		cmd  *cobra.Command
+		args []string
+		want string
+	}{
+		{cmd: cmd.BisectStartCmd, want: "status: waiting for both good and bad commits\n"},
+		{cmd: cmd.BisectBadCmd, want: "status: waiting for good commit(s), bad commit known\n"},
+		{cmd: cmd.BisectGoodCmd, args: []string{"{1}"}, want: "Bisecting: 1 revision(s) left to test after this (roughly 1 step(s))\n[{3}] change 3\n"},
+	}
+	for _, s := range steps {
+		if got, want := run(s.cmd, s.args...), expandOids(s.want, oids); got != want {
+			t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", got, want)
+		}
+	}
+	checkFile("bug")
+
+	stdout := run(cmd.BisectRunCmd, "sh", "-c", "! grep -q bug file_01")
+	if want := expandOids("{3} is the first bad commit\n", oids); !strings.Contains(stdout, "running sh -c ! grep -q bug file_01\n") || !strings.Contains(stdout, want) {
+		t.Errorf("Stdout should find the first bad commit:\n{ got: %q }", stdout)
+	}
+	log := run(cmd.BisectLogCmd)
+	if want := expandOids("# good: [{2}] change 2\npgit bisect good {2}\n", oids); !strings.HasPrefix(log, "pgit bisect start\n") || !strings.HasSuffix(log, want) {
+		t.Errorf("the log should record the marks:\n{ got: %q }", log)
+	}
+	if err := data.WriteFile("bisect.log", []byte(log)); err != nil {
+		t.Fatal(err)
+	}
+
+	run(cmd.BisectResetCmd)
+	checkFile("bug")
+	if got, err := os.ReadFile(data.RefHEADPath); err != nil || string(got) != string(head) {
+		t.Errorf("HEAD should be back: (got: %q, want: %q, error: %v)", got, head, err)
+	}
+	if _, err := os.Stat(data.BisectStartPath); !os.IsNotExist(err) {
+		t.Errorf("the bisection should end: (error: %v)", err)
+	}
+
+	stdout = run(cmd.BisectReplayCmd, "bisect.log")
+	if want := expandOids("{3} is the first bad commit\n", oids); !strings.HasPrefix(stdout, want) {
+		t.Errorf("replay should find the first bad commit again:\n{ got: %q }", stdout)
+	}
+	run(cmd.BisectResetCmd)
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		desc    string
+		args    []string
+		dirty   bool
+		want    string //the oids of the commits are written as placeholders (see expandOids)
+		wantErr bool
+	}{
+		{desc: "01_lightweight tag", args: []string{"--tags"}, want: "v1-2-g{3:7}\n"},
+		{desc: "02_abbrev", args: []string{"--tags", "--abbrev", "10"}, want: "v1-2-g{3:10}\n"},
+		{desc: "03_tagged commit", args: []string{"--tags", "{1}"}, want: "v1\n"},
+		{desc: "04_always", args: []string{"--always"}, want: "{3:7}\n"},
+		{desc: "05_dirty", args: []string{"--tags", "--dirty"}, dirty: true, want: "v1-2-g{3:7}-dirty\n"},
+		{desc: "06_clean", args: []string{"--tags", "--dirty"}, want: "v1-2-g{3:7}\n"},
+		{desc: "07_no annotated tags", args: []string{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rootPath := joinTestDir(t, "describe")
+			initPgitForTest(t)
+			t.Cleanup(func() {
+				leaveTestDir(t, rootPath)
+			})
+			oids := commitFilesForTest(t, testCommit{content: "first"}, testCommit{content: "second"}, testCommit{content: "third"})
+			if err := data.WriteFile(filepath.Join(cmd.TagDir, "v1"), []byte(oids[0])); err != nil {
+				t.Fatal(err)
+			}
+			if tt.dirty {
+				if err := data.WriteFile("file_01", []byte("changed")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stdout, err := execCmd(t, cmd.DescribeCmd, expandOidArgs(tt.args, oids))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("error should not be nil: (stdout: %q)", stdout)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("error should be emtpy: (error: %s)", err)
+			}
+			if want := expandOids(tt.want, oids); stdout != want {
+				t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+			}
+		})
+	}
+}
+
+func TestShortlogAndChangelog(t *testing.T) {
+	rootPath := joinTestDir(t, "shortlog")
+	initPgitForTest(t)
+	t.Cleanup(func() {
+		leaveTestDir(t, rootPath)
+	})
+	oids := commitFilesForTest(t,
+		testCommit{content: "0", msg: "initial commit", author: "Taro <taro@example.com>"},
+		testCommit{content: "1", msg: "feat(cli): add --verbose", author: "taro <taro@old.example.com>"},
+		testCommit{content: "2", msg: "fix!: reject empty input", author: "Hanako <hanako@example.com>"},
+	)
+	if err := data.WriteFile(data.MailmapFile, []byte("Taro <taro@example.com> <taro@old.example.com>\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("01_shortlog", func(t *testing.T) {
+		stdout, err := execCmd(t, cmd.ShortlogCmd, []string{})
+
+		if err != nil {
+			t.Errorf("error should be emtpy: (error: %s)", err)
+		}
+		want := "Hanako (1):\n      fix!: reject empty input\n\nTaro (2):\n      initial commit\n      feat(cli): add --verbose\n\n"
+		if stdout != want {
+			t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+		}
+	})
+	t.Run("02_shortlog numbered summary", func(t *testing.T) {
+		stdout, err := execCmd(t, cmd.ShortlogCmd, []string{"-n", "-s", "-e"})
+
+		if err != nil {
+			t.Errorf("error should be emtpy: (error: %s)", err)
+		}
+		if want := "     2\tTaro <taro@example.com>\n     1\tHanako <hanako@example.com>\n"; stdout != want {
+			t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+		}
+	})
+	t.Run("03_changelog", func(t *testing.T) {
+		stdout, err := execCmd(t, cmd.ChangelogCmd, expandOidArgs([]string{"{1}..HEAD"}, oids))
+
+		if err != nil {
+			t.Errorf("error should be emtpy: (error: %s)", err)
+		}
+		want := expandOids("## {1}..HEAD\n\n### Breaking Changes\n\n- reject empty input ({3:7})\n\n"+
+			"### Features\n\n- **cli:** add --verbose ({2:7})\n\n### Bug Fixes\n\n- reject empty input ({3:7})\n\n"+
+			"### Contributors\n\n- Hanako (1)\n- Taro (1)\n", oids)
+		if stdout != want {
+			t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+		}
+	})
+	t.Run("04_changelog without range", func(t *testing.T) {
+		if stdout, err := execCmd(t, cmd.ChangelogCmd, []string{}); err == nil {
+			t.Errorf("error should not be nil: (stdout: %q)", stdout)
+		}
+	})
+}
+
+func TestSignedCommit(t *testing.T) {
+	rootPath := joinTestDir(t, "signedCommit")
+	initPgitForTest(t)
+	t.Cleanup(func() {
+		leaveTestDir(t, rootPath)
+	})
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, ed25519.SeedSize))
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.WriteFile(filepath.Join(data.PgitDirBase, "signing_key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err != nil {
+		t.Fatal(err)
+	}
+	conf := data.Config{data.ConfigUserName: "Taro", data.ConfigUserEmail: "taro@example.com", data.ConfigSigningKey: filepath.Join(data.PgitDirBase, "signing_key")}
+	if err := conf.Write(data.ConfigPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.WriteFile("file_01", []byte("signed")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execCmd(t, cmd.CommitCmd, []string{"-S", "-m", "signed"}); err != nil {
+		t.Fatalf("error should be emtpy: (error: %s)", err)
+	}
+	signed, err := data.ResolveRefOid(data.RefHEADPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned := commitFilesForTest(t, testCommit{content: "unsigned"})[0]
+	fingerprint := data.Fingerprint(key.Public().(ed25519.PublicKey))
+
+	t.Run("01_unknown key", func(t *testing.T) {
+		if stdout, err := execCmd(t, cmd.VerifyCommitCmd, []string{signed}); err == nil {
+			t.Errorf("error should not be nil: (stdout: %q)", stdout)
+		}
+	})
+	keyring := fmt.Sprintf("taro@example.com ed25519 %s\n", base64.StdEncoding.EncodeToString(pub))
+	if err := data.WriteFile(data.KeyringPath, []byte(keyring)); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("02_good signature", func(t *testing.T) {
+		stdout, err := execCmd(t, cmd.VerifyCommitCmd, []string{signed})
+
+		if err != nil {
+			t.Errorf("error should be emtpy: (error: %s)", err)
+		}
+		if want := "Good signature from \"taro@example.com\" with key " + fingerprint + "\n"; stdout != want {
+			t.Errorf("Stdout should be equal:\n{ got: %q, want: %q }", stdout, want)
+		}
+	})
+	t.Run("03_no signature", func(t *testing.T) {
+		if stdout, err := execCmd(t, cmd.VerifyCommitCmd, []string{unsigned}); err == nil {
+			t.Errorf("error should not be nil: (stdout: %q)", stdout)
+		}
+	})
+	t.Run("04_log", func(t *testing.T) {
+		stdout, err := execCmd(t, cmd.LogCmd, []string{"--show-signature", "--oneline"})
+
+		if err != nil {
+			t.Errorf("error should be emtpy: (error: %s)", err)
+		}
+		want := regexp.MustCompile(`^[0-9a-f]{7} \(HEAD -> master\) unsigned\nGood signature from "taro@example.com" with key \S+\n[0-9a-f]{7} signed\n$`)
+		if !want.MatchString(stdout) {
+			t.Errorf("Stdout should match %s: (got: %q)", want, stdout)
+		}
+	})
+	t.Run("05_message with a trailing newline", func(t *testing.T) {
+		if err := data.WriteFile("file_01", []byte("trailing newline")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := execCmd(t, cmd.CommitCmd, []string{"-S", "-m", "trailing newline\n"}); err != nil {
+			t.Fatal(err)
+		}
+
+		stdout, err := execCmd(t, cmd.VerifyCommitCmd, []string{"HEAD"})
+
+		if err != nil {
+			t.Errorf("error should be emtpy: (error: %s)", err)
+		}
+		if !strings.HasPrefix(stdout, "Good signature") {
+			t.Errorf("signature should be good: (stdout: %q)", stdout)
+		}
+	})
+	t.Run("06_injected header", func(t *testing.T) {
+		content, err := data.ReadAllFileContent(filepath.Join(cmd.ObjDir, signed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, rest, _ := strings.Cut(string(content), "\n")
+		injected, err := cmd.SaveHashObj([]byte(tree + "\nx-injected evil\n" + rest))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if stdout, err := execCmd(t, cmd.VerifyCommitCmd, []string{injected}); err == nil {
+			t.Errorf("error should not be nil: (stdout: %q)", stdout)
+		}
+	})
+}
@@ -4,8 +4,10 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"bytes"
+	"crypto/ed25519"
 	"fmt"
+	"os/user"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/taimats/pgit/data"
@@ -13,13 +15,30 @@ import (
 
 // commitCmd represents the commit command
 var commitCmd = &cobra.Command{
-	Use:   "commit",
+	Use:   "commit [-S] [-m <message>]",
 	Short: "create a commit object",
+	Long: `commit the index onto HEAD if any change is staged by add (or apply --cached), or the whole working tree
+otherwise. With -S (or the config "commit.sign" set to true), the commit
+is signed with the ed25519 key in the config "user.signingKey", either in PKCS #8 PEM or in the OpenSSH format.
+The signature can be checked by verify-commit and log --show-signature.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := CheckPgitInit(); err != nil {
 			return err
 		}
-		oid, err := NewCommit(message)
+		msg, _ := cmd.Flags().GetString("message")
+		conf, err := readConfig()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		sign, _ := cmd.Flags().GetBool("sign")
+		sign = sign || conf.Get(data.ConfigCommitSign, "") == "true"
+		var key ed25519.PrivateKey
+		if sign {
+			if key, err = readSigningKey(); err != nil {
+				return err
+			}
+		}
+		oid, err := NewSignedCommit(msg, key)
 		if err != nil {
 			return err
 		}
@@ -29,42 +48,112 @@ var commitCmd = &cobra.Command{
 }
 
 func NewCommit(msg string) (commitOid string, err error) {
-	treeOid, err := data.WriteTree(".", ObjDir)
+	commitOid, err = NewSignedCommit(msg, nil)
 	if err != nil {
 		return "", fmt.Errorf("NewCommit: %w", err)
 	}
+	return commitOid, nil
+}
+
+// NewSignedCommit is NewCommit signing the commit with the key, unless it is nil.
+func NewSignedCommit(msg string, key ed25519.PrivateKey) (commitOid string, err error) {
+	lock, err := lockRepo()
+	if err != nil {
+		return "", fmt.Errorf("NewSignedCommit: %w", err)
+	}
+	defer lock.Release()
+
+	commitOid, err = writeCommit(msg, nil, key)
+	if err != nil {
+		return "", fmt.Errorf("NewSignedCommit: %w", err)
+	}
+	return commitOid, nil
+}
+
+// commits the index onto HEAD if anything is staged, or the whole working tree otherwise. The author is
+// the committer (= the current user) if nil. The commit is signed with the key unless it is nil.
+// The caller must hold the repository lock.
+func writeCommit(msg string, author *data.Signature, key ed25519.PrivateKey) (commitOid string, err error) {
+	treeOid, err := writeCommitTree()
+	if err != nil {
+		return "", fmt.Errorf("writeCommit: %w", err)
+	}
 	ref, err := data.NewRef(data.RefHEADPath)
 	if err != nil {
-		return "", fmt.Errorf("NewCommit: %w", err)
+		return "", fmt.Errorf("writeCommit: %w", err)
 	}
 	if ref.IsSymbolic {
 		ref, err = ref.ResolveSymbolic(ref.Next)
 		if err != nil {
-			return "", fmt.Errorf("NewCommit: %w", err)
+			return "", fmt.Errorf("writeCommit: %w", err)
 		}
 	}
-	var buf bytes.Buffer
-	fmt.Fprintf(&buf, "%s %s\n", ObjTypeTree, treeOid)
-	if ref.Oid != "" {
-		fmt.Fprintf(&buf, "%s %s\n", "parent", ref.Oid)
+	committer, err := currentSignature()
+	if err != nil {
+		return "", fmt.Errorf("writeCommit: %w", err)
+	}
+	c := &data.Commit{TreeOid: treeOid, Parent: ref.Oid, Author: committer, Committer: committer, Msg: msg}
+	if author != nil {
+		c.Author = *author
+	}
+	if key != nil {
+		if c.Signature, err = data.Sign(key, c.Payload()); err != nil {
+			return "", fmt.Errorf("writeCommit: %w", err)
+		}
 	}
-	buf.WriteString("\n")
-	buf.WriteString(msg)
 
-	commitOid, err = SaveHashObj(buf.Bytes())
+	commitOid, err = SaveHashObj(c.Encode())
 	if err != nil {
-		return "", fmt.Errorf("NewCommit: %w", err)
+		return "", fmt.Errorf("writeCommit: %w", err)
 	}
 	if err := ref.Update(commitOid); err != nil {
-		return "", fmt.Errorf("NewCommit: %w", err)
+		return "", fmt.Errorf("writeCommit: %w", err)
+	}
+	//the index is the same as the tree of the new commit now, so nothing is left staged
+	if err := resetIndex(); err != nil {
+		return "", fmt.Errorf("writeCommit: %w", err)
 	}
 	return commitOid, nil
 }
 
-var message string
+// saves the tree to be committed. The index is saved as it is if it exists (= changes are staged
+// by add or apply --cached), and the whole working tree is saved otherwise.
+func writeCommitTree() (treeOid string, err error) {
+	idx, err := data.ReadIndex(data.IndexPath)
+	if err != nil {
+		return "", fmt.Errorf("writeCommitTree: %w", err)
+	}
+	if idx != nil {
+		treeOid, err = idx.WriteTree(ObjDir)
+	} else {
+		treeOid, err = data.WriteTree(".", ObjDir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("writeCommitTree: %w", err)
+	}
+	return treeOid, nil
+}
+
+// returns the identity of the current user (= user.name and user.email in the config) at the moment.
+// Without user.name, the login name is used instead.
+func currentSignature() (data.Signature, error) {
+	conf, err := readConfig()
+	if err != nil {
+		return data.Signature{}, fmt.Errorf("currentSignature: %w", err)
+	}
+	name := conf.Get(data.ConfigUserName, "")
+	if name == "" {
+		name = "unknown"
+		if u, err := user.Current(); err == nil && u.Username != "" {
+			name = u.Username
+		}
+	}
+	return data.NewSignature(name, conf.Get(data.ConfigUserEmail, ""), time.Now()), nil
+}
 
 func init() {
 	rootCmd.AddCommand(commitCmd)
 
-	commitCmd.Flags().StringP("message", "m", message, "add a message")
+	commitCmd.Flags().StringP("message", "m", "", "add a message")
+	commitCmd.Flags().BoolP("sign", "S", false, "sign the commit with the key in the config \"user.signingKey\"")
 }
@@ -0,0 +1,62 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config [<key> [<value>]]",
+	Short: "get and set repository options",
+	Args:  cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		conf, err := readConfig()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		unset, _ := cmd.Flags().GetBool("unset")
+		switch {
+		case len(args) == 0:
+			keys := make([]string, 0, len(conf))
+			for k := range conf {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Printf("%s %s\n", k, conf[k])
+			}
+			return nil
+		case len(args) == 1 && unset:
+			delete(conf, args[0])
+		case len(args) == 1:
+			v, ok := conf[args[0]]
+			if !ok {
+				return fmt.Errorf("no such a key: %s", args[0])
+			}
+			fmt.Println(v)
+			return nil
+		default:
+			conf[args[0]] = args[1]
+		}
+		if err := conf.Write(data.ConfigPath); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+
+	configCmd.Flags().Bool("unset", false, "remove the key from the config")
+}
@@ -0,0 +1,50 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// countObjectsCmd represents the count-objects command
+var countObjectsCmd = &cobra.Command{
+	Use:   "count-objects",
+	Short: "count objects and their disk consumption",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		count, err := data.CountObjects(ObjDir)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		if !verbose {
+			fmt.Printf("%d objects, %d kilobytes\n", count.Count, kib(count.Size))
+			return nil
+		}
+		fmt.Printf("count: %d\n", count.Count)
+		fmt.Printf("size: %d\n", kib(count.Size))
+		fmt.Printf("garbage: %d\n", count.Garbage)
+		fmt.Printf("size-garbage: %d\n", kib(count.GarbageSize))
+		fmt.Printf("lfs-count: %d\n", count.LFSCount)
+		fmt.Printf("size-lfs: %d\n", kib(count.LFSSize))
+		return nil
+	},
+}
+
+// converts bytes into kilobytes, rounding up
+func kib(size int64) int64 {
+	return (size + 1023) / 1024
+}
+
+func init() {
+	rootCmd.AddCommand(countObjectsCmd)
+
+	countObjectsCmd.Flags().BoolP("verbose", "v", false, "report more details (sizes in kilobytes)")
+}
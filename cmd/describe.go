@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// describeCmd represents the describe command
+var describeCmd = &cobra.Command{
+	Use:   "describe [--tags] [--always] [--dirty[=<mark>]] [--match <pattern>] [--abbrev <n>] [<revision>...]",
+	Short: "name commits after the nearest tag, like v1.2.0-14-gabc1234",
+	Long: `name each revision (default: HEAD) after the nearest tag reachable from it, followed by the number of
+the commits on top of the tag and the abbreviated oid after "g". A tagged commit is named by the tag alone.
+Only annotated tags are used unless --tags is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		opts := data.NewDescribeOptions()
+		opts.Tags, _ = cmd.Flags().GetBool("tags")
+		opts.Always, _ = cmd.Flags().GetBool("always")
+		opts.Match, _ = cmd.Flags().GetStringArray("match")
+		opts.Abbrev, _ = cmd.Flags().GetInt("abbrev")
+		if opts.Abbrev < 0 {
+			return fmt.Errorf("invalid --abbrev: %d", opts.Abbrev)
+		}
+		dirty, _ := cmd.Flags().GetString("dirty")
+		revs := cmd.Flags().Args()
+		if dirty != "" && len(revs) > 0 {
+			return errors.New("--dirty is incompatible with revisions")
+		}
+		if len(revs) == 0 {
+			revs = []string{HEAD}
+		}
+		tags, err := tagRefs()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+
+		var names []string
+		for _, rev := range revs {
+			oid, err := resolveRev(rev)
+			if err != nil {
+				return err
+			}
+			name, err := data.Describe(oid, tags, opts)
+			if errors.Is(err, data.ErrNoTags) {
+				return noTagsError(oid, tags, opts)
+			}
+			if err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			names = append(names, name)
+		}
+		if dirty != "" {
+			changed, err := isDirty()
+			if err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			if changed {
+				names[0] += dirty
+			}
+		}
+		fmt.Print(strings.Join(names, "\n") + "\n")
+		return nil
+	},
+}
+
+// returns all the tags under refs/tags pointing to commits, sorted by name. Annotated tags are peeled
+// to the commits they point to.
+func tagRefs() ([]data.TagRef, error) {
+	refs, err := data.ListRefs()
+	if err != nil {
+		return nil, fmt.Errorf("tagRefs: %w", err)
+	}
+	var tags []data.TagRef
+	for name, oid := range refs {
+		tag, ok := strings.CutPrefix(name, "refs/tags/")
+		if !ok {
+			continue
+		}
+		commit, objType, err := data.PeelTag(oid)
+		if err != nil {
+			return nil, fmt.Errorf("tagRefs: %w", err)
+		}
+		switch objType {
+		case "":
+			tags = append(tags, data.TagRef{Name: tag, Oid: commit})
+		case data.ObjTypeCommit:
+			tags = append(tags, data.TagRef{Name: tag, Oid: commit, Annotated: true})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// returns the error for a commit no tag describes, suggesting --tags if only lightweight tags are found
+func noTagsError(oid string, tags []data.TagRef, opts *data.DescribeOptions) error {
+	if !opts.Tags {
+		for _, t := range tags {
+			if !t.Annotated {
+				return fmt.Errorf("no annotated tags can describe %s, but there are lightweight tags: try --tags", oid)
+			}
+		}
+	}
+	return fmt.Errorf("no tags can describe %s", oid)
+}
+
+// reports whether the index or the tracked files in the working tree differ from HEAD
+func isDirty() (bool, error) {
+	head, err := headTree()
+	if err != nil {
+		return false, fmt.Errorf("isDirty: %w", err)
+	}
+	idx, err := loadIndex()
+	if err != nil {
+		return false, fmt.Errorf("isDirty: %w", err)
+	}
+	attrs, err := data.ReadAttributes(data.AttributesFile)
+	if err != nil {
+		return false, fmt.Errorf("isDirty: %w", err)
+	}
+	work, err := idx.WorkingTree(".", attrs)
+	if err != nil {
+		return false, fmt.Errorf("isDirty: %w", err)
+	}
+	return len(data.DiffTreeEntries(head, idx.Tree())) > 0 || len(data.DiffTreeEntries(idx.Tree(), work)) > 0, nil
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+
+	describeCmd.Flags().Bool("tags", false, "use lightweight tags as well as annotated ones")
+	describeCmd.Flags().Bool("always", false, "print the abbreviated oid when no tag describes the commit")
+	describeCmd.Flags().String("dirty", "", "append the mark (default: -dirty) if the working tree has changes")
+	describeCmd.Flags().Lookup("dirty").NoOptDefVal = "-dirty"
+	describeCmd.Flags().StringArray("match", nil, "use only the tags matching the glob pattern (repeatable)")
+	describeCmd.Flags().Int("abbrev", data.DefaultAbbrev, "the hex digits of the abbreviated oid, where 0 prints the tag alone")
+}
@@ -0,0 +1,298 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+var (
+	ErrTooManyRevs  = errors.New("too many revisions")
+	ErrAmbiguousArg = errors.New("ambiguous argument")
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [--cached] [<rev> [<rev>]] [--] [<path>...]",
+	Short: "show changes between the working tree, the index and commits",
+	Long: `show changes between the working tree, the index and commits:
+  pgit diff                 the index vs the working tree
+  pgit diff --cached [rev]  the commit (default: HEAD) vs the index
+  pgit diff <rev>           the commit vs the working tree
+  pgit diff <a> <b>, a..b   the commit a vs the commit b
+  pgit diff a...b           the merge base of a and b vs the commit b
+Paths after the revisions (or after "--") limit the files to compare.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		threshold, _ := cmd.Flags().GetString("big-file-threshold")
+		if err := setBigFileThreshold(threshold); err != nil {
+			return err
+		}
+		cached, _ := cmd.Flags().GetBool("cached")
+		opts, err := diffOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		revs, paths, err := splitRevsAndPaths(cmd.Flags().Args(), cmd.Flags().ArgsLenAtDash())
+		if err != nil {
+			return err
+		}
+		opts.Pathspecs = paths
+		from, to, err := diffSides(revs, cached, opts)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if _, err := writeDiff(&buf, cmd, opts, from, to); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		fmt.Print(buf.String())
+		return nil
+	},
+}
+
+// the summary formats of diffs chosen by flags, in the order they are written
+var diffFormats = []string{"name-only", "name-status", "numstat", "stat", "shortstat"}
+
+// adds the flags of the summary formats shared by the commands printing diffs
+func addDiffFormatFlags(c *cobra.Command) {
+	c.Flags().Bool("name-only", false, "show only the names of changed files")
+	c.Flags().Bool("name-status", false, "show the names of changed files with their status (e.g. M, R087)")
+	c.Flags().Bool("numstat", false, "show the numbers of inserted and deleted lines per file, separated by tabs")
+	c.Flags().Bool("stat", false, "show the number of changed lines per file with a histogram")
+	c.Flags().Bool("shortstat", false, "show only the total of --stat")
+	c.Flags().Bool("binary", false, "write binary patches of binary files, which apply can consume")
+}
+
+// returns the summary formats chosen by the flags
+func chosenDiffFormats(c *cobra.Command) []string {
+	var formats []string
+	for _, f := range diffFormats {
+		if on, _ := c.Flags().GetBool(f); on {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// writes the changes between the trees in the summary formats chosen by the flags,
+// or as patches if none is chosen. Returns the number of changed files.
+func writeDiff(buf *bytes.Buffer, c *cobra.Command, opts *data.DiffOptions, from data.Tree, to data.Tree) (int, error) {
+	entries, err := opts.DiffTreeEntries(from, to, ObjDir)
+	if err != nil {
+		return 0, fmt.Errorf("writeDiff: %w", err)
+	}
+	formats := chosenDiffFormats(c)
+	if len(formats) == 0 {
+		diffs, err := opts.Patches(entries, ObjDir)
+		if err != nil {
+			return 0, fmt.Errorf("writeDiff: %w", err)
+		}
+		for _, diff := range diffs {
+			buf.WriteString(diff.Diff)
+		}
+		return len(entries), nil
+	}
+	var stats []*data.FileStat
+	if slices.Contains(formats, "numstat") || slices.Contains(formats, "stat") || slices.Contains(formats, "shortstat") {
+		stats, err = opts.DiffStats(entries, ObjDir)
+		if err != nil {
+			return 0, fmt.Errorf("writeDiff: %w", err)
+		}
+	}
+	for _, f := range formats {
+		switch f {
+		case "name-only":
+			for _, e := range entries {
+				fmt.Fprintln(buf, e.Path)
+			}
+		case "name-status":
+			for _, e := range entries {
+				fmt.Fprintln(buf, e.NameStatus())
+			}
+		case "numstat":
+			data.WriteNumstat(buf, stats)
+		case "stat":
+			data.WriteStat(buf, stats, data.DefaultStatWidth)
+		case "shortstat":
+			//--stat ends with the same line
+			if !slices.Contains(formats, "stat") {
+				data.WriteShortstat(buf, stats)
+			}
+		}
+	}
+	return len(entries), nil
+}
+
+// decides the trees to compare from the revisions and the --cached flag.
+// When the new side is the working tree, opts.WorkDir is set to read the files from it.
+func diffSides(revs []string, cached bool, opts *data.DiffOptions) (from data.Tree, to data.Tree, err error) {
+	switch {
+	case len(revs) > 2:
+		return nil, nil, fmt.Errorf("%w: %s", ErrTooManyRevs, strings.Join(revs, " "))
+	case len(revs) == 2:
+		return revTrees(revs[0], revs[1])
+	case len(revs) == 1 && strings.Contains(revs[0], ".."):
+		a, b, symmetric := parseRange(revs[0])
+		if symmetric {
+			a, err = mergeBaseRev(a, b)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return revTrees(a, b)
+	}
+
+	idx, err := loadIndex()
+	if err != nil {
+		return nil, nil, fmt.Errorf("internal error: %w", err)
+	}
+	if cached {
+		to = idx.Tree()
+		if len(revs) == 1 {
+			from, err = revTree(revs[0])
+		} else {
+			from, err = headTree()
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return from, to, nil
+	}
+	from = idx.Tree()
+	if len(revs) == 1 {
+		from, err = revTree(revs[0])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	to, err = idx.WorkingTree(".", opts.Attributes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("internal error: %w", err)
+	}
+	opts.WorkDir = "."
+	return from, to, nil
+}
+
+func revTrees(a string, b string) (from data.Tree, to data.Tree, err error) {
+	from, err = revTree(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err = revTree(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// resolves the merge base of the revisions a and b into a commit oid
+func mergeBaseRev(a string, b string) (string, error) {
+	aOid, err := resolveRev(a)
+	if err != nil {
+		return "", err
+	}
+	bOid, err := resolveRev(b)
+	if err != nil {
+		return "", err
+	}
+	base, err := data.MergeBase(aOid, bOid)
+	if err != nil {
+		return "", fmt.Errorf("internal error: %w", err)
+	}
+	if base == "" {
+		return "", fmt.Errorf("no merge base: %s and %s share no history", a, b)
+	}
+	return base, nil
+}
+
+// parses a range "a..b" or "a...b" (= symmetric). An omitted side stands for HEAD.
+func parseRange(arg string) (a string, b string, symmetric bool) {
+	a, b, symmetric = strings.Cut(arg, "...")
+	if !symmetric {
+		a, b, _ = strings.Cut(arg, "..")
+	}
+	if a == "" {
+		a = HEAD
+	}
+	if b == "" {
+		b = HEAD
+	}
+	return a, b, symmetric
+}
+
+// splits the arguments into revisions and pathspecs. Without "--" (= dash < 0), the leading
+// arguments that resolve as revisions (or ranges of them) are taken as revisions, and each of the rest
+// has to be a path in the working tree or in the index, so that a mistyped revision is not taken as a path.
+func splitRevsAndPaths(args []string, dash int) (revs []string, paths []string, err error) {
+	if dash >= 0 && dash <= len(args) {
+		return args[:dash], args[dash:], nil
+	}
+	i := 0
+	for i < len(args) && isRevArg(args[i]) {
+		i++
+	}
+	revs, paths = args[:i], args[i:]
+	if len(paths) == 0 {
+		return revs, nil, nil
+	}
+	idx, err := loadIndex()
+	if err != nil {
+		return nil, nil, fmt.Errorf("internal error: %w", err)
+	}
+	for _, p := range paths {
+		if !isPathArg(p, idx) {
+			return nil, nil, fmt.Errorf("%w '%s': unknown revision or path not in the working tree (use \"--\" to separate paths from revisions)", ErrAmbiguousArg, p)
+		}
+	}
+	return revs, paths, nil
+}
+
+// reports whether the pathspec names a file in the working tree or matches a path in the index
+func isPathArg(spec string, idx data.Index) bool {
+	if fileExists(spec) {
+		return true
+	}
+	for p := range idx {
+		if data.MatchPathspec(p, spec) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRevArg(arg string) bool {
+	if rev, ok := strings.CutPrefix(arg, "^"); ok {
+		return isRevArg(rev)
+	}
+	if strings.Contains(arg, "..") {
+		a, b, _ := parseRange(arg)
+		return isRevArg(a) && isRevArg(b)
+	}
+	//an ambiguous oid prefix is still meant as a revision, so that resolving it reports the ambiguity
+	_, err := resolveRev(arg)
+	return err == nil || errors.Is(err, data.ErrAmbiguousOid)
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().Bool("cached", false, "compare the index with a commit (default: HEAD)")
+	diffCmd.Flags().IntP("unified", "U", data.DefaultContext, "show this number of unchanged lines around each change")
+	addRenameFlags(diffCmd)
+	addDiffAlgorithmFlags(diffCmd)
+	addDiffFormatFlags(diffCmd)
+	addColorFlags(diffCmd)
+	diffCmd.Flags().String("big-file-threshold", "", "skip diffs of files larger than this size (e.g. 512k, 10m, 1g)")
+}
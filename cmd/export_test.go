@@ -1,6 +1,6 @@
 package cmd
 
-//Commands
+// Commands
 var (
 	InitCmd       = initCmd
 	HashObjectCmd = hashObjCmd
@@ -11,14 +11,46 @@ var (
 	LogCmd        = logCmd
 	CheckoutCmd   = checkoutCmd
 	TagCmd        = tagCmd
-	// KCmd          = kCmd
-	BranchCmd = branchCmd
-	StatusCmd = statusCmd
-	ResetCmd  = resetCmd
-	ShowCmd   = showCmd
+	KCmd          = kCmd
+	BranchCmd     = branchCmd
+	StatusCmd     = statusCmd
+	ResetCmd      = resetCmd
+	ShowCmd       = showCmd
+	ConfigCmd     = configCmd
+	FsckCmd       = fsckCmd
+	GCCmd         = gcCmd
+	PruneCmd      = pruneCmd
+	AddCmd        = addCmd
+	DiffCmd       = diffCmd
+	ApplyCmd      = applyCmd
+	AmCmd         = amCmd
+
+	FormatPatchCmd = formatPatchCmd
+	RevListCmd     = revListCmd
+	BlameCmd       = blameCmd
+	DescribeCmd    = describeCmd
+	ShortlogCmd    = shortlogCmd
+	ChangelogCmd   = changelogCmd
+
+	VerifyCommitCmd = verifyCommitCmd
+
+	CountObjectsCmd = countObjectsCmd
+	StatsCmd        = statsCmd
+
+	BisectStartCmd  = bisectStartCmd
+	BisectBadCmd    = bisectBadCmd
+	BisectGoodCmd   = bisectGoodCmd
+	BisectRunCmd    = bisectRunCmd
+	BisectLogCmd    = bisectLogCmd
+	BisectReplayCmd = bisectReplayCmd
+	BisectResetCmd  = bisectResetCmd
+
+	LFSTrackCmd   = lfsTrackCmd
+	LFSLsFilesCmd = lfsLsFilesCmd
+	LFSPruneCmd   = lfsPruneCmd
 )
 
-//The rest other than commands
+// The rest other than commands
 var (
 	SweepDir = sweepDir
 )
@@ -0,0 +1,155 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// formatPatchCmd represents the format-patch command
+var formatPatchCmd = &cobra.Command{
+	Use:   "format-patch [-o <dir>] [--stdout] (<since> | <revision range>)",
+	Short: "write each commit as a patch in an email",
+	Long: `write each commit in the range as an email of a mailbox, which am can apply.
+A single revision <since> stands for the range <since>..HEAD.
+Each email is saved as a file named like 0001-{subject}.patch, whose path is printed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		args = cmd.Flags().Args()
+		if len(args) != 1 {
+			return errors.New("format-patch needs exactly one revision or revision range")
+		}
+		commits, err := rangeCommits(args[0])
+		if err != nil {
+			return err
+		}
+		outDir, _ := cmd.Flags().GetString("output-directory")
+		toStdout, _ := cmd.Flags().GetBool("stdout")
+		if !toStdout {
+			if err := os.MkdirAll(outDir, 0755); err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+		}
+
+		opts := data.NewDiffOptions()
+		opts.Binary = true
+		var out bytes.Buffer
+		for i, oid := range commits {
+			m, err := commitMail(oid, opts)
+			if err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			var buf bytes.Buffer
+			m.Write(&buf, i+1, len(commits))
+			if toStdout {
+				out.Write(buf.Bytes())
+				continue
+			}
+			path := filepath.Join(outDir, patchFileName(i+1, m.Subject))
+			if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			fmt.Fprintln(&out, path)
+		}
+		fmt.Print(out.String())
+		return nil
+	},
+}
+
+// returns the commits in the range "a..b" (or "a..HEAD" for a single revision a), the oldest first
+func rangeCommits(arg string) ([]string, error) {
+	a, b := arg, HEAD
+	if strings.Contains(arg, "..") {
+		var symmetric bool
+		a, b, symmetric = parseRange(arg)
+		if symmetric {
+			return nil, fmt.Errorf("symmetric range is not supported: %s", arg)
+		}
+	}
+	aOid, err := resolveRev(a)
+	if err != nil {
+		return nil, err
+	}
+	bOid, err := resolveRev(b)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := data.CommitsBetween(aOid, bOid)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// makes the mail of the commit, with the diffstat and the patches of its changes.
+// A commit recorded without its author is sent as by the current user.
+func commitMail(oid string, opts *data.DiffOptions) (*data.Mail, error) {
+	c, err := data.GetCommit(oid)
+	if err != nil {
+		return nil, fmt.Errorf("commitMail: %w", err)
+	}
+	from, to, err := commitTrees(c)
+	if err != nil {
+		return nil, fmt.Errorf("commitMail: %w", err)
+	}
+	entries, err := opts.DiffTreeEntries(from, to, ObjDir)
+	if err != nil {
+		return nil, fmt.Errorf("commitMail: %w", err)
+	}
+	stats, err := opts.DiffStats(entries, ObjDir)
+	if err != nil {
+		return nil, fmt.Errorf("commitMail: %w", err)
+	}
+	patches, err := opts.Patches(entries, ObjDir)
+	if err != nil {
+		return nil, fmt.Errorf("commitMail: %w", err)
+	}
+	var buf bytes.Buffer
+	data.WriteStat(&buf, stats, data.DefaultStatWidth)
+	buf.WriteString("\n")
+	for _, p := range patches {
+		buf.WriteString(p.Diff)
+	}
+
+	author := c.Author
+	if author.Name == "" {
+		author, err = currentSignature()
+		if err != nil {
+			return nil, fmt.Errorf("commitMail: %w", err)
+		}
+	}
+	return &data.Mail{Oid: oid, Author: author, Subject: c.Subject(), Body: c.Body(), Patch: buf.String()}, nil
+}
+
+var nonSlugRegex = regexp.MustCompile(`[^A-Za-z0-9._]+`)
+
+// returns the file name of the n-th patch like "0001-fix-the-typo.patch"
+func patchFileName(n int, subject string) string {
+	slug := strings.Trim(nonSlugRegex.ReplaceAllString(subject, "-"), "-.")
+	if len(slug) > 52 {
+		slug = strings.TrimRight(slug[:52], "-.")
+	}
+	return fmt.Sprintf("%04d-%s.patch", n, slug)
+}
+
+func init() {
+	rootCmd.AddCommand(formatPatchCmd)
+
+	formatPatchCmd.Flags().StringP("output-directory", "o", ".", "write the patch files into this directory")
+	formatPatchCmd.Flags().Bool("stdout", false, "print all the patches as a mailbox instead of writing files")
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+var ErrFsckFailed = errors.New("fsck found problems")
+
+// fsckCmd represents the fsck command
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "verify the connectivity and validity of all the objects",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		connectivityOnly, _ := cmd.Flags().GetBool("connectivity-only")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		report, err := data.Fsck(PgitDir, connectivityOnly)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+		} else {
+			for _, p := range report.Problems {
+				fmt.Println(p.String())
+			}
+		}
+		if !report.OK() {
+			return ErrFsckFailed
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fsckCmd)
+
+	fsckCmd.Flags().Bool("connectivity-only", false, "check only that referred objects exist, without hashing their content")
+	fsckCmd.Flags().Bool("json", false, "print the report in JSON")
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "clean up unreachable objects older than a grace period",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		expire, _ := cmd.Flags().GetString("prune")
+		if expire == "" {
+			conf, err := readConfig()
+			if err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			expire = conf.Get(data.ConfigPruneExpire, data.DefaultPruneExpire)
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		report, err := pruneUnreachable(expire, dryRun)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reclaimed %d bytes from %d unreachable objects (%d kept within the grace period)\n", report.Bytes, report.Objects, report.Kept)
+		return nil
+	},
+}
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "delete all unreachable objects from the object storage",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		expire, _ := cmd.Flags().GetString("expire")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		report, err := pruneUnreachable(expire, dryRun)
+		if err != nil {
+			return err
+		}
+		if verbose || dryRun {
+			for _, oid := range report.Oids {
+				fmt.Println(oid)
+			}
+		}
+		return nil
+	},
+}
+
+// deletes the objects no ref (nor HEAD) can reach and which were written before the expiry,
+// holding the repository lock so that no other pgit command writes objects in the meantime
+func pruneUnreachable(expire string, dryRun bool) (*data.PruneReport, error) {
+	until, err := data.ParseExpire(expire, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	lock, err := lockRepo()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	//the tag objects must survive as well as the commits they point to
+	tips, err := refTips()
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	reachable, err := data.ReachableObjects(ObjDir, tips)
+	if err != nil {
+		return nil, fmt.Errorf("refusing to prune a broken repository (run fsck): %w", err)
+	}
+	//staged files are not committed yet, but must survive
+	idx, err := data.ReadIndex(data.IndexPath)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	for _, oid := range idx {
+		reachable[oid] = true
+	}
+	report, err := data.PruneObjects(ObjDir, reachable, until, dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	return report, nil
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd, pruneCmd)
+
+	gcCmd.Flags().String("prune", "", "prune unreachable objects older than this date (default: gc.pruneExpire or 2.weeks.ago)")
+	gcCmd.Flags().BoolP("dry-run", "n", false, "only report what would be deleted")
+	pruneCmd.Flags().String("expire", "now", "only prune unreachable objects older than this date")
+	pruneCmd.Flags().BoolP("dry-run", "n", false, "only print the objects that would be deleted")
+	pruneCmd.Flags().BoolP("verbose", "v", false, "print the deleted objects")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
@@ -62,14 +63,20 @@ var hashObjCmd = &cobra.Command{
 			return err
 		}
 		filename := filepath.Clean(args[0])
-		content, err := data.ReadAllFileContent(filename)
+		f, err := os.Open(filename)
 		if err != nil {
 			return fmt.Errorf("hash-object: internal error: %w", err)
 		}
-		oid, err := SaveHashObj(content)
+		defer f.Close()
+		lock, err := lockRepo()
 		if err != nil {
 			return err
 		}
+		defer lock.Release()
+		oid, err := data.SaveBlobObjFrom(ObjDir, f)
+		if err != nil {
+			return fmt.Errorf("hash-object: internal error: %w", err)
+		}
 		log.Printf("saved a hashed-object!!\noid: %s\n", oid)
 		return nil
 	},
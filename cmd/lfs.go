@@ -0,0 +1,256 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// lfsCmd represents the lfs command
+var lfsCmd = &cobra.Command{
+	Use:   "lfs",
+	Short: "manage large files kept outside of the object storage",
+}
+
+// lfsTrackCmd represents the lfs track command
+var lfsTrackCmd = &cobra.Command{
+	Use:   "track [<pattern>...]",
+	Short: "store files matching the patterns in the large file store",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		attrs, err := data.ReadAttributes(data.AttributesFile)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		if len(args) == 0 {
+			for _, rule := range attrs {
+				if rule.Attrs["filter"] == "lfs" {
+					fmt.Println(rule.Pattern)
+				}
+			}
+			return nil
+		}
+		content, err := os.ReadFile(data.AttributesFile)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		buf := bytes.NewBuffer(content)
+		if buf.Len() > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			buf.WriteString("\n")
+		}
+		for _, pattern := range args {
+			if isTracked(attrs, pattern) {
+				continue
+			}
+			fmt.Fprintf(buf, "%s filter=lfs -diff\n", pattern)
+			fmt.Printf("tracking %s\n", pattern)
+		}
+		if err := data.WriteFile(data.AttributesFile, buf.Bytes()); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		return nil
+	},
+}
+
+func isTracked(attrs data.Attributes, pattern string) bool {
+	for _, rule := range attrs {
+		if rule.Pattern == pattern && rule.Attrs["filter"] == "lfs" {
+			return true
+		}
+	}
+	return false
+}
+
+// lfsLsFilesCmd represents the lfs ls-files command
+var lfsLsFilesCmd = &cobra.Command{
+	Use:   "ls-files [<rev>]",
+	Short: "list files stored in the large file store for a commit",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		var rev string
+		if len(args) == 1 {
+			rev = args[0]
+		}
+		oid, err := resolveRev(rev)
+		if err != nil {
+			return err
+		}
+		c, err := data.GetCommit(oid)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		pointers, err := data.CollectLFSPointers(ObjDir, c.TreeOid)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		paths := make([]string, 0, len(pointers))
+		for path := range pointers {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			p := pointers[path]
+			//"*" means the content exists locally, "-" means only the pointer does
+			mark := "-"
+			if data.HasLFSObj(data.LFSObjPath, p) {
+				mark = "*"
+			}
+			fmt.Printf("%s %s %s\n", p.Oid[:10], mark, path)
+		}
+		return nil
+	},
+}
+
+// lfsPruneCmd represents the lfs prune command
+var lfsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "delete large files no commit refers to",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		pointers, err := reachableLFSPointers()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		ents, err := os.ReadDir(data.LFSObjPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("internal error: %w", err)
+		}
+		var num, size int64
+		for _, ent := range ents {
+			if _, ok := pointers[ent.Name()]; ok || ent.IsDir() {
+				continue
+			}
+			fi, err := ent.Info()
+			if err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			if !dryRun {
+				if err := os.Remove(filepath.Join(data.LFSObjPath, ent.Name())); err != nil {
+					return fmt.Errorf("internal error: %w", err)
+				}
+			}
+			num++
+			size += fi.Size()
+		}
+		fmt.Printf("pruned %d large files (%d bytes)\n", num, size)
+		return nil
+	},
+}
+
+// lfsPushCmd represents the lfs push command
+var lfsPushCmd = &cobra.Command{
+	Use:   "push <dir>",
+	Short: "copy large files referred to by any commit into another local repository",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		return transferLFSObjs(data.LFSObjPath, remoteLFSStore(args[0]), "pushed")
+	},
+}
+
+// lfsFetchCmd represents the lfs fetch command
+var lfsFetchCmd = &cobra.Command{
+	Use:   "fetch <dir>",
+	Short: "copy missing large files referred to by any commit from another local repository",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		return transferLFSObjs(remoteLFSStore(args[0]), data.LFSObjPath, "fetched")
+	},
+}
+
+// returns the large file store of another local repository
+func remoteLFSStore(dir string) string {
+	return filepath.Join(dir, data.LFSObjPath)
+}
+
+// copies the large files referred to by any commit from srcStore to trgStore.
+// Files missing in srcStore are reported and skipped.
+func transferLFSObjs(srcStore string, trgStore string, verb string) error {
+	pointers, err := reachableLFSPointers()
+	if err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	var copied, missing []string
+	for oid, p := range pointers {
+		if !data.HasLFSObj(srcStore, p) {
+			if !data.HasLFSObj(trgStore, p) {
+				missing = append(missing, oid)
+			}
+			continue
+		}
+		ok, err := data.CopyLFSObj(srcStore, trgStore, p)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		if ok {
+			copied = append(copied, oid)
+		}
+	}
+	fmt.Printf("%s %d large files\n", verb, len(copied))
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing large files: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// collects the pointers in the trees of all the commits reachable from refs and HEAD
+// { key: sha256 of the content, value: pointer }
+func reachableLFSPointers() (map[string]*data.LFSPointer, error) {
+	roots, err := refRoots()
+	if err != nil {
+		return nil, fmt.Errorf("reachableLFSPointers: %w", err)
+	}
+	commits, err := data.ReachableCommits(roots...)
+	if err != nil {
+		return nil, fmt.Errorf("reachableLFSPointers: %w", err)
+	}
+	pointers := make(map[string]*data.LFSPointer)
+	for _, oid := range commits {
+		c, err := data.GetCommit(oid)
+		if err != nil {
+			return nil, fmt.Errorf("reachableLFSPointers: %w", err)
+		}
+		ps, err := data.CollectLFSPointers(ObjDir, c.TreeOid)
+		if err != nil {
+			return nil, fmt.Errorf("reachableLFSPointers: %w", err)
+		}
+		for _, p := range ps {
+			pointers[p.Oid] = p
+		}
+	}
+	return pointers, nil
+}
+
+func init() {
+	rootCmd.AddCommand(lfsCmd)
+	lfsCmd.AddCommand(lfsTrackCmd, lfsLsFilesCmd, lfsPruneCmd, lfsPushCmd, lfsFetchCmd)
+
+	lfsPruneCmd.Flags().Bool("dry-run", false, "only report what would be deleted")
+}
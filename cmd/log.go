@@ -4,12 +4,9 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
-	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/taimats/pgit/data"
@@ -19,67 +16,157 @@ var ErrNotFound = errors.New("not found")
 
 // logCmd represents the log command
 var logCmd = &cobra.Command{
-	Use:   "log",
+	Use:   "log [<revision>...] [[--] <path>...]",
 	Short: "print commit log list",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `print the commits reachable from the revisions (default: HEAD), the newest first
+(but never before their children). A revision may be a range like A..B or A...B, or ^A to exclude
+the commits reachable from A.
+Each commit is printed with its author, date and message, or in the format chosen by
+--oneline or --format. The changes follow with -p or the summary formats such as --stat.
+Paths after the revisions (or after "--") limit the commits to the ones changing them, and the changes
+to the paths. With --follow, the history of a single file is followed across renames.
+With --show-signature, the signature of each signed commit is verified against the keyring (see verify-commit).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var name string
-		if len(args) == 1 {
-			name = args[0]
+		w, err := revWalkFromFlags(cmd, cmd.Flags().Args())
+		if err != nil {
+			return err
 		}
-		var refPath string
-		if name == data.HEADAlias || name == HEAD || name == "" {
-			refPath = filepath.Join(data.RefHEADPath)
-		} else {
-			refPath = filepath.Join(data.RefBranchPath, name)
+		format, err := logFormat(cmd)
+		if err != nil {
+			return err
 		}
-		ref, err := data.NewRef(refPath)
+		opts, err := diffOptionsFromFlags(cmd)
 		if err != nil {
-			return fmt.Errorf("internal error: %w", err)
+			return err
 		}
-		if ref.IsSymbolic {
-			ref, err = ref.ResolveSymbolic(ref.Next)
+		opts.Pathspecs = w.Paths
+		var decorations map[string][]string
+		if decorate, _ := cmd.Flags().GetBool("decorate"); decorate {
+			decorations, err = data.Decorations()
 			if err != nil {
 				return fmt.Errorf("internal error: %w", err)
 			}
 		}
-		var buf strings.Builder
-		current := ref.Oid
-		for {
-			fmt.Fprintf(&buf, "%s", current)
-			oid, err := commitParent(current)
-			if err != nil {
+		var keyring data.Keyring
+		showSignature, _ := cmd.Flags().GetBool("show-signature")
+		if showSignature {
+			if keyring, err = readKeyring(); err != nil {
 				return fmt.Errorf("internal error: %w", err)
 			}
-			if oid == "" {
-				break
+		}
+		var graph *data.Graph
+		if g, _ := cmd.Flags().GetBool("graph"); g {
+			//the lines of history are drawn from the children to the parents
+			if w.Reverse {
+				return errors.New("--reverse and --graph cannot be used together")
+			}
+			graph = &data.Graph{}
+			w.Order = data.OrderTopo
+		}
+		oids, history, err := w.Walk()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+
+		var buf bytes.Buffer
+		for n, oid := range oids {
+			c := history.Commits[oid]
+			if w.Follow {
+				opts.Pathspecs = []string{history.FollowedPaths[oid]}
+			}
+			if n > 0 && format == data.FormatMedium {
+				if graph != nil {
+					buf.WriteString(graph.Padding())
+				}
+				buf.WriteString("\n")
+			}
+			e := &data.LogEntry{Oid: oid, Commit: c, Refs: decorations[oid]}
+			if showSignature && c.Signature != "" {
+				payload, signature, err := data.GetCommitPayload(oid)
+				if err != nil {
+					return fmt.Errorf("internal error: %w", err)
+				}
+				e.Signature = data.SignatureStatus(keyring.Verify(payload, signature))
+			}
+			if graph == nil {
+				if err := writeLogEntry(&buf, cmd, e, format, opts); err != nil {
+					return err
+				}
+				continue
 			}
-			current = oid
+			var entry bytes.Buffer
+			if err := writeLogEntry(&entry, cmd, e, format, opts); err != nil {
+				return err
+			}
+			graph.Write(&buf, oid, history.Parents(oid), entry.String())
 		}
-		fmt.Println(buf.String())
+		fmt.Print(buf.String())
 		return nil
 	},
 }
 
-func commitParent(oid string) (parentOid string, err error) {
-	c, err := data.ReadAllFileContent(filepath.Join(ObjDir, oid))
+// returns the format of the log entries chosen by --oneline or --format
+func logFormat(c *cobra.Command) (string, error) {
+	if oneline, _ := c.Flags().GetBool("oneline"); oneline {
+		return data.FormatOneline, nil
+	}
+	format, _ := c.Flags().GetString("format")
+	if format == "" {
+		return data.FormatMedium, nil
+	}
+	return format, nil
+}
+
+// writes the log entry followed by its changes in the patch and summary formats chosen by the flags
+func writeLogEntry(buf *bytes.Buffer, c *cobra.Command, e *data.LogEntry, format string, opts *data.DiffOptions) error {
+	if err := e.Write(buf, format, opts.Color); err != nil {
+		return err
+	}
+	patch, _ := c.Flags().GetBool("patch")
+	formats := chosenDiffFormats(c)
+	if !patch && len(formats) == 0 {
+		return nil
+	}
+	from, to, err := commitTrees(e.Commit)
 	if err != nil {
-		return "", fmt.Errorf("commitParent: %w", err)
+		return fmt.Errorf("internal error: %w", err)
+	}
+	if format == data.FormatMedium {
+		buf.WriteString("\n")
 	}
-	sc := bufio.NewScanner(bytes.NewReader(c))
-	sc.Split(bufio.ScanLines)
-	for sc.Scan() {
-		sep := strings.Split(sc.Text(), " ")
-		if len(sep) < 2 {
-			continue
+	if len(formats) > 0 {
+		if _, err := writeDiff(buf, c, opts, from, to); err != nil {
+			return fmt.Errorf("internal error: %w", err)
 		}
-		if sep[0] == "parent" {
-			return sep[1], nil
+		if !patch {
+			return nil
 		}
+		buf.WriteString("\n")
 	}
-	return "", nil
+	diffs, err := opts.DiffTrees(from, to, ObjDir)
+	if err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	for _, d := range diffs {
+		buf.WriteString(d.Diff)
+	}
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(logCmd)
+
+	logCmd.Flags().Bool("oneline", false, "print each commit in a line of the abbreviated oid and the subject")
+	logCmd.Flags().String("format", "", "print each commit in the format: medium, oneline, or a string with placeholders like %h %an %s")
+	logCmd.Flags().Bool("graph", false, "draw the lines of the history beside the commits")
+	logCmd.Flags().Bool("follow", false, "follow the history of the only path across renames")
+	logCmd.Flags().BoolP("patch", "p", false, "print the changes of each commit as patches")
+	logCmd.Flags().Bool("decorate", true, "print the branches and tags pointing to each commit")
+	logCmd.Flags().Bool("show-signature", false, "verify the signatures of signed commits against the keyring (see verify-commit)")
+	logCmd.Flags().IntP("unified", "U", data.DefaultContext, "show this number of unchanged lines around each change")
+	addRevWalkFlags(logCmd)
+	addRenameFlags(logCmd)
+	addDiffAlgorithmFlags(logCmd)
+	addDiffFormatFlags(logCmd)
+	addColorFlags(logCmd)
 }
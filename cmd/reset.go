@@ -32,6 +32,9 @@ var resetCmd = &cobra.Command{
 				return fmt.Errorf("internal error: %w", err)
 			}
 		}
+		if err := resetIndex(); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
 		return nil
 	},
 }
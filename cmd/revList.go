@@ -0,0 +1,50 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// revListCmd represents the rev-list command
+var revListCmd = &cobra.Command{
+	Use:   "rev-list [<revision>...] [[--] <path>...]",
+	Short: "print the oids of commits, one per line",
+	Long: `print the oids of the commits reachable from the revisions (default: HEAD), selected and ordered
+in the same way as log. A revision may be a range like A..B or A...B, or ^A to exclude the commits
+reachable from A. Paths after the revisions (or after "--") limit the commits to the ones changing them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		w, err := revWalkFromFlags(cmd, cmd.Flags().Args())
+		if err != nil {
+			return err
+		}
+		oids, _, err := w.Walk()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		if count, _ := cmd.Flags().GetBool("count"); count {
+			fmt.Println(len(oids))
+			return nil
+		}
+		var buf bytes.Buffer
+		for _, oid := range oids {
+			fmt.Fprintln(&buf, oid)
+		}
+		fmt.Print(buf.String())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(revListCmd)
+
+	addRevWalkFlags(revListCmd)
+	revListCmd.Flags().Bool("count", false, "print only the number of the commits")
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// shortlogCmd represents the shortlog command
+var shortlogCmd = &cobra.Command{
+	Use:   "shortlog [-n] [-s] [-e] [<revision>...] [[--] <path>...]",
+	Short: "summarize the commits by author",
+	Long: `list the subjects of the commits reachable from the revisions (default: HEAD) grouped by author,
+the oldest first, with the number of the commits. The authors are mapped to their proper names and
+emails by the mailmap file (default: .mailmap, or the config "mailmap.file").`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		w, err := revWalkFromFlags(cmd, cmd.Flags().Args())
+		if err != nil {
+			return err
+		}
+		w.Reverse = true
+		oids, h, err := w.Walk()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		mailmap, err := readMailmap()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		commits := make([]*data.Commit, len(oids))
+		for i, oid := range oids {
+			commits[i] = h.Commits[oid]
+		}
+		opts := &data.ShortlogOptions{}
+		opts.Numbered, _ = cmd.Flags().GetBool("numbered")
+		opts.Email, _ = cmd.Flags().GetBool("email")
+		summary, _ := cmd.Flags().GetBool("summary")
+
+		var buf bytes.Buffer
+		data.WriteShortlog(&buf, data.Shortlog(commits, mailmap, opts), summary)
+		fmt.Print(buf.String())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shortlogCmd)
+
+	shortlogCmd.Flags().BoolP("numbered", "n", false, "sort the authors by the number of their commits")
+	shortlogCmd.Flags().BoolP("summary", "s", false, "print only the number of the commits for each author")
+	shortlogCmd.Flags().BoolP("email", "e", false, "show the email of each author as well")
+	shortlogCmd.Flags().Bool("all", false, "walk from all the refs and HEAD as well as the revisions")
+	shortlogCmd.Flags().Bool("no-merges", false, "no merge commits")
+	shortlogCmd.Flags().String("since", "", "only the commits made at or after the date (e.g. 2025-01-31, \"2 weeks ago\")")
+	shortlogCmd.Flags().String("until", "", "only the commits made at or before the date")
+}
@@ -6,7 +6,6 @@ package cmd
 import (
 	"bytes"
 	"fmt"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/taimats/pgit/data"
@@ -17,45 +16,43 @@ var showCmd = &cobra.Command{
 	Use:   "show",
 	Short: "print commit details",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ref, err := data.NewRef(data.RefHEADPath)
-		if err != nil {
-			return fmt.Errorf("internal error: %w", err)
+		threshold, _ := cmd.Flags().GetString("big-file-threshold")
+		if err := setBigFileThreshold(threshold); err != nil {
+			return err
 		}
-		if ref.IsSymbolic {
-			ref, err = ref.ResolveSymbolic(ref.Next)
-			if err != nil {
-				return fmt.Errorf("internal error: %w", err)
-			}
+		opts, err := diffOptionsFromFlags(cmd)
+		if err != nil {
+			return err
 		}
-		c, err := data.GetCommit(ref.Oid)
+		oid, err := resolveRev(HEAD)
 		if err != nil {
-			return fmt.Errorf("internal error: %w", err)
+			return err
 		}
-		fromTree, err := data.ParseTreeFile(filepath.Join(ObjDir, c.Parent))
+		c, err := data.GetCommit(oid)
 		if err != nil {
 			return fmt.Errorf("internal error: %w", err)
 		}
-		toTree, err := data.ParseTreeFile(filepath.Join(ObjDir, c.TreeOid))
+		fromTree, toTree, err := commitTrees(c)
 		if err != nil {
 			return fmt.Errorf("internal error: %w", err)
 		}
-		diffs, err := data.DiffTrees(fromTree, toTree, ObjDir)
+		decorations, err := data.Decorations()
 		if err != nil {
 			return fmt.Errorf("internal error: %w", err)
 		}
 
 		var buf bytes.Buffer
-		fmt.Fprintf(&buf, "commit %s\n", c.TreeOid)
-		fmt.Fprintln(&buf, "")
-		fmt.Fprintf(&buf, "%s\n", c.Msg)
+		e := &data.LogEntry{Oid: oid, Commit: c, Refs: decorations[oid]}
+		if err := e.Write(&buf, data.FormatMedium, opts.Color); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
 		fmt.Fprintln(&buf, "")
-		if len(diffs) == 0 {
-			fmt.Fprintln(&buf, "No diffs right now!")
-			fmt.Println(buf.String())
-			return nil
+		n, err := writeDiff(&buf, cmd, opts, fromTree, toTree)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
 		}
-		for _, diff := range diffs {
-			fmt.Fprintf(&buf, "%s\n%s\n", diff.Filename, diff.Diff)
+		if n == 0 {
+			fmt.Fprintln(&buf, "No diffs right now!")
 		}
 		fmt.Println(buf.String())
 		return nil
@@ -64,4 +61,11 @@ var showCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(showCmd)
+
+	showCmd.Flags().IntP("unified", "U", data.DefaultContext, "show this number of unchanged lines around each change")
+	addRenameFlags(showCmd)
+	addDiffAlgorithmFlags(showCmd)
+	addDiffFormatFlags(showCmd)
+	addColorFlags(showCmd)
+	showCmd.Flags().String("big-file-threshold", "", "skip diffs of files larger than this size (e.g. 512k, 10m, 1g)")
 }
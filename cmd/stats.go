@@ -0,0 +1,66 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "report where the space of the repository goes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		top, _ := cmd.Flags().GetInt("top")
+		refs, err := data.ListRefs()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		stats, err := data.CollectStats(ObjDir, refs, top)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		fmt.Println("objects:")
+		for _, objType := range []string{data.ObjTypeCommit, data.ObjTypeTree, data.ObjTypeBlob, data.ObjTypeTag} {
+			s := stats.ByType[objType]
+			fmt.Printf("  %-12s %8d %12d bytes\n", objType, s.Count, s.Bytes)
+		}
+		fmt.Printf("  %-12s %8d %12d bytes\n", "unreachable", stats.Unreachable.Count, stats.Unreachable.Bytes)
+
+		fmt.Println("branches:")
+		branches := make([]string, 0, len(stats.BranchCommits))
+		for b := range stats.BranchCommits {
+			branches = append(branches, b)
+		}
+		sort.Strings(branches)
+		for _, b := range branches {
+			fmt.Printf("  %-20s %d commits\n", b, stats.BranchCommits[b])
+		}
+
+		fmt.Println("trees:")
+		fmt.Printf("  max depth    %d\n", stats.MaxTreeDepth)
+		fmt.Printf("  max fan-out  %d\n", stats.MaxTreeFanOut)
+		fmt.Printf("  mean fan-out %.2f\n", stats.MeanTreeFanOut)
+
+		fmt.Println("largest blobs:")
+		for _, b := range stats.LargestBlobs {
+			fmt.Printf("  %s %12d bytes  %s (%s)\n", shortOid(b.Oid), b.Size, b.Path, shortOid(b.Commit))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().Int("top", 10, "number of the largest blobs to report")
+}
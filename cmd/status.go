@@ -4,9 +4,11 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
 )
 
 // statusCmd represents the status command
@@ -19,11 +21,72 @@ var statusCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("no such a branch: %w", err)
 		}
-		fmt.Printf("on branch %s\n", current)
+		opts := data.NewDiffOptions()
+		if err := setRenameOptions(cmd, opts); err != nil {
+			return err
+		}
+		idx, err := loadIndex()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		head, err := headTree()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		staged, err := opts.DiffTreeEntries(head, idx.Tree(), ObjDir)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		attrs, err := data.ReadAttributes(data.AttributesFile)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		work, err := idx.WorkingTree(".", attrs)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		opts.WorkDir = "."
+		unstaged, err := opts.DiffTreeEntries(idx.Tree(), work, ObjDir)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		untracked, err := idx.Untracked(".")
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "on branch %s\n", current)
+		writeStatusEntries(&buf, "changes to be committed:", staged)
+		writeStatusEntries(&buf, "changes not staged:", unstaged)
+		if len(untracked) > 0 {
+			fmt.Fprintln(&buf, "untracked files:")
+			for _, path := range untracked {
+				fmt.Fprintf(&buf, "\t%s\n", path)
+			}
+		}
+		fmt.Print(buf.String())
 		return nil
 	},
 }
 
+// writes the entries under the title like "\tM file" or "\tR087 old -> new", nothing if no entry
+func writeStatusEntries(buf *bytes.Buffer, title string, entries []*data.DiffEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintln(buf, title)
+	for _, e := range entries {
+		if e.OldPath != "" {
+			fmt.Fprintf(buf, "\t%s %s -> %s\n", e.StatusCode(), e.OldPath, e.Path)
+			continue
+		}
+		fmt.Fprintf(buf, "\t%s %s\n", e.StatusCode(), e.Path)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
+
+	addRenameFlags(statusCmd)
 }
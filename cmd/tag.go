@@ -4,8 +4,12 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/taimats/pgit/data"
@@ -13,39 +17,262 @@ import (
 
 // tagCmd represents the tag command
 var tagCmd = &cobra.Command{
-	Use:   "tag",
+	Use:   "tag [-a | -s] [-m <message>] [-f] <name> [<revision>] | -d <name>... | -v <name>... | [-l] [--contains <revision>] [<pattern>...]",
 	Short: "attach a name to an oid",
-	Args:  cobra.MaximumNArgs(2),
+	Long: `create a tag of the revision (default: HEAD). A lightweight tag is only a ref to the commit, while an
+annotated tag (-a, or -m) is a tag object with the tagger, the date and the message, and a signed tag (-s)
+is an annotated tag signed with the ed25519 key in the config "user.signingKey".
+
+Without a name (or with -l), the tags matching any of the glob patterns are listed. -d deletes the tags,
+and -v verifies the signatures of the tags against the keyring (default: .pgit/keyring, or the config
+"gpg.keyring"), whose lines are like "taro@example.com ed25519 {public key in base64}".`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var name, oid string
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		args = cmd.Flags().Args()
+		if del, _ := cmd.Flags().GetBool("delete"); del {
+			return deleteTags(args)
+		}
+		if verify, _ := cmd.Flags().GetBool("verify"); verify {
+			return verifyTags(args)
+		}
+		list, _ := cmd.Flags().GetBool("list")
+		contains, _ := cmd.Flags().GetString("contains")
+		if list || contains != "" || len(args) == 0 {
+			return listTags(args, contains)
+		}
+		if len(args) > 2 {
+			return errors.New("too many arguments: tag <name> [<revision>]")
+		}
+		rev := HEAD
 		if len(args) == 2 {
-			name, oid = args[0], args[1]
-		} else {
-			name = args[0]
+			rev = args[1]
+		}
+		return createTag(cmd, args[0], rev)
+	},
+}
+
+// creates the tag of the revision, which is annotated if a message is given.
+// The repository is locked, so that gc does not prune the tag object before the ref points to it.
+func createTag(cmd *cobra.Command, name string, rev string) error {
+	if !validTagName(name) {
+		return fmt.Errorf("invalid tag name: %q", name)
+	}
+	lock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	path := filepath.Join(TagDir, name)
+	if force, _ := cmd.Flags().GetBool("force"); !force && fileExists(path) {
+		return fmt.Errorf("tag '%s' already exists", name)
+	}
+	oid, err := resolveRev(rev)
+	if err != nil {
+		return err
+	}
+	annotate, _ := cmd.Flags().GetBool("annotate")
+	sign, _ := cmd.Flags().GetBool("sign")
+	msg, _ := cmd.Flags().GetString("message")
+	if (annotate || sign) && msg == "" {
+		return errors.New("an annotated tag needs a message: use -m")
+	}
+	if msg != "" {
+		if oid, err = writeTagObject(oid, name, msg, sign); err != nil {
+			return err
 		}
-		if oid == "" {
-			head, err := data.NewRef(data.RefHEADPath)
+	}
+	if err := data.WriteFile(path, []byte(oid)); err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	fmt.Println("created a tag!!")
+	return nil
+}
+
+// saves the annotated tag of the object made by the current user, and returns its oid
+func writeTagObject(object string, name string, msg string, sign bool) (string, error) {
+	tagger, err := currentSignature()
+	if err != nil {
+		return "", fmt.Errorf("internal error: %w", err)
+	}
+	t, err := data.NewTag(object, name, tagger, msg)
+	if err != nil {
+		return "", fmt.Errorf("internal error: %w", err)
+	}
+	if sign {
+		key, err := readSigningKey()
+		if err != nil {
+			return "", err
+		}
+		if t.Signature, err = data.Sign(key, t.Payload()); err != nil {
+			return "", fmt.Errorf("internal error: %w", err)
+		}
+	}
+	oid, err := SaveHashObj(t.Encode())
+	if err != nil {
+		return "", fmt.Errorf("internal error: %w", err)
+	}
+	return oid, nil
+}
+
+// a tag name is a relative path under refs/tags without "..", spaces or a leading "-"
+func validTagName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, "-") && !strings.HasPrefix(name, "/") && !strings.HasSuffix(name, "/") &&
+		!strings.Contains(name, "..") && !strings.ContainsAny(name, " \t\n\\")
+}
+
+// prints the names of the tags matching any of the patterns, sorted. With contains, only the tags
+// of the commits the revision is reachable from are listed.
+func listTags(patterns []string, contains string) error {
+	var want string
+	if contains != "" {
+		oid, err := resolveRev(contains)
+		if err != nil {
+			return err
+		}
+		want = oid
+	}
+	refs, err := data.ListRefs()
+	if err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	var names []string
+	for ref, oid := range refs {
+		name, ok := strings.CutPrefix(ref, "refs/tags/")
+		if !ok || !matchAnyPattern(name, patterns) {
+			continue
+		}
+		if want != "" {
+			ok, err := tagContains(oid, want)
 			if err != nil {
 				return fmt.Errorf("internal error: %w", err)
 			}
-			if head.IsSymbolic {
-				head, err = head.ResolveSymbolic(head.Next)
-				if err != nil {
-					return fmt.Errorf("internal error: %w", err)
-				}
+			if !ok {
+				continue
 			}
-			oid = head.Oid
 		}
-		if err := data.WriteFile(filepath.Join(TagDir, name), []byte(oid)); err != nil {
-			if err != nil {
-				return fmt.Errorf("internal error: %w", err)
-			}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func matchAnyPattern(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
 		}
-		fmt.Println("created a tag!!")
-		return nil
-	},
+	}
+	return false
+}
+
+// reports whether the commit is reachable from the commit the tag points to
+func tagContains(tagOid string, commit string) (bool, error) {
+	oid, objType, err := data.PeelTag(tagOid)
+	if err != nil {
+		return false, fmt.Errorf("tagContains: %w", err)
+	}
+	if objType != "" && objType != data.ObjTypeCommit {
+		return false, nil
+	}
+	reachable, err := data.ReachableCommits(oid)
+	if err != nil {
+		return false, fmt.Errorf("tagContains: %w", err)
+	}
+	for _, r := range reachable {
+		if r == commit {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deletes the tags, printing the oids they pointed to
+func deleteTags(names []string) error {
+	if len(names) == 0 {
+		return errors.New("no tags to delete")
+	}
+	for _, name := range names {
+		path := filepath.Join(TagDir, name)
+		if !validTagName(name) || !fileExists(path) {
+			return fmt.Errorf("tag '%s' not found", name)
+		}
+		oid, err := data.ResolveRefOid(path)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		fmt.Printf("Deleted tag '%s' (was %s)\n", name, shortOid(oid))
+	}
+	return nil
+}
+
+// verifies the signatures of the tags against the keyring
+func verifyTags(names []string) error {
+	if len(names) == 0 {
+		return errors.New("no tags to verify")
+	}
+	keyring, err := readKeyring()
+	if err != nil {
+		return fmt.Errorf("internal error: %w", err)
+	}
+	for _, name := range names {
+		oid, err := resolveTagObject(name)
+		if err != nil {
+			return err
+		}
+		t, err := data.GetTag(oid)
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		check, err := keyring.Verify(t.Payload(), t.Signature)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, data.SignatureStatus(check, err))
+		}
+		fmt.Println(data.SignatureStatus(check, nil))
+	}
+	return nil
+}
+
+// returns the oid of the tag object the tag points to
+func resolveTagObject(name string) (string, error) {
+	path := filepath.Join(TagDir, name)
+	if !validTagName(name) || !fileExists(path) {
+		return "", fmt.Errorf("tag '%s' not found", name)
+	}
+	oid, err := data.ResolveRefOid(path)
+	if err != nil {
+		return "", fmt.Errorf("internal error: %w", err)
+	}
+	ok, err := data.IsTagObject(oid)
+	if err != nil {
+		return "", fmt.Errorf("internal error: %w", err)
+	}
+	if !ok {
+		return "", fmt.Errorf("%s: cannot verify a lightweight tag", name)
+	}
+	return oid, nil
 }
 
 func init() {
 	rootCmd.AddCommand(tagCmd)
+
+	tagCmd.Flags().BoolP("annotate", "a", false, "make an annotated tag with the message")
+	tagCmd.Flags().BoolP("sign", "s", false, "make an annotated tag signed with the key in the config \"user.signingKey\"")
+	tagCmd.Flags().StringP("message", "m", "", "the message of an annotated tag (implies -a)")
+	tagCmd.Flags().BoolP("force", "f", false, "replace the tag if it already exists")
+	tagCmd.Flags().BoolP("delete", "d", false, "delete the tags")
+	tagCmd.Flags().BoolP("verify", "v", false, "verify the signatures of the tags")
+	tagCmd.Flags().BoolP("list", "l", false, "list the tags matching any of the patterns")
+	tagCmd.Flags().String("contains", "", "list only the tags of the commits the revision is reachable from")
 }
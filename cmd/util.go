@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/spf13/cobra"
 	"github.com/taimats/pgit/data"
 )
 
@@ -36,3 +42,522 @@ func SaveHashObj(content []byte) (oid string, err error) {
 	}
 	return oid, nil
 }
+
+// reads the repository config (= .pgit/config)
+func readConfig() (data.Config, error) {
+	conf, err := data.ReadConfig(data.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("readConfig: %w", err)
+	}
+	return conf, nil
+}
+
+// reads the mailmap file set in the config, or .mailmap in the working tree
+func readMailmap() (data.Mailmap, error) {
+	conf, err := readConfig()
+	if err != nil {
+		return nil, fmt.Errorf("readMailmap: %w", err)
+	}
+	m, err := data.ReadMailmap(conf.Get(data.ConfigMailmapFile, data.MailmapFile))
+	if err != nil {
+		return nil, fmt.Errorf("readMailmap: %w", err)
+	}
+	return m, nil
+}
+
+// reads the private key in the config "user.signingKey"
+func readSigningKey() (ed25519.PrivateKey, error) {
+	conf, err := readConfig()
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	path := conf.Get(data.ConfigSigningKey, "")
+	if path == "" {
+		return nil, fmt.Errorf("no signing key: set %s in the config", data.ConfigSigningKey)
+	}
+	key, err := data.ReadSigningKey(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the signing key: %w", err)
+	}
+	return key, nil
+}
+
+// reads the keyring set in the config, or .pgit/keyring
+func readKeyring() (data.Keyring, error) {
+	conf, err := readConfig()
+	if err != nil {
+		return nil, fmt.Errorf("readKeyring: %w", err)
+	}
+	k, err := data.ReadKeyring(conf.Get(data.ConfigKeyring, data.KeyringPath))
+	if err != nil {
+		return nil, fmt.Errorf("readKeyring: %w", err)
+	}
+	return k, nil
+}
+
+// sets data.BigFileThreshold from the flag value if any, otherwise from the config
+func setBigFileThreshold(flagValue string) error {
+	if flagValue != "" {
+		size, err := data.ParseSize(flagValue)
+		if err != nil {
+			return fmt.Errorf("setBigFileThreshold: %w", err)
+		}
+		data.BigFileThreshold = size
+		return nil
+	}
+	conf, err := readConfig()
+	if err != nil {
+		return fmt.Errorf("setBigFileThreshold: %w", err)
+	}
+	size, err := conf.Size(data.ConfigBigFileThreshold, data.DefaultBigFileThreshold)
+	if err != nil {
+		return fmt.Errorf("setBigFileThreshold: %w", err)
+	}
+	data.BigFileThreshold = size
+	return nil
+}
+
+// the fewest hex digits of an abbreviated oid accepted as a revision
+const minOidPrefix = 4
+
+// resolves a revision name into a commit oid in the following order:
+// HEAD (or @, or empty) => branch name => tag name => raw oid => unique prefix of an oid
+func resolveRev(name string) (oid string, err error) {
+	var path string
+	switch {
+	case name == "" || name == HEAD || name == data.HEADAlias:
+		path = data.RefHEADPath
+	case fileExists(filepath.Join(data.RefBranchPath, name)):
+		path = filepath.Join(data.RefBranchPath, name)
+	case fileExists(filepath.Join(data.RefTagPath, name)):
+		path = filepath.Join(data.RefTagPath, name)
+	case fileExists(filepath.Join(ObjDir, name)):
+		return name, nil
+	case isOidPrefix(name):
+		oid, err = data.ResolveOidPrefix(ObjDir, name)
+		if errors.Is(err, data.ErrObjNotFound) {
+			return "", fmt.Errorf("resolveRev: unknown revision: %s", name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("resolveRev: %w", err)
+		}
+		return oid, nil
+	default:
+		return "", fmt.Errorf("resolveRev: unknown revision: %s", name)
+	}
+	oid, err = data.ResolveRefOid(path)
+	if err != nil {
+		return "", fmt.Errorf("resolveRev: %w", err)
+	}
+	if oid == "" {
+		return "", fmt.Errorf("resolveRev: %s points to no commit yet", name)
+	}
+	//an annotated tag stands for the commit it points to
+	if oid, _, err = data.PeelTag(oid); err != nil {
+		return "", fmt.Errorf("resolveRev: %w", err)
+	}
+	return oid, nil
+}
+
+// reports whether s can be an abbreviated oid (= at least minOidPrefix lowercase hex digits)
+func isOidPrefix(s string) bool {
+	return len(s) >= minOidPrefix && strings.Trim(s, "0123456789abcdef") == ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// returns the oids HEAD and all the refs point to as they are, including annotated tags
+func refTips() ([]string, error) {
+	refs, err := data.ListRefs()
+	if err != nil {
+		return nil, fmt.Errorf("refTips: %w", err)
+	}
+	head, err := data.ResolveRefOid(data.RefHEADPath)
+	if err != nil {
+		return nil, fmt.Errorf("refTips: %w", err)
+	}
+	tips := []string{}
+	if head != "" {
+		tips = append(tips, head)
+	}
+	for _, oid := range refs {
+		tips = append(tips, oid)
+	}
+	return tips, nil
+}
+
+// returns the commit oids HEAD and all the refs point to, which are the starting points of reachability.
+// Annotated tags are peeled to the commits they point to, and tags of trees and blobs are left out.
+func refRoots() ([]string, error) {
+	tips, err := refTips()
+	if err != nil {
+		return nil, fmt.Errorf("refRoots: %w", err)
+	}
+	roots := []string{}
+	for _, oid := range tips {
+		oid, objType, err := data.PeelTag(oid)
+		if err != nil {
+			return nil, fmt.Errorf("refRoots: %w", err)
+		}
+		if objType == "" || objType == data.ObjTypeCommit {
+			roots = append(roots, oid)
+		}
+	}
+	return roots, nil
+}
+
+// takes the repository lock (= .pgit/pgit.lock). The caller must release it.
+func lockRepo() (*data.Lock, error) {
+	lock, err := data.AcquireLock(data.LockPath)
+	if err != nil {
+		return nil, fmt.Errorf("lockRepo: %w", err)
+	}
+	return lock, nil
+}
+
+// abbreviates an oid to the first 7 characters
+func shortOid(oid string) string {
+	if len(oid) < 7 {
+		return oid
+	}
+	return oid[:7]
+}
+
+// returns the tree of the commit the revision points to
+func revTree(rev string) (data.Tree, error) {
+	oid, err := resolveRev(rev)
+	if err != nil {
+		return nil, fmt.Errorf("revTree: %w", err)
+	}
+	c, err := data.GetCommit(oid)
+	if err != nil {
+		return nil, fmt.Errorf("revTree: %w", err)
+	}
+	tree, err := data.ParseTreeFile(filepath.Join(ObjDir, c.TreeOid))
+	if err != nil {
+		return nil, fmt.Errorf("revTree: %w", err)
+	}
+	return tree, nil
+}
+
+// returns the tree of HEAD, or an empty tree before the first commit
+func headTree() (data.Tree, error) {
+	oid, err := data.ResolveRefOid(data.RefHEADPath)
+	if err != nil {
+		return nil, fmt.Errorf("headTree: %w", err)
+	}
+	if oid == "" {
+		return make(data.Tree), nil
+	}
+	return revTree(oid)
+}
+
+// reads the index (= .pgit/index). Without the file, the index is the same as the tree of HEAD.
+func loadIndex() (data.Index, error) {
+	idx, err := data.ReadIndex(data.IndexPath)
+	if err != nil {
+		return nil, fmt.Errorf("loadIndex: %w", err)
+	}
+	if idx != nil {
+		return idx, nil
+	}
+	tree, err := headTree()
+	if err != nil {
+		return nil, fmt.Errorf("loadIndex: %w", err)
+	}
+	return data.IndexFromTree(tree), nil
+}
+
+// drops the staged changes, so that the index gets back to the tree of HEAD
+func resetIndex() error {
+	if err := os.Remove(data.IndexPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("resetIndex: %w", err)
+	}
+	return nil
+}
+
+// adds the flags of rename detection shared by the commands printing diffs
+func addRenameFlags(c *cobra.Command) {
+	c.Flags().Bool("no-renames", false, "do not detect renames")
+	c.Flags().BoolP("find-copies", "C", false, "detect copies as well as renames")
+	c.Flags().Int("rename-threshold", 0, "similarity in percent to regard files as renamed (default: diff.renameThreshold or 50)")
+}
+
+// sets the rename detection of the options from the flags, falling back on the config
+// (= diff.renames, diff.renameThreshold)
+func setRenameOptions(c *cobra.Command, opts *data.DiffOptions) error {
+	conf, err := readConfig()
+	if err != nil {
+		return fmt.Errorf("setRenameOptions: %w", err)
+	}
+	opts.SetRenames(conf.Get(data.ConfigDiffRenames, "true"))
+	opts.RenameThreshold, err = conf.Int(data.ConfigDiffRenameThreshold, data.DefaultRenameThreshold)
+	if err != nil {
+		return fmt.Errorf("setRenameOptions: %w", err)
+	}
+	if threshold, _ := c.Flags().GetInt("rename-threshold"); threshold > 0 {
+		opts.RenameThreshold = threshold
+	}
+	if copies, _ := c.Flags().GetBool("find-copies"); copies {
+		opts.Renames, opts.Copies = true, true
+	}
+	if noRenames, _ := c.Flags().GetBool("no-renames"); noRenames {
+		opts.Renames, opts.Copies = false, false
+	}
+	return nil
+}
+
+// adds the flags of the diff algorithm and the whitespace options shared by the commands printing diffs
+func addDiffAlgorithmFlags(c *cobra.Command) {
+	c.Flags().String("diff-algorithm", "", "myers, patience or histogram (default: diff.algorithm or myers)")
+	c.Flags().BoolP("ignore-all-space", "w", false, "ignore whitespace when comparing lines")
+	c.Flags().BoolP("ignore-space-change", "b", false, "ignore changes in the amount of whitespace")
+	c.Flags().Bool("ignore-blank-lines", false, "ignore changes whose lines are all blank")
+}
+
+func setDiffAlgorithmOptions(c *cobra.Command, opts *data.DiffOptions) error {
+	conf, err := readConfig()
+	if err != nil {
+		return fmt.Errorf("setDiffAlgorithmOptions: %w", err)
+	}
+	name := conf.Get(data.ConfigDiffAlgorithm, string(data.DefaultDiffAlgorithm))
+	if flag, _ := c.Flags().GetString("diff-algorithm"); flag != "" {
+		name = flag
+	}
+	opts.Algorithm, err = data.ParseDiffAlgorithm(name)
+	if err != nil {
+		return err
+	}
+	opts.IgnoreAllSpace, _ = c.Flags().GetBool("ignore-all-space")
+	opts.IgnoreSpaceChange, _ = c.Flags().GetBool("ignore-space-change")
+	opts.IgnoreBlankLines, _ = c.Flags().GetBool("ignore-blank-lines")
+	return nil
+}
+
+// tells binary files by the attributes of the working tree, and writes them as binary patches with --binary
+func setBinaryOptions(c *cobra.Command, opts *data.DiffOptions) error {
+	attrs, err := data.ReadAttributes(data.AttributesFile)
+	if err != nil {
+		return fmt.Errorf("setBinaryOptions: %w", err)
+	}
+	opts.Attributes = attrs
+	opts.Binary, _ = c.Flags().GetBool("binary")
+	return nil
+}
+
+// adds the flags of colored output and word diffs shared by the commands printing diffs
+func addColorFlags(c *cobra.Command) {
+	c.Flags().String("color", "", "color the output: always, never or auto (default: color.diff, color.ui or auto)")
+	c.Flags().Lookup("color").NoOptDefVal = string(data.ColorAlways)
+	c.Flags().String("word-diff", "", "show changed words instead of lines: plain, color, porcelain or none")
+	c.Flags().Lookup("word-diff").NoOptDefVal = string(data.WordDiffPlain)
+	c.Flags().String("word-diff-regex", "", "the regular expression of a word, implying --word-diff (default: diff.wordRegex)")
+}
+
+// sets the colors and the word diff of the options from the flags, falling back on the config
+// (= color.diff, color.ui, diff.wordRegex). With auto, the output is colored only on a terminal.
+func setColorOptions(c *cobra.Command, opts *data.DiffOptions) error {
+	conf, err := readConfig()
+	if err != nil {
+		return fmt.Errorf("setColorOptions: %w", err)
+	}
+	when := conf.Get(data.ConfigColorDiff, conf.Get(data.ConfigColorUI, string(data.ColorAuto)))
+	if flag, _ := c.Flags().GetString("color"); flag != "" {
+		when = flag
+	}
+	mode, err := data.ParseColorMode(when)
+	if err != nil {
+		return err
+	}
+	opts.Color = mode == data.ColorAlways || (mode == data.ColorAuto && isTerminal(os.Stdout))
+
+	wordDiff, _ := c.Flags().GetString("word-diff")
+	regex, _ := c.Flags().GetString("word-diff-regex")
+	if wordDiff == "" && regex != "" {
+		wordDiff = string(data.WordDiffPlain)
+	}
+	if wordDiff == "" {
+		return nil
+	}
+	opts.WordDiff, err = data.ParseWordDiffMode(wordDiff)
+	if err != nil {
+		return err
+	}
+	if regex == "" {
+		regex = conf.Get(data.ConfigDiffWordRegex, "")
+	}
+	if regex != "" {
+		opts.WordRegex, err = regexp.Compile(regex)
+		if err != nil {
+			return fmt.Errorf("invalid word regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// reports whether the file is a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// returns the diff options from the flags (and the config) of the command printing diffs
+func diffOptionsFromFlags(c *cobra.Command) (*data.DiffOptions, error) {
+	opts := data.NewDiffOptions()
+	if c.Flags().Lookup("unified") != nil {
+		opts.Context, _ = c.Flags().GetInt("unified")
+	}
+	for _, set := range []func(*cobra.Command, *data.DiffOptions) error{
+		setRenameOptions, setDiffAlgorithmOptions, setBinaryOptions, setColorOptions,
+	} {
+		if err := set(c, opts); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+// returns the trees to compare for the changes of the commit, that is, the tree of its parent
+// (or an empty tree for the first commit) and its own tree
+func commitTrees(c *data.Commit) (from data.Tree, to data.Tree, err error) {
+	from = make(data.Tree)
+	if c.Parent != "" {
+		parent, err := data.GetCommit(c.Parent)
+		if err != nil {
+			return nil, nil, fmt.Errorf("commitTrees: %w", err)
+		}
+		from, err = data.ParseTreeFile(filepath.Join(ObjDir, parent.TreeOid))
+		if err != nil {
+			return nil, nil, fmt.Errorf("commitTrees: %w", err)
+		}
+	}
+	to, err = data.ParseTreeFile(filepath.Join(ObjDir, c.TreeOid))
+	if err != nil {
+		return nil, nil, fmt.Errorf("commitTrees: %w", err)
+	}
+	return from, to, nil
+}
+
+// adds the flags selecting and ordering commits shared by the commands walking the history
+func addRevWalkFlags(c *cobra.Command) {
+	c.Flags().Bool("all", false, "walk from all the refs and HEAD as well as the revisions")
+	c.Flags().String("author", "", "only the commits whose author (= \"{name} <{email}>\") matches the regular expression")
+	c.Flags().String("grep", "", "only the commits whose message matches the regular expression")
+	c.Flags().String("since", "", "only the commits made at or after the date (e.g. 2025-01-31, \"2 weeks ago\")")
+	c.Flags().String("until", "", "only the commits made at or before the date")
+	c.Flags().Bool("first-parent", false, "follow only the first parent of merge commits")
+	c.Flags().Bool("merges", false, "only merge commits")
+	c.Flags().Bool("no-merges", false, "no merge commits")
+	c.Flags().Bool("reverse", false, "list the oldest commit first")
+	c.Flags().Bool("topo-order", false, "list the commits of a line of history together")
+	c.Flags().Bool("date-order", false, "list the commits by date, but never before their children (default)")
+	c.Flags().IntP("max-count", "n", -1, "list at most this number of commits")
+}
+
+// returns the walk over the revisions in the args and the flags. A revision is either "A" (= walk from A),
+// "^A" (= exclude A and its ancestors), "A..B" (= B ^A) or "A...B" (= A B ^{merge base}).
+// Without any revision to walk from, HEAD is walked. Paths after the revisions (or after "--") limit
+// the commits to the ones changing them.
+func revWalkFromFlags(c *cobra.Command, args []string) (*data.RevWalk, error) {
+	w := data.NewRevWalk()
+	revs, paths, err := splitRevsAndPaths(args, c.Flags().ArgsLenAtDash())
+	if err != nil {
+		return nil, err
+	}
+	w.Paths = paths
+	if follow, _ := c.Flags().GetBool("follow"); follow {
+		if len(paths) != 1 {
+			return nil, errors.New("--follow requires exactly one path")
+		}
+		w.Follow = true
+	}
+	for _, arg := range revs {
+		switch {
+		case strings.Contains(arg, ".."):
+			a, b, symmetric := parseRange(arg)
+			aOid, err := resolveRev(a)
+			if err != nil {
+				return nil, err
+			}
+			bOid, err := resolveRev(b)
+			if err != nil {
+				return nil, err
+			}
+			if !symmetric {
+				w.Include, w.Exclude = append(w.Include, bOid), append(w.Exclude, aOid)
+				continue
+			}
+			base, err := mergeBaseRev(a, b)
+			if err != nil {
+				return nil, err
+			}
+			w.Include, w.Exclude = append(w.Include, aOid, bOid), append(w.Exclude, base)
+		case strings.HasPrefix(arg, "^"):
+			oid, err := resolveRev(arg[1:])
+			if err != nil {
+				return nil, err
+			}
+			w.Exclude = append(w.Exclude, oid)
+		default:
+			oid, err := resolveRev(arg)
+			if err != nil {
+				return nil, err
+			}
+			w.Include = append(w.Include, oid)
+		}
+	}
+	if all, _ := c.Flags().GetBool("all"); all {
+		roots, err := refRoots()
+		if err != nil {
+			return nil, fmt.Errorf("internal error: %w", err)
+		}
+		w.Include = append(w.Include, roots...)
+	}
+	if len(w.Include) == 0 {
+		oid, err := resolveRev(HEAD)
+		if err != nil {
+			return nil, err
+		}
+		w.Include = append(w.Include, oid)
+	}
+
+	for name, re := range map[string]**regexp.Regexp{"author": &w.Author, "grep": &w.Grep} {
+		pattern, _ := c.Flags().GetString(name)
+		if pattern == "" {
+			continue
+		}
+		var err error
+		if *re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", name, err)
+		}
+	}
+	now := time.Now()
+	for name, t := range map[string]*time.Time{"since": &w.Since, "until": &w.Until} {
+		date, _ := c.Flags().GetString(name)
+		if date == "" {
+			continue
+		}
+		var err error
+		if *t, err = data.ParseDate(date, now); err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", name, err)
+		}
+	}
+	w.FirstParent, _ = c.Flags().GetBool("first-parent")
+	if merges, _ := c.Flags().GetBool("merges"); merges {
+		w.MinParents = 2
+	}
+	if noMerges, _ := c.Flags().GetBool("no-merges"); noMerges {
+		w.MaxParents = 1
+	}
+	if topo, _ := c.Flags().GetBool("topo-order"); topo {
+		w.Order = data.OrderTopo
+	}
+	w.Reverse, _ = c.Flags().GetBool("reverse")
+	if n, err := c.Flags().GetInt("max-count"); err == nil {
+		w.MaxCount = n
+	}
+	return w, nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/taimats/pgit/data"
+)
+
+// verifyCommitCmd represents the verify-commit command
+var verifyCommitCmd = &cobra.Command{
+	Use:   "verify-commit <revision>...",
+	Short: "verify the signatures of commits",
+	Long: `verify the signatures of the commits (see commit -S) against the keyring (default: .pgit/keyring,
+or the config "gpg.keyring"). Each line of the keyring trusts a key for a principal, like
+"taro@example.com ed25519 {public key in base64}", and the lines of an SSH allowed signers file
+(like "taro@example.com ssh-ed25519 AAAA...") are accepted as well.
+It fails unless every commit has a good signature made by a key in the keyring.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := CheckPgitInit(); err != nil {
+			return err
+		}
+		revs := cmd.Flags().Args()
+		if len(revs) == 0 {
+			return errors.New("no commits to verify")
+		}
+		keyring, err := readKeyring()
+		if err != nil {
+			return fmt.Errorf("internal error: %w", err)
+		}
+		for _, rev := range revs {
+			oid, err := resolveRev(rev)
+			if err != nil {
+				return err
+			}
+			payload, signature, err := data.GetCommitPayload(oid)
+			if err != nil {
+				return fmt.Errorf("internal error: %w", err)
+			}
+			check, err := keyring.Verify(payload, signature)
+			if err != nil {
+				return fmt.Errorf("%s: %s", rev, data.SignatureStatus(check, err))
+			}
+			fmt.Println(data.SignatureStatus(check, nil))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCommitCmd)
+}
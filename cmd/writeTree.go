@@ -30,6 +30,12 @@ var writeTreeCmd = &cobra.Command{
 
 // saveTree is just a high-level layer of function to execute write-tree command.
 func saveTree(rootPath string) (oid string, err error) {
+	lock, err := lockRepo()
+	if err != nil {
+		return "", fmt.Errorf("saveTree: %w", err)
+	}
+	defer lock.Release()
+
 	oid, err = data.WriteTree(rootPath, ObjDir)
 	if err != nil {
 		return "", fmt.Errorf("saveTree: %w", err)
@@ -0,0 +1,346 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultFuzz is the default number of context lines at each end of a hunk that may be ignored
+// to find where the hunk applies, the same as patch(1).
+const DefaultFuzz = 2
+
+var (
+	ErrPatchFailed   = errors.New("patch does not apply")
+	ErrPatchConflict = errors.New("patch applied with conflicts")
+)
+
+// ApplyOptions controls how patches are applied.
+type ApplyOptions struct {
+	Fuzz int //the number of context lines at each end of a hunk that may be ignored
+	//falls back on a three-way merge when a hunk does not apply, with the blob recorded in the "index"
+	//line of the patch as the base
+	ThreeWay bool
+	ObjDir   string //the object storage to find the blobs for ThreeWay
+}
+
+func NewApplyOptions(objDir string) *ApplyOptions {
+	return &ApplyOptions{Fuzz: DefaultFuzz, ObjDir: objDir}
+}
+
+// PatchResult is the state of a path after patches are applied to it.
+type PatchResult struct {
+	Path     string
+	Content  []byte
+	Deleted  bool
+	Mode     string //the mode the patch gives the file, empty if it does not tell
+	Conflict bool   //merged by the three-way fallback, and Content has conflict markers
+}
+
+// PatchSource reads the current content of a path, where ok is false if the path does not exist.
+type PatchSource func(path string) (content []byte, ok bool, err error)
+
+// ApplyPatches applies the patches to the contents read from src, and returns the results of the
+// paths in the order they are first touched. Nothing is written, and an error is returned if any
+// of the patches does not apply, so that the caller can write all the results or none of them.
+// A later patch sees the results of the earlier ones.
+func (o *ApplyOptions) ApplyPatches(patches []*FilePatch, src PatchSource) ([]*PatchResult, error) {
+	results := make(map[string]*PatchResult)
+	var order []string
+	read := func(path string) ([]byte, bool, error) {
+		if r, ok := results[path]; ok {
+			return r.Content, !r.Deleted, nil
+		}
+		return src(path)
+	}
+	set := func(r *PatchResult) {
+		if _, ok := results[r.Path]; !ok {
+			order = append(order, r.Path)
+		}
+		results[r.Path] = r
+	}
+	for _, p := range patches {
+		var content []byte
+		if p.Status != StatusAdded {
+			c, ok, err := read(p.OldPath)
+			if err != nil {
+				return nil, fmt.Errorf("ApplyPatches: %w", err)
+			}
+			if !ok {
+				return nil, fmt.Errorf("%w: %s: does not exist", ErrPatchFailed, p.OldPath)
+			}
+			content = c
+		}
+		if p.Status == StatusAdded || p.Status == StatusRenamed || p.Status == StatusCopied {
+			_, ok, err := read(p.NewPath)
+			if err != nil {
+				return nil, fmt.Errorf("ApplyPatches: %w", err)
+			}
+			if ok {
+				return nil, fmt.Errorf("%w: %s: already exists", ErrPatchFailed, p.NewPath)
+			}
+		}
+		if p.Status == StatusDeleted && len(p.Hunks) == 0 && !p.Binary {
+			set(&PatchResult{Path: p.OldPath, Deleted: true})
+			continue
+		}
+		applied, conflict, err := o.applyContent(p, content)
+		if err != nil {
+			return nil, err
+		}
+		switch p.Status {
+		case StatusDeleted:
+			if len(applied) > 0 {
+				return nil, fmt.Errorf("%w: %s: the deleted file still has contents", ErrPatchFailed, p.OldPath)
+			}
+			set(&PatchResult{Path: p.OldPath, Deleted: true})
+			continue
+		case StatusRenamed:
+			set(&PatchResult{Path: p.OldPath, Deleted: true})
+		}
+		set(&PatchResult{Path: p.NewPath, Content: applied, Mode: p.NewMode, Conflict: conflict})
+	}
+	list := make([]*PatchResult, 0, len(order))
+	for _, path := range order {
+		list = append(list, results[path])
+	}
+	return list, nil
+}
+
+// returns the content the patch turns the content into
+func (o *ApplyOptions) applyContent(p *FilePatch, content []byte) (applied []byte, conflict bool, err error) {
+	if p.Binary {
+		if !p.BinaryData {
+			return nil, false, fmt.Errorf("%w: %s: cannot apply a binary patch without its data (see diff --binary)", ErrPatchFailed, p.Path())
+		}
+		if p.Status != StatusAdded && !bytes.Equal(content, p.OldContent) {
+			return nil, false, fmt.Errorf("%w: %s: the binary file differs from the patch", ErrPatchFailed, p.Path())
+		}
+		return p.NewContent, false, nil
+	}
+	applied, err = ApplyHunks(content, p.Hunks, o.Fuzz)
+	if err == nil {
+		return applied, false, nil
+	}
+	if !o.ThreeWay {
+		return nil, false, fmt.Errorf("%w: %s: %w", ErrPatchFailed, p.Path(), err)
+	}
+	merged, conflict, err3 := o.threeWay(p, content)
+	if err3 != nil {
+		return nil, false, fmt.Errorf("%w: %s: %w, and the three-way merge failed: %w", ErrPatchFailed, p.Path(), err, err3)
+	}
+	return merged, conflict, nil
+}
+
+// applies the patch to the blob it was made from, and merges the changes into the content
+func (o *ApplyOptions) threeWay(p *FilePatch, content []byte) ([]byte, bool, error) {
+	if p.OldOid == "" || strings.Trim(p.OldOid, "0") == "" {
+		return nil, false, errors.New("the patch records no blob to merge with")
+	}
+	oid, err := ResolveOidPrefix(o.ObjDir, p.OldOid)
+	if err != nil {
+		return nil, false, err
+	}
+	base, err := ReadAllFileContent(filepath.Join(o.ObjDir, oid))
+	if err != nil {
+		return nil, false, err
+	}
+	theirs, err := ApplyHunks(base, p.Hunks, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	merged, conflict := Merge3(content, base, theirs)
+	return merged, conflict, nil
+}
+
+// ApplyHunks applies the hunks in order to the content. A hunk is looked for at the line its header
+// tells, shifted by the offset the previous hunk was found at, and then at the lines nearer to it first.
+// If it is found nowhere, up to fuzz context lines at each end of it are ignored to try again.
+func ApplyHunks(content []byte, hunks []*Hunk, fuzz int) ([]byte, error) {
+	lines := splitLines(string(content))
+	var out strings.Builder
+	pos, offset := 0, 0
+	for n, h := range hunks {
+		at, head, tail, ok := findHunk(lines, h, pos, offset, fuzz)
+		if !ok {
+			return nil, fmt.Errorf("hunk #%d does not apply at line %d", n+1, h.OldStart)
+		}
+		old, neu := hunkSides(h.Lines[head : len(h.Lines)-tail])
+		for _, line := range lines[pos:at] {
+			out.WriteString(line)
+		}
+		for _, line := range neu {
+			out.WriteString(line)
+		}
+		pos = at + len(old)
+		offset = at - (hunkPosition(h) + head)
+	}
+	for _, line := range lines[pos:] {
+		out.WriteString(line)
+	}
+	return []byte(out.String()), nil
+}
+
+// the index of the line the old side of the hunk begins at
+func hunkPosition(h *Hunk) int {
+	if h.OldLines == 0 {
+		return h.OldStart
+	}
+	return h.OldStart - 1
+}
+
+// returns the old and the new sides of the hunk lines, without their ops
+func hunkSides(hunkLines []string) (old []string, neu []string) {
+	for _, line := range hunkLines {
+		if line[0] != LineInsert {
+			old = append(old, line[1:])
+		}
+		if line[0] != LineDelete {
+			neu = append(neu, line[1:])
+		}
+	}
+	return old, neu
+}
+
+// finds the index of the lines at or after pos where the old side of the hunk is, together with the numbers
+// of the context lines ignored at its head and tail by the fuzz
+func findHunk(lines []string, h *Hunk, pos int, offset int, fuzz int) (at int, head int, tail int, ok bool) {
+	leading, trailing := 0, 0
+	for leading < len(h.Lines) && h.Lines[leading][0] == LineContext {
+		leading++
+	}
+	for trailing < len(h.Lines)-leading && h.Lines[len(h.Lines)-1-trailing][0] == LineContext {
+		trailing++
+	}
+	for f := 0; f <= fuzz; f++ {
+		if f > 0 && f > leading && f > trailing {
+			break //nothing more to ignore
+		}
+		head, tail = min(f, leading), min(f, trailing)
+		old, _ := hunkSides(h.Lines[head : len(h.Lines)-tail])
+		want := hunkPosition(h) + head + offset
+		last := len(lines) - len(old)
+		for d := 0; want-d >= pos || want+d <= last; d++ {
+			for _, at := range []int{want + d, want - d} {
+				if at >= pos && at <= last && linesEqual(lines[at:at+len(old)], old) {
+					return at, head, tail, true
+				}
+			}
+		}
+	}
+	return 0, 0, 0, false
+}
+
+func linesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge3 merges the changes from base to theirs into ours line by line. Where both sides change the
+// same lines differently, both of them are kept between conflict markers, and conflict is true:
+// -----------------
+// <<<<<<< ours
+// {lines of ours}
+// =======
+// {lines of theirs}
+// >>>>>>> theirs
+// -----------------
+func Merge3(ours []byte, base []byte, theirs []byte) (merged []byte, conflict bool) {
+	o := NewDiffOptions()
+	oLines, bLines, tLines := splitLines(string(ours)), splitLines(string(base)), splitLines(string(theirs))
+	b, oSeq := o.internLines(bLines, oLines)
+	_, tSeq := o.internLines(bLines, tLines) //the base gets the same ids as above
+	//the line of each side every base line is matched with, or -1
+	oursAt, theirsAt := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range oursAt {
+		oursAt[i], theirsAt[i] = -1, -1
+	}
+	for _, m := range o.matchSequences(b, oSeq) {
+		oursAt[m.i] = m.j
+	}
+	for _, m := range o.matchSequences(b, tSeq) {
+		theirsAt[m.i] = m.j
+	}
+
+	var out strings.Builder
+	writeLines := func(lines []string) {
+		for _, line := range lines {
+			out.WriteString(line)
+		}
+	}
+	i, j, k := 0, 0, 0
+	for end := 0; end <= len(b); end++ {
+		if oursAt[end] < 0 || theirsAt[end] < 0 {
+			continue
+		}
+		//base[i:end], ours[j:oursAt[end]] and theirs[k:theirsAt[end]] lie between stable lines
+		baseChunk, oursChunk, theirsChunk := bLines[i:end], oLines[j:oursAt[end]], tLines[k:theirsAt[end]]
+		switch {
+		case linesEqual(oursChunk, baseChunk):
+			writeLines(theirsChunk)
+		case linesEqual(theirsChunk, baseChunk), linesEqual(oursChunk, theirsChunk):
+			writeLines(oursChunk)
+		default:
+			conflict = true
+			out.WriteString("<<<<<<< ours\n")
+			writeLines(oursChunk)
+			terminateLine(&out)
+			out.WriteString("=======\n")
+			writeLines(theirsChunk)
+			terminateLine(&out)
+			out.WriteString(">>>>>>> theirs\n")
+		}
+		if end < len(b) {
+			out.WriteString(oLines[oursAt[end]])
+		}
+		i, j, k = end+1, oursAt[end]+1, theirsAt[end]+1
+	}
+	return []byte(out.String()), conflict
+}
+
+// puts "\n" at the end unless it is empty or already there
+func terminateLine(b *strings.Builder) {
+	if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		b.WriteString("\n")
+	}
+}
+
+// WritePatchResults writes the results into the working tree under rootPath. A deleted path is
+// removed, and the mode of a patch sets the executable bit of the file.
+func WritePatchResults(rootPath string, results []*PatchResult) error {
+	for _, r := range results {
+		path := filepath.Join(rootPath, filepath.FromSlash(r.Path))
+		if r.Deleted {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("WritePatchResults: %w", err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("WritePatchResults: %w", err)
+		}
+		if err := WriteFile(path, r.Content); err != nil {
+			return fmt.Errorf("WritePatchResults: %w", err)
+		}
+		perm := os.FileMode(0644)
+		if r.Mode == ModeExecutable {
+			perm = 0755
+		}
+		if r.Mode != "" {
+			if err := os.Chmod(path, perm); err != nil {
+				return fmt.Errorf("WritePatchResults: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package data_test
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+const gitPatch = `From 1234 Mon Sep 17 00:00:00 2001
+Subject: [PATCH] change files
+
+diff --git a/f b/f
+index 3b18e51..8a9e2a0 100644
+--- a/f
++++ b/f
+@@ -1,3 +1,3 @@
+ 1
+-2
++two
+ 3
+diff --git a/new b/new
+new file mode 100755
+index 0000000..e69de29
+--- /dev/null
++++ b/new
+@@ -0,0 +1 @@
++created
+\ No newline at end of file
+diff --git a/old b/old
+deleted file mode 100644
+index 9daeafb..0000000
+--- a/old
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/a b/moved
+similarity index 100%
+rename from a
+rename to moved
+`
+
+func TestParsePatch(t *testing.T) {
+	got, err := data.ParsePatch([]byte(gitPatch))
+
+	if err != nil {
+		t.Errorf("error should be nil:\nerror:%s\n", err)
+	}
+	want := []*data.FilePatch{
+		{
+			OldPath: "f", NewPath: "f", OldMode: data.ModeBlob, NewMode: data.ModeBlob, Status: data.StatusModified,
+			OldOid: "3b18e51", NewOid: "8a9e2a0",
+			Hunks: []*data.Hunk{{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3, Lines: []string{" 1\n", "-2\n", "+two\n", " 3\n"}}},
+		},
+		{
+			NewPath: "new", NewMode: data.ModeExecutable, Status: data.StatusAdded, OldOid: "0000000", NewOid: "e69de29",
+			Hunks: []*data.Hunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []string{"+created"}}},
+		},
+		{
+			OldPath: "old", OldMode: data.ModeBlob, Status: data.StatusDeleted, OldOid: "9daeafb", NewOid: "0000000",
+			Hunks: []*data.Hunk{{OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0, Lines: []string{"-gone\n"}}},
+		},
+		{OldPath: "a", NewPath: "moved", Status: data.StatusRenamed, Score: 100},
+	}
+	CmpStructs(t, got, want)
+}
+
+func TestApplyPatches(t *testing.T) {
+	patches, err := data.ParsePatch([]byte(gitPatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"f": "1\n2\n3\n", "old": "gone\n", "a": "a\n"}
+	src := func(path string) ([]byte, bool, error) {
+		c, ok := files[path]
+		return []byte(c), ok, nil
+	}
+
+	got, err := data.NewApplyOptions("").ApplyPatches(patches, src)
+
+	if err != nil {
+		t.Errorf("error should be nil:\nerror:%s\n", err)
+	}
+	want := []*data.PatchResult{
+		{Path: "f", Content: []byte("1\ntwo\n3\n"), Mode: data.ModeBlob},
+		{Path: "new", Content: []byte("created"), Mode: data.ModeExecutable},
+		{Path: "old", Deleted: true},
+		{Path: "a", Deleted: true},
+		{Path: "moved", Content: []byte("a\n")},
+	}
+	CmpStructs(t, got, want)
+
+	t.Run("nothing applies when a patch does not", func(t *testing.T) {
+		files["f"] = "changed\n"
+
+		_, err := data.NewApplyOptions("").ApplyPatches(patches, src)
+
+		if !errors.Is(err, data.ErrPatchFailed) {
+			t.Errorf("error should be ErrPatchFailed: { got: %v }", err)
+		}
+	})
+}
+
+func TestApplyHunks(t *testing.T) {
+	hunks := data.DiffHunks([]byte("1\n2\n3\n4\n5\n6\n7\n"), []byte("1\n2\n3\nfour\n5\n6\n7\n"), 3)
+	tests := []struct {
+		desc    string
+		content string
+		fuzz    int
+		want    string
+		fails   bool
+	}{
+		{desc: "01_exact", content: "1\n2\n3\n4\n5\n6\n7\n", want: "1\n2\n3\nfour\n5\n6\n7\n"},
+		{desc: "02_offset", content: "0\n0\n1\n2\n3\n4\n5\n6\n7\n", want: "0\n0\n1\n2\n3\nfour\n5\n6\n7\n"},
+		{desc: "03_fuzz", content: "one\n2\n3\n4\n5\n6\nseven\n", fuzz: 1, want: "one\n2\n3\nfour\n5\n6\nseven\n"},
+		{desc: "04_beyond the fuzz", content: "one\n2\n3\n4\n5\n6\nseven\n", fuzz: 0, fails: true},
+		{desc: "05_changed line", content: "1\n2\n3\nFOUR\n5\n6\n7\n", fuzz: 3, fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := data.ApplyHunks([]byte(tt.content), hunks, tt.fuzz)
+
+			if tt.fails {
+				if err == nil {
+					t.Errorf("error should not be nil: { got: %q }", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("error should be nil:\nerror:%s\n", err)
+			}
+			CmpStructs(t, string(got), tt.want)
+		})
+	}
+}
+
+func TestApplyHunksRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for n := 0; n < 200; n++ {
+		from := randomContent(r, r.Intn(30), 8)
+		to := randomContent(r, r.Intn(30), 8)
+		patch := []byte("--- a/f\n+++ b/f\n")
+		var hunks []byte
+		for _, h := range data.DiffHunks([]byte(from), []byte(to), r.Intn(4)) {
+			hunks = append(hunks, h.Header()+"\n"...)
+			for _, l := range h.Lines {
+				hunks = append(hunks, l...)
+			}
+		}
+		patches, err := data.ParsePatch(append(patch, hunks...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(patches) == 0 {
+			continue
+		}
+
+		got, err := data.ApplyHunks([]byte(from), patches[0].Hunks, 0)
+		if err != nil || string(got) != to {
+			t.Fatalf("should turn from into to: { from: %q, to: %q, got: %q, error: %v }", from, to, got, err)
+		}
+		patches[0].Reverse()
+		got, err = data.ApplyHunks([]byte(to), patches[0].Hunks, 0)
+		if err != nil || string(got) != from {
+			t.Fatalf("reverse should turn to into from: { from: %q, to: %q, got: %q, error: %v }", from, to, got, err)
+		}
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		desc     string
+		ours     string
+		base     string
+		theirs   string
+		want     string
+		conflict bool
+	}{
+		{
+			desc:   "01_separate changes",
+			ours:   "ONE\n2\n3\n4\n5\n",
+			base:   "1\n2\n3\n4\n5\n",
+			theirs: "1\n2\n3\n4\nFIVE\n",
+			want:   "ONE\n2\n3\n4\nFIVE\n",
+		},
+		{
+			desc:   "02_the same change",
+			ours:   "1\nTWO\n3\n",
+			base:   "1\n2\n3\n",
+			theirs: "1\nTWO\n3\n",
+			want:   "1\nTWO\n3\n",
+		},
+		{
+			desc:     "03_conflict",
+			ours:     "1\nours\n3\n",
+			base:     "1\n2\n3\n",
+			theirs:   "1\ntheirs\n3\n",
+			want:     "1\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\n3\n",
+			conflict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, conflict := data.Merge3([]byte(tt.ours), []byte(tt.base), []byte(tt.theirs))
+
+			CmpStructs(t, string(got), tt.want)
+			if conflict != tt.conflict {
+				t.Errorf("conflict should be %t", tt.conflict)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	AttributesFile = ".pgitattributes"
+
+	AttrSet   = "true"
+	AttrUnset = "false"
+)
+
+// AttrRule is a line of the attributes file like this:
+// -----------------
+// pattern   attr...
+// *.png     filter=lfs -diff
+// assets/** filter=lfs
+// -----------------
+// "attr" sets the attribute, "-attr" unsets it and "attr=value" gives it a value.
+type AttrRule struct {
+	Pattern string
+	Attrs   map[string]string
+}
+
+// Attributes holds rules in the order of the attributes file. Later rules take precedence.
+type Attributes []*AttrRule
+
+// Reads the attributes file in the path. If there is no such a file, empty Attributes is returned.
+func ReadAttributes(path string) (Attributes, error) {
+	c, err := ReadAllFileContent(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Attributes{}, nil
+		}
+		return nil, fmt.Errorf("ReadAttributes: %w", err)
+	}
+	return ParseAttributes(c), nil
+}
+
+// Parses the content of an attributes file. Blank lines and lines starting with "#" are ignored.
+func ParseAttributes(content []byte) Attributes {
+	var attrs Attributes
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Split(bufio.ScanLines)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		rule := &AttrRule{Pattern: fields[0], Attrs: make(map[string]string)}
+		for _, f := range fields[1:] {
+			switch {
+			case strings.HasPrefix(f, "-"):
+				rule.Attrs[f[1:]] = AttrUnset
+			case strings.Contains(f, "="):
+				k, v, _ := strings.Cut(f, "=")
+				rule.Attrs[k] = v
+			default:
+				rule.Attrs[f] = AttrSet
+			}
+		}
+		attrs = append(attrs, rule)
+	}
+	return attrs
+}
+
+// Returns the value of the attribute for a slash-separated path relative to the root directory.
+// An empty string means the attribute is not specified.
+func (a Attributes) Get(relPath string, attr string) string {
+	var value string
+	for _, rule := range a {
+		v, ok := rule.Attrs[attr]
+		if !ok || !rule.match(relPath) {
+			continue
+		}
+		value = v
+	}
+	return value
+}
+
+// reports whether the content of the path should be stored in the large file store
+func (a Attributes) IsLFS(relPath string) bool {
+	return a.Get(relPath, "filter") == "lfs"
+}
+
+// A pattern without "/" matches the base name at any depth, while a pattern with "/" matches
+// the whole relative path. A trailing "/**" matches everything under the directory.
+func (r *AttrRule) match(relPath string) bool {
+	pattern := strings.TrimPrefix(r.Pattern, "/")
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return strings.HasPrefix(relPath, dir+"/")
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(relPath))
+		return ok
+	}
+	ok, _ := path.Match(pattern, relPath)
+	return ok
+}
@@ -0,0 +1,30 @@
+package data_test
+
+import (
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestAttributesGet(t *testing.T) {
+	attrs := data.ParseAttributes([]byte("# comment\n*.png filter=lfs -diff\nassets/** filter=lfs\ndocs/*.png -filter\n"))
+	tests := []struct {
+		desc    string
+		relPath string
+		attr    string
+		want    string
+	}{
+		{desc: "01_matched by base name", relPath: "img/logo.png", attr: "filter", want: "lfs"},
+		{desc: "02_unset attribute", relPath: "logo.png", attr: "diff", want: data.AttrUnset},
+		{desc: "03_matched by directory", relPath: "assets/video/intro.mp4", attr: "filter", want: "lfs"},
+		{desc: "04_overridden by a later rule", relPath: "docs/figure.png", attr: "filter", want: data.AttrUnset},
+		{desc: "05_not matched", relPath: "main.go", attr: "filter", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := attrs.Get(tt.relPath, tt.attr)
+
+			CmpStructs(t, got, tt.want)
+		})
+	}
+}
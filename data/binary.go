@@ -0,0 +1,239 @@
+package data
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// only this many bytes at the beginning of a content are inspected to tell if it is binary
+const binaryProbeSize = 8000
+
+// the header line of a binary patch, followed by the literals of the new and the old contents
+const BinaryPatchHeader = "GIT binary patch"
+
+// the number of bytes encoded on each line of a binary literal
+const binaryLineSize = 52
+
+// the digits of base85, in the order used by git
+const base85Digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+var ErrMalformedBinaryPatch = errors.New("malformed binary patch")
+
+// Reports whether the content looks binary: it has a NUL byte, or more than 1 in 10 of its bytes
+// are control characters other than whitespace. Only the beginning of the content is inspected.
+func IsBinary(content []byte) bool {
+	probe := content[:min(len(content), binaryProbeSize)]
+	if bytes.IndexByte(probe, 0) >= 0 {
+		return true
+	}
+	controls := 0
+	for _, c := range probe {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\b' && c != 0x1b {
+			controls++
+		}
+	}
+	return controls*10 > len(probe)
+}
+
+// Reports whether the file in the path is binary by its attributes: "binary" or "-diff" makes it
+// binary, while "diff" makes it text. ok is false if the attributes say nothing about it.
+func (a Attributes) IsBinary(relPath string) (binary bool, ok bool) {
+	if a.Get(relPath, "binary") == AttrSet {
+		return true, true
+	}
+	switch a.Get(relPath, "diff") {
+	case AttrUnset:
+		return true, true
+	case AttrSet:
+		return false, true
+	}
+	return false, false
+}
+
+// tells whether the contents of the path are diffed as binary, by the attributes if they say so,
+// or else by the contents themselves
+func (o *DiffOptions) isBinary(relPath string, contents ...[]byte) bool {
+	if relPath != "" {
+		if binary, ok := o.Attributes.IsBinary(relPath); ok {
+			return binary
+		}
+	}
+	for _, c := range contents {
+		if IsBinary(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// writes the notice of differing binary files in place of the hunks
+func writeBinaryNotice(buf *bytes.Buffer, fromName string, toName string) {
+	fmt.Fprintf(buf, "Binary files %s and %s differ\n", fromName, toName)
+}
+
+// Writes the binary patch turning from into to, which holds the whole new content and the whole
+// old content so that it can be applied in either direction:
+// -----------------
+// GIT binary patch
+// literal {size of to}
+// {base85 lines of the deflated to}
+//
+// literal {size of from}
+// {base85 lines of the deflated from}
+//
+// -----------------
+func WriteBinaryPatch(buf *bytes.Buffer, from []byte, to []byte) error {
+	buf.WriteString(BinaryPatchHeader + "\n")
+	for _, content := range [][]byte{to, from} {
+		if err := writeBinaryLiteral(buf, content); err != nil {
+			return fmt.Errorf("WriteBinaryPatch: %w", err)
+		}
+	}
+	return nil
+}
+
+func writeBinaryLiteral(buf *bytes.Buffer, content []byte) error {
+	var deflated bytes.Buffer
+	w := zlib.NewWriter(&deflated)
+	if _, err := w.Write(content); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(buf, "literal %d\n", len(content))
+	data := deflated.Bytes()
+	for len(data) > 0 {
+		n := min(len(data), binaryLineSize)
+		buf.WriteByte(binaryLineLength(n))
+		buf.WriteString(encodeBase85(data[:n]))
+		buf.WriteString("\n")
+		data = data[n:]
+	}
+	buf.WriteString("\n")
+	return nil
+}
+
+// Parses the lines of a binary patch following its header line, and returns the new and the old
+// contents in it. The lines are without their "\n".
+func ParseBinaryPatch(lines []string) (to []byte, from []byte, err error) {
+	to, rest, err := parseBinaryLiteral(lines)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ParseBinaryPatch: %w", err)
+	}
+	from, _, err = parseBinaryLiteral(rest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ParseBinaryPatch: %w", err)
+	}
+	return to, from, nil
+}
+
+// parses a literal up to its blank line, and returns its content and the lines after it
+func parseBinaryLiteral(lines []string) ([]byte, []string, error) {
+	if len(lines) == 0 {
+		return nil, nil, fmt.Errorf("%w: missing literal", ErrMalformedBinaryPatch)
+	}
+	sizeStr, ok := strings.CutPrefix(lines[0], "literal ")
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q", ErrMalformedBinaryPatch, lines[0])
+	}
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %q", ErrMalformedBinaryPatch, lines[0])
+	}
+	var deflated []byte
+	i := 1
+	for ; i < len(lines) && lines[i] != ""; i++ {
+		line := lines[i]
+		n := binaryLineLengthValue(line[0])
+		decoded, err := decodeBase85(line[1:])
+		if n < 0 || err != nil || len(decoded) < n {
+			return nil, nil, fmt.Errorf("%w: %q", ErrMalformedBinaryPatch, line)
+		}
+		deflated = append(deflated, decoded[:n]...)
+	}
+	if i < len(lines) {
+		i++ //the blank line closing the literal
+	}
+	r, err := zlib.NewReader(bytes.NewReader(deflated))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrMalformedBinaryPatch, err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrMalformedBinaryPatch, err)
+	}
+	if len(content) != size {
+		return nil, nil, fmt.Errorf("%w: size %d, want %d", ErrMalformedBinaryPatch, len(content), size)
+	}
+	return content, lines[i:], nil
+}
+
+// the length of a line is written as "A"-"Z" for 1-26 bytes and "a"-"z" for 27-52 bytes
+func binaryLineLength(n int) byte {
+	if n <= 26 {
+		return byte('A' + n - 1)
+	}
+	return byte('a' + n - 27)
+}
+
+// returns -1 for an invalid character
+func binaryLineLengthValue(c byte) int {
+	switch {
+	case 'A' <= c && c <= 'Z':
+		return int(c-'A') + 1
+	case 'a' <= c && c <= 'z':
+		return int(c-'a') + 27
+	}
+	return -1
+}
+
+// encodes every 4 bytes into 5 digits, the most significant first. The last group is padded with zeros.
+func encodeBase85(data []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i += 4 {
+		var group uint32
+		for j := 0; j < 4; j++ {
+			group <<= 8
+			if i+j < len(data) {
+				group |= uint32(data[i+j])
+			}
+		}
+		var digits [5]byte
+		for j := 4; j >= 0; j-- {
+			digits[j] = base85Digits[group%85]
+			group /= 85
+		}
+		b.Write(digits[:])
+	}
+	return b.String()
+}
+
+// decodes the digits of encodeBase85, including the padding of the last group
+func decodeBase85(s string) ([]byte, error) {
+	if len(s)%5 != 0 {
+		return nil, ErrMalformedBinaryPatch
+	}
+	out := make([]byte, 0, len(s)/5*4)
+	for i := 0; i < len(s); i += 5 {
+		var group uint64
+		for j := 0; j < 5; j++ {
+			d := strings.IndexByte(base85Digits, s[i+j])
+			if d < 0 {
+				return nil, ErrMalformedBinaryPatch
+			}
+			group = group*85 + uint64(d)
+		}
+		if group > 0xffffffff {
+			return nil, ErrMalformedBinaryPatch
+		}
+		out = append(out, byte(group>>24), byte(group>>16), byte(group>>8), byte(group))
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package data_test
+
+import (
+	"bytes"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		desc    string
+		content []byte
+		want    bool
+	}{
+		{desc: "01_text", content: []byte("hello\n\tworld\r\n"), want: false},
+		{desc: "02_empty", content: []byte{}, want: false},
+		{desc: "03_NUL byte", content: []byte("PNG\x00\x01"), want: true},
+		{desc: "04_control characters", content: []byte("\x01\x02\x03abc"), want: true},
+		{desc: "05_NUL beyond the probe", content: append(bytes.Repeat([]byte("a"), 9000), 0), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := data.IsBinary(tt.content)
+
+			if got != tt.want {
+				t.Errorf("should be equal: { got: %t, want: %t }", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffFilesBinary(t *testing.T) {
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, "from"), filepath.Join(dir, "to")
+	if err := data.WriteFile(from, []byte("a\x00b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.WriteFile(to, []byte("a\x00c\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := data.DiffFiles(from, to)
+
+	if err != nil {
+		t.Errorf("error should be nil:\nerror:%s\n", err)
+	}
+	CmpStructs(t, got, "Binary files "+from+" and "+to+" differ\n")
+}
+
+func TestDiffEntryContentBinaryAttributes(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := data.WriteFile(filepath.Join(srcDir, "oid_from"), []byte("a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.WriteFile(filepath.Join(srcDir, "oid_to"), []byte("b\n")); err != nil {
+		t.Fatal(err)
+	}
+	entry := &data.DiffEntry{Path: "font.dat", OldOid: "oid_from", NewOid: "oid_to",
+		OldMode: data.ModeBlob, NewMode: data.ModeBlob, Status: data.StatusModified}
+	opts := data.NewDiffOptions()
+	opts.Attributes = data.ParseAttributes([]byte("*.dat -diff\n"))
+
+	got, err := opts.DiffEntryContent(entry, srcDir)
+
+	if err != nil {
+		t.Errorf("error should be nil:\nerror:%s\n", err)
+	}
+	CmpStructs(t, got, "Binary files a/font.dat and b/font.dat differ\n")
+}
+
+func TestBinaryPatch(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tests := []struct {
+		desc string
+		from []byte
+		to   []byte
+	}{
+		{desc: "01_modified", from: []byte("a\x00b"), to: []byte("a\x00c\x01")},
+		{desc: "02_added", from: nil, to: []byte("\x00\x01\x02")},
+		{desc: "03_random bytes over lines", from: randomBytes(r, 500), to: randomBytes(r, 1000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := data.WriteBinaryPatch(&buf, tt.from, tt.to); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if lines[0] != data.BinaryPatchHeader {
+				t.Fatalf("should begin with the header: { got: %q }", lines[0])
+			}
+
+			to, from, err := data.ParseBinaryPatch(lines[1:])
+
+			if err != nil {
+				t.Errorf("error should be nil:\nerror:%s\n", err)
+			}
+			if !bytes.Equal(to, tt.to) || !bytes.Equal(from, tt.from) {
+				t.Errorf("should be restored: { to: %q, from: %q }", to, from)
+			}
+		})
+	}
+}
+
+func randomBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	r.Read(b)
+	return b
+}
@@ -0,0 +1,199 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// the files keeping the state of a bisection
+var (
+	//".pgit/BISECT_START", the content of HEAD before the bisection started
+	BisectStartPath = filepath.Join(PgitDirBase, "BISECT_START")
+	//".pgit/BISECT_BAD", the oid of the bad commit
+	BisectBadPath = filepath.Join(PgitDirBase, "BISECT_BAD")
+	//".pgit/BISECT_GOOD", the oids of the good commits, one per line
+	BisectGoodPath = filepath.Join(PgitDirBase, "BISECT_GOOD")
+	//".pgit/BISECT_SKIP", the oids of the skipped commits, one per line
+	BisectSkipPath = filepath.Join(PgitDirBase, "BISECT_SKIP")
+	//".pgit/BISECT_LOG", the commands of the bisection, which can be replayed
+	BisectLogPath = filepath.Join(PgitDirBase, "BISECT_LOG")
+)
+
+var ErrNotBisecting = errors.New("not bisecting")
+
+// Bisect is the state of a binary search for the commit that introduced a regression, between
+// the bad commit and the good commits.
+type Bisect struct {
+	Start string //the content of HEAD to get back to after the bisection, like "ref: .pgit/refs/heads/master <- HEAD"
+	Bad   string
+	Good  []string
+	Skip  []string
+}
+
+// ReadBisect reads the state of the bisection in progress, or returns ErrNotBisecting.
+func ReadBisect() (*Bisect, error) {
+	start, err := os.ReadFile(BisectStartPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("ReadBisect: %w", ErrNotBisecting)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ReadBisect: %w", err)
+	}
+	b := &Bisect{Start: string(start)}
+	for path, oids := range map[string]*[]string{BisectGoodPath: &b.Good, BisectSkipPath: &b.Skip} {
+		content, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("ReadBisect: %w", err)
+		}
+		*oids = strings.Fields(string(content))
+	}
+	bad, err := os.ReadFile(BisectBadPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("ReadBisect: %w", err)
+	}
+	b.Bad = strings.TrimSpace(string(bad))
+	return b, nil
+}
+
+// Write saves the state of the bisection.
+func (b *Bisect) Write() error {
+	files := map[string]string{
+		BisectStartPath: b.Start,
+		BisectBadPath:   b.Bad,
+		BisectGoodPath:  joinLines(b.Good),
+		BisectSkipPath:  joinLines(b.Skip),
+	}
+	for path, content := range files {
+		if err := WriteFile(path, []byte(content)); err != nil {
+			return fmt.Errorf("Bisect Write: %w", err)
+		}
+	}
+	return nil
+}
+
+// StartOid returns the oid of the commit HEAD pointed to before the bisection started.
+func (b *Bisect) StartOid() (string, error) {
+	start := []byte(b.Start)
+	if !isSymbolic(start) {
+		return strings.TrimSpace(b.Start), nil
+	}
+	oid, err := ResolveRefOid(string(getSymbolicRefPath(start)))
+	if err != nil {
+		return "", fmt.Errorf("Bisect StartOid: %w", err)
+	}
+	return oid, nil
+}
+
+// returns the lines each followed by a newline
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// AppendBisectLog adds the lines to the log of the bisection.
+func AppendBisectLog(lines ...string) error {
+	f, err := os.OpenFile(BisectLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("AppendBisectLog: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(joinLines(lines)); err != nil {
+		return fmt.Errorf("AppendBisectLog: %w", err)
+	}
+	return nil
+}
+
+// RemoveBisect removes all the files of the bisection.
+func RemoveBisect() error {
+	for _, path := range []string{BisectStartPath, BisectBadPath, BisectGoodPath, BisectSkipPath, BisectLogPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("RemoveBisect: %w", err)
+		}
+	}
+	return nil
+}
+
+// BisectStep is the result of a step of the bisection. Exactly one of Next, Found and Skipped
+// is set once both a bad and a good commit are known.
+type BisectStep struct {
+	Next      string   //the commit to test next
+	Remaining int      //the number of the commits left to test after Next
+	Steps     int      //the rough number of steps left after Next
+	Found     string   //the first bad commit
+	Skipped   []string //the commits any of which may be the first bad commit, since only skipped ones are left
+}
+
+// Next finds the commit halving the commits that may be the first bad one, that is, the ancestors of
+// the bad commit (including itself) that are not reachable from the good commits. Among them,
+// the commit whose ancestors in the candidates are closest to half of them is chosen, leaving out
+// the skipped commits. A nil step is returned until both a bad and a good commit are known.
+func (b *Bisect) Next() (*BisectStep, error) {
+	if b.Bad == "" || len(b.Good) == 0 {
+		return nil, nil
+	}
+	w := NewRevWalk()
+	w.Include, w.Exclude = []string{b.Bad}, b.Good
+	candidates, h, err := w.Walk()
+	if err != nil {
+		return nil, fmt.Errorf("Bisect Next: %w", err)
+	}
+	inRange := make(map[string]bool, len(candidates))
+	for _, oid := range candidates {
+		inRange[oid] = true
+	}
+	skipped := make(map[string]bool, len(b.Skip))
+	for _, oid := range b.Skip {
+		skipped[oid] = true
+	}
+	var testable []string
+	for _, oid := range candidates {
+		if oid != b.Bad && !skipped[oid] {
+			testable = append(testable, oid)
+		}
+	}
+	if len(testable) == 0 {
+		if len(candidates) == 1 {
+			return &BisectStep{Found: b.Bad}, nil
+		}
+		return &BisectStep{Skipped: candidates}, nil
+	}
+
+	n := len(candidates)
+	best, bestScore := "", -1
+	for _, oid := range testable {
+		weight := countAncestors(h, oid, inRange)
+		if score := min(weight, n-weight); score > bestScore {
+			best, bestScore = oid, score
+		}
+	}
+	//the next commit is either bad (leaving its ancestors) or good (leaving the rest) to test
+	weight := countAncestors(h, best, inRange)
+	remaining := max(weight-1, n-weight-1)
+	steps := 0
+	for left := remaining; left > 0; left /= 2 {
+		steps++
+	}
+	return &BisectStep{Next: best, Remaining: remaining, Steps: steps}, nil
+}
+
+// counts the commit and its ancestors among the commits in range
+func countAncestors(h *History, oid string, inRange map[string]bool) int {
+	seen := map[string]bool{oid: true}
+	queue := []string{oid}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, p := range h.Parents(c) {
+			if inRange[p] && !seen[p] {
+				seen[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	return len(seen)
+}
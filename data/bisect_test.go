@@ -0,0 +1,95 @@
+package data_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestBisectNext(t *testing.T) {
+	//a line of history from c0 to c7
+	commits := make([]testCommit, 8)
+	for i := range commits {
+		commits[i] = testCommit{oid: fmt.Sprintf("c%d", i)}
+		if i > 0 {
+			commits[i].parents = []string{fmt.Sprintf("c%d", i-1)}
+		}
+	}
+	writeTestTrees(t, nil, commits)
+
+	b := &data.Bisect{Bad: "c7", Good: []string{"c0"}}
+	for _, want := range []struct {
+		next      string
+		remaining int
+		mark      func(oid string)
+	}{
+		{next: "c4", remaining: 3, mark: func(oid string) { b.Good = append(b.Good, oid) }},
+		{next: "c6", remaining: 1, mark: func(oid string) { b.Bad = oid }},
+		{next: "c5", remaining: 0, mark: func(oid string) { b.Good = append(b.Good, oid) }},
+	} {
+		step, err := b.Next()
+		if err != nil {
+			t.Fatalf("error should be nil: (error: %s)", err)
+		}
+		if step.Next != want.next || step.Remaining != want.remaining {
+			t.Fatalf("the next step should be %s with %d left: (got: %+v)", want.next, want.remaining, step)
+		}
+		want.mark(step.Next)
+	}
+	step, err := b.Next()
+	if err != nil {
+		t.Fatalf("error should be nil: (error: %s)", err)
+	}
+	if step.Found != "c6" {
+		t.Errorf("the first bad commit should be c6: (got: %+v)", step)
+	}
+
+	t.Run("only skipped left", func(t *testing.T) {
+		b := &data.Bisect{Bad: "c2", Good: []string{"c0"}, Skip: []string{"c1"}}
+		step, err := b.Next()
+		if err != nil {
+			t.Fatalf("error should be nil: (error: %s)", err)
+		}
+		CmpStructs(t, step, &data.BisectStep{Skipped: []string{"c2", "c1"}})
+	})
+	t.Run("waiting for a good commit", func(t *testing.T) {
+		step, err := (&data.Bisect{Bad: "c2"}).Next()
+		if err != nil || step != nil {
+			t.Errorf("step should be nil: (step: %+v, error: %v)", step, err)
+		}
+	})
+}
+
+func TestBisectReadWrite(t *testing.T) {
+	if err := os.MkdirAll(data.PgitDirBase, os.ModeDir|0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(data.PgitDirBase) })
+	if _, err := data.ReadBisect(); err == nil {
+		t.Fatal("error should be ErrNotBisecting before a bisection starts")
+	}
+	want := &data.Bisect{Start: "ref: .pgit/refs/heads/master <- HEAD\n", Bad: "c2", Good: []string{"c0", "c1"}, Skip: nil}
+	if err := want.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.AppendBisectLog("pgit bisect start"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := data.ReadBisect()
+
+	if err != nil {
+		t.Fatalf("error should be nil: (error: %s)", err)
+	}
+	want.Skip = []string{}
+	CmpStructs(t, got, want)
+	if err := data.RemoveBisect(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(data.PgitDirBase, "BISECT_LOG")); !os.IsNotExist(err) {
+		t.Errorf("BISECT_LOG should be removed: (error: %v)", err)
+	}
+}
@@ -0,0 +1,351 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// BlameLine is a line of a file attributed to the commit that introduced it.
+type BlameLine struct {
+	Oid      string //the commit introducing the line
+	Path     string //the path of the file in that commit, which differs from the blamed one across renames
+	OrigLine int    //the line number in the file of that commit, starting at 1
+	Line     int    //the line number in the blamed file, starting at 1
+	Text     string //the content of the line without the newline
+}
+
+// BlameOptions controls how the lines are attributed.
+type BlameOptions struct {
+	//the range of the lines to blame, starting at 1 and including End. Zero stands for the first
+	//(or the last) line.
+	Start int
+	End   int
+	//finds the lines moved (or copied) within the file, which are attributed to the commits they come from
+	//instead of the commits moving them
+	Moves bool
+	Diff  *DiffOptions //the algorithm to match the lines of a commit with those of its parents
+}
+
+func NewBlameOptions() *BlameOptions {
+	return &BlameOptions{Diff: NewDiffOptions()}
+}
+
+// Blame is the lines of a file, each with the commit that introduced it.
+type Blame struct {
+	Path    string             //the blamed path
+	Lines   []*BlameLine       //in the order of the file
+	Commits map[string]*Commit //{ key: commit oid, value: commit } of the commits in Lines
+}
+
+var (
+	ErrNoSuchPath       = errors.New("no such path")
+	ErrInvalidLineRange = errors.New("invalid line range")
+)
+
+// blocks of moved lines need at least this number of alphanumeric characters, so that
+// blank lines and braces alone are not regarded as moved
+const BlameMoveScore = 20
+
+// the lines waiting to be attributed to a commit or passed on to its parents
+type blameSuspect struct {
+	oid  string
+	path string
+}
+
+type blamePending struct {
+	orig int //the index of the line in the file of the suspect
+	line *BlameLine
+}
+
+// BlameFile attributes each line of the file in the path of the commit to the commit that introduced it.
+// Going back in the history from the commit, a line is passed on to a parent that has the same line
+// (matched by the diff algorithm, or found in a moved block with Moves), following renames of the file.
+// A line that no parent has is attributed to the commit.
+func BlameFile(oid string, path string, opts *BlameOptions) (*Blame, error) {
+	h, err := LoadHistory(oid)
+	if err != nil {
+		return nil, fmt.Errorf("BlameFile: %w", err)
+	}
+	trees := make(treeCache)
+	tree, err := trees.commitTree(h, oid)
+	if err != nil {
+		return nil, fmt.Errorf("BlameFile: %w", err)
+	}
+	content, err := readTreeFile(tree, path)
+	if err != nil {
+		return nil, fmt.Errorf("BlameFile: %w", err)
+	}
+	lines := splitLines(string(content))
+	b := &Blame{Path: path, Commits: make(map[string]*Commit)}
+	if len(lines) == 0 && opts.Start == 0 && opts.End == 0 {
+		return b, nil
+	}
+	start, end := opts.Start, opts.End
+	if start == 0 {
+		start = 1
+	}
+	if end == 0 {
+		end = len(lines)
+	}
+	if start < 1 || end > len(lines) || start > end {
+		return nil, fmt.Errorf("BlameFile: %w: %d,%d (%d lines)", ErrInvalidLineRange, opts.Start, opts.End, len(lines))
+	}
+
+	suspects := map[blameSuspect][]*blamePending{}
+	for i := start - 1; i < end; i++ {
+		l := &BlameLine{Line: i + 1, Text: strings.TrimSuffix(lines[i], "\n")}
+		b.Lines = append(b.Lines, l)
+		key := blameSuspect{oid, path}
+		suspects[key] = append(suspects[key], &blamePending{orig: i, line: l})
+	}
+	paths := map[string][]string{oid: {path}} //the paths with pending lines of each commit, in the order they come
+	for _, oid := range h.Sorted() {
+		for _, path := range paths[oid] {
+			key := blameSuspect{oid, path}
+			if err := b.passBlame(h, trees, key, suspects, paths, opts); err != nil {
+				return nil, fmt.Errorf("BlameFile: %w", err)
+			}
+		}
+	}
+	return b, nil
+}
+
+// passes the pending lines of the suspect on to its parents, and attributes the rest to the suspect
+func (b *Blame) passBlame(h *History, trees treeCache, key blameSuspect, suspects map[blameSuspect][]*blamePending,
+	paths map[string][]string, opts *BlameOptions) error {
+	pending := suspects[key]
+	delete(suspects, key)
+	if len(pending) == 0 {
+		return nil
+	}
+	tree, err := trees.commitTree(h, key.oid)
+	if err != nil {
+		return err
+	}
+	content, err := readTreeFile(tree, key.path)
+	if err != nil {
+		return err
+	}
+	lines := splitLines(string(content))
+	for _, p := range h.Parents(key.oid) {
+		if len(pending) == 0 {
+			break
+		}
+		ptree, err := trees.commitTree(h, p)
+		if err != nil {
+			return err
+		}
+		ppath, err := parentPath(ptree, tree, key.path, opts.Diff)
+		if err != nil {
+			return err
+		}
+		if ppath == "" {
+			continue
+		}
+		pcontent, err := readTreeFile(ptree, ppath)
+		if err != nil {
+			return err
+		}
+		plines := splitLines(string(pcontent))
+		origins := opts.Diff.matchingLines(plines, lines)
+		if opts.Moves {
+			findMovedLines(origins, plines, lines, pending)
+		}
+		pkey := blameSuspect{p, ppath}
+		var left []*blamePending
+		for _, l := range pending {
+			if i, ok := origins[l.orig]; ok {
+				if _, ok := suspects[pkey]; !ok {
+					paths[p] = append(paths[p], ppath)
+				}
+				suspects[pkey] = append(suspects[pkey], &blamePending{orig: i, line: l.line})
+				continue
+			}
+			left = append(left, l)
+		}
+		pending = left
+	}
+	for _, l := range pending {
+		l.line.Oid, l.line.Path, l.line.OrigLine = key.oid, key.path, l.orig+1
+	}
+	if len(pending) > 0 {
+		b.Commits[key.oid] = h.Commits[key.oid]
+	}
+	return nil
+}
+
+// returns the path of the file in the parent tree, which differs from the path if the file is renamed,
+// or an empty string if the parent has no such file
+func parentPath(ptree Tree, tree Tree, path string, opts *DiffOptions) (string, error) {
+	if elem := lookupTreePath(ptree, path); elem != nil && elem.ObjType == ObjTypeBlob {
+		return path, nil
+	}
+	renames := *opts
+	renames.Renames, renames.Pathspecs = true, []string{path}
+	entries, err := renames.DiffTreeEntries(ptree, tree, filepath.Join(PgitDirBase, ObjDirBase))
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.Path == path && e.Status == StatusRenamed {
+			return e.OldPath, nil
+		}
+	}
+	return "", nil
+}
+
+// returns { key: index of a line of b, value: index of the same line of a } for the lines the diff
+// algorithm matches
+func (o *DiffOptions) matchingLines(aLines []string, bLines []string) map[int]int {
+	a, b := o.internLines(aLines, bLines)
+	matches := o.matchSequences(a, b)
+	origins := make(map[int]int, len(matches))
+	for _, m := range matches[:len(matches)-1] {
+		origins[m.j] = m.i
+	}
+	return origins
+}
+
+// adds to origins the lines of b found in blocks of a, among the pending lines not matched yet.
+// The longest block is taken at each line, and only blocks scoring BlameMoveScore count.
+func findMovedLines(origins map[int]int, a []string, b []string, pending []*blamePending) {
+	left := make(map[int]bool, len(pending))
+	for _, l := range pending {
+		if _, ok := origins[l.orig]; !ok {
+			left[l.orig] = true
+		}
+	}
+	for j := 0; j < len(b); j++ {
+		if !left[j] {
+			continue
+		}
+		best, bestLen := -1, 0
+		for i := range a {
+			n := 0
+			for i+n < len(a) && left[j+n] && a[i+n] == b[j+n] {
+				n++
+			}
+			if n > bestLen {
+				best, bestLen = i, n
+			}
+		}
+		if bestLen == 0 || alnumCount(b[j:j+bestLen]) < BlameMoveScore {
+			continue
+		}
+		for k := 0; k < bestLen; k++ {
+			origins[j+k] = best + k
+		}
+		j += bestLen - 1
+	}
+}
+
+// counts the letters and digits in the lines
+func alnumCount(lines []string) int {
+	n := 0
+	for _, l := range lines {
+		for _, r := range l {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// returns the element of the tree in the slash-separated path, or nil if there is none
+func lookupTreePath(tree Tree, path string) *TreeElem {
+	dir, rest, found := strings.Cut(path, "/")
+	elem := tree[dir]
+	if !found || elem == nil {
+		return elem
+	}
+	return lookupTreePath(elem.Child, rest)
+}
+
+// reads the content of the file in the path of the tree
+func readTreeFile(tree Tree, path string) ([]byte, error) {
+	elem := lookupTreePath(tree, path)
+	if elem == nil || elem.ObjType != ObjTypeBlob {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchPath, path)
+	}
+	content, err := ReadAllFileContent(filepath.Join(PgitDirBase, ObjDirBase, elem.Oid))
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// BlameDateLayout is the layout of the dates in the default output of blame.
+const BlameDateLayout = "2006-01-02 15:04:05 -0700"
+
+// Write writes each line with the abbreviated oid, the author, the date and the line number
+// of the commit introducing it, like this:
+// -----------------
+// 1a2b3c4 (Taro 2025-01-31 15:04:05 +0900 1) content
+// -----------------
+// The path of the file in the commit follows the oid if the file has been renamed.
+func (b *Blame) Write(buf *bytes.Buffer) {
+	if len(b.Lines) == 0 {
+		return
+	}
+	nameWidth, pathWidth := 0, 0
+	renamed := false
+	for _, l := range b.Lines {
+		nameWidth = max(nameWidth, len(b.Commits[l.Oid].Author.Name))
+		pathWidth = max(pathWidth, len(l.Path))
+		renamed = renamed || l.Path != b.Path
+	}
+	lineWidth := len(strconv.Itoa(b.Lines[len(b.Lines)-1].Line))
+	for _, l := range b.Lines {
+		c := b.Commits[l.Oid]
+		buf.WriteString(abbrevOid(l.Oid) + " ")
+		if renamed {
+			fmt.Fprintf(buf, "%-*s ", pathWidth, l.Path)
+		}
+		fmt.Fprintf(buf, "(%-*s %s %*d) %s\n", nameWidth, c.Author.Name, c.Author.When.Format(BlameDateLayout), lineWidth, l.Line, l.Text)
+	}
+}
+
+// WritePorcelain writes the lines in the format for machines. Each group of the lines coming
+// from consecutive lines of a commit starts with "{oid} {orig line} {line} {number of lines}",
+// and the other lines with "{oid} {orig line} {line}". The details of a commit follow the first line
+// from it, and every line of the file follows a tab.
+func (b *Blame) WritePorcelain(buf *bytes.Buffer) {
+	shown := make(map[string]bool, len(b.Commits))
+	for i, l := range b.Lines {
+		prev := i > 0 && b.Lines[i-1].Oid == l.Oid && b.Lines[i-1].OrigLine+1 == l.OrigLine
+		if prev {
+			fmt.Fprintf(buf, "%s %d %d\n", l.Oid, l.OrigLine, l.Line)
+		} else {
+			n := 1
+			for j := i + 1; j < len(b.Lines) && b.Lines[j].Oid == l.Oid && b.Lines[j].OrigLine == l.OrigLine+n; j++ {
+				n++
+			}
+			fmt.Fprintf(buf, "%s %d %d %d\n", l.Oid, l.OrigLine, l.Line, n)
+		}
+		if !shown[l.Oid] {
+			shown[l.Oid] = true
+			c := b.Commits[l.Oid]
+			for _, s := range []struct {
+				role string
+				sig  Signature
+			}{{"author", c.Author}, {"committer", c.Committer}} {
+				fmt.Fprintf(buf, "%s %s\n", s.role, s.sig.Name)
+				fmt.Fprintf(buf, "%s-mail <%s>\n", s.role, s.sig.Email)
+				fmt.Fprintf(buf, "%s-time %d\n", s.role, s.sig.When.Unix())
+				fmt.Fprintf(buf, "%s-tz %s\n", s.role, s.sig.When.Format("-0700"))
+			}
+			fmt.Fprintf(buf, "summary %s\n", c.Subject())
+			if len(c.Parents()) == 0 {
+				buf.WriteString("boundary\n")
+			}
+			fmt.Fprintf(buf, "filename %s\n", l.Path)
+		}
+		fmt.Fprintf(buf, "\t%s\n", l.Text)
+	}
+}
@@ -0,0 +1,142 @@
+package data_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestBlameFile(t *testing.T) {
+	writeTestTrees(t, map[string]string{
+		"f_0": "a\nb\nc\n",
+		"f_1": "a\nB\nc\nd\n",
+		"g_3": "x\na\nB\nc\nd\n",
+	}, []testCommit{
+		{oid: "c0", files: map[string]string{"f": "f_0"}},
+		{oid: "c1", parents: []string{"c0"}, files: map[string]string{"f": "f_1"}},
+		{oid: "c2", parents: []string{"c1"}, files: map[string]string{"g": "f_1"}},
+		{oid: "c3", parents: []string{"c2"}, files: map[string]string{"g": "g_3"}},
+	})
+	tests := []struct {
+		desc  string
+		start int
+		end   int
+		want  []*data.BlameLine
+	}{
+		{
+			desc: "01_whole file across a rename",
+			want: []*data.BlameLine{
+				{Oid: "c3", Path: "g", OrigLine: 1, Line: 1, Text: "x"},
+				{Oid: "c0", Path: "f", OrigLine: 1, Line: 2, Text: "a"},
+				{Oid: "c1", Path: "f", OrigLine: 2, Line: 3, Text: "B"},
+				{Oid: "c0", Path: "f", OrigLine: 3, Line: 4, Text: "c"},
+				{Oid: "c1", Path: "f", OrigLine: 4, Line: 5, Text: "d"},
+			},
+		},
+		{
+			desc:  "02_line range",
+			start: 3,
+			end:   4,
+			want: []*data.BlameLine{
+				{Oid: "c1", Path: "f", OrigLine: 2, Line: 3, Text: "B"},
+				{Oid: "c0", Path: "f", OrigLine: 3, Line: 4, Text: "c"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			opts := data.NewBlameOptions()
+			opts.Start, opts.End = tt.start, tt.end
+
+			got, err := data.BlameFile("c3", "g", opts)
+
+			if err != nil {
+				t.Fatalf("error should be nil: (error: %s)", err)
+			}
+			CmpStructs(t, got.Lines, tt.want)
+		})
+	}
+	t.Run("03_errors", func(t *testing.T) {
+		opts := data.NewBlameOptions()
+		if _, err := data.BlameFile("c3", "f", opts); !errors.Is(err, data.ErrNoSuchPath) {
+			t.Errorf("error should be ErrNoSuchPath: (error: %v)", err)
+		}
+		opts.Start, opts.End = 4, 6
+		if _, err := data.BlameFile("c3", "g", opts); !errors.Is(err, data.ErrInvalidLineRange) {
+			t.Errorf("error should be ErrInvalidLineRange: (error: %v)", err)
+		}
+	})
+}
+
+func TestBlameFileMoves(t *testing.T) {
+	writeTestTrees(t, map[string]string{
+		"before": "the line moved to the end\nfirst line of the block\nsecond line of the block\n",
+		"after":  "first line of the block\nsecond line of the block\nthe line moved to the end\n",
+	}, []testCommit{
+		{oid: "m0", files: map[string]string{"f": "before"}},
+		{oid: "m1", parents: []string{"m0"}, files: map[string]string{"f": "after"}},
+	})
+	for _, moves := range []bool{false, true} {
+		t.Run(fmt.Sprintf("moves=%t", moves), func(t *testing.T) {
+			opts := data.NewBlameOptions()
+			opts.Moves = moves
+
+			got, err := data.BlameFile("m1", "f", opts)
+
+			if err != nil {
+				t.Fatalf("error should be nil: (error: %s)", err)
+			}
+			want := map[bool]string{false: "m1", true: "m0"}[moves]
+			if last := got.Lines[2]; last.Oid != want {
+				t.Errorf("the moved line should be attributed to %s: (got: %s)", want, last.Oid)
+			}
+			if first := got.Lines[0]; first.Oid != "m0" || first.OrigLine != 2 {
+				t.Errorf("the unchanged line should be attributed to m0:2: (got: %s:%d)", first.Oid, first.OrigLine)
+			}
+		})
+	}
+}
+
+func TestBlameWrite(t *testing.T) {
+	when := time.Date(2025, 1, 31, 15, 4, 5, 0, time.FixedZone("", 9*60*60))
+	commits := map[string]*data.Commit{
+		"1111111111": {Author: data.NewSignature("Taro", "taro@example.com", when), Committer: data.NewSignature("Taro", "taro@example.com", when), Msg: "first\n"},
+		"2222222222": {Parent: "1111111111", Author: data.NewSignature("Hanako", "hanako@example.com", when), Committer: data.NewSignature("Hanako", "hanako@example.com", when), Msg: "second\n"},
+	}
+	b := &data.Blame{Path: "f", Commits: commits, Lines: []*data.BlameLine{
+		{Oid: "1111111111", Path: "f", OrigLine: 1, Line: 1, Text: "a"},
+		{Oid: "1111111111", Path: "f", OrigLine: 2, Line: 2, Text: "b"},
+		{Oid: "2222222222", Path: "f", OrigLine: 3, Line: 3, Text: "c"},
+	}}
+	t.Run("default", func(t *testing.T) {
+		var buf bytes.Buffer
+		b.Write(&buf)
+		want := "1111111 (Taro   2025-01-31 15:04:05 +0900 1) a\n" +
+			"1111111 (Taro   2025-01-31 15:04:05 +0900 2) b\n" +
+			"2222222 (Hanako 2025-01-31 15:04:05 +0900 3) c\n"
+		if buf.String() != want {
+			t.Errorf("output should be equal:\n{ got: %q, want: %q }", buf.String(), want)
+		}
+	})
+	t.Run("porcelain", func(t *testing.T) {
+		var buf bytes.Buffer
+		b.WritePorcelain(&buf)
+		details := func(name string, email string, subject string) string {
+			s := ""
+			for _, role := range []string{"author", "committer"} {
+				s += fmt.Sprintf("%s %s\n%s-mail <%s>\n%s-time %d\n%s-tz +0900\n", role, name, role, email, role, when.Unix(), role)
+			}
+			return s + "summary " + subject + "\n"
+		}
+		want := "1111111111 1 1 2\n" + details("Taro", "taro@example.com", "first") + "boundary\nfilename f\n\ta\n" +
+			"1111111111 2 2\n\tb\n" +
+			"2222222222 3 3 1\n" + details("Hanako", "hanako@example.com", "second") + "filename f\n\tc\n"
+		if buf.String() != want {
+			t.Errorf("output should be equal:\n{ got: %q, want: %q }", buf.String(), want)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ConventionalCommit is a commit message following Conventional Commits, like this:
+// -----------------
+// feat(parser)!: accept arrays
+//
+// BREAKING CHANGE: a single value is no longer accepted
+// -----------------
+type ConventionalCommit struct {
+	Type        string //like "feat" and "fix", in lower case
+	Scope       string //empty if none
+	Description string
+	Breaking    bool   //marked by "!" after the type (or scope), or by a BREAKING CHANGE footer
+	BreakingFor string //the text of the BREAKING CHANGE footer, if any
+}
+
+var conventionalSubject = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?: +(.+)$`)
+
+// ParseConventionalCommit parses the message, reporting false if its subject does not follow
+// Conventional Commits.
+func ParseConventionalCommit(msg string) (*ConventionalCommit, bool) {
+	subject, body, _ := strings.Cut(strings.TrimLeft(msg, "\n"), "\n")
+	m := conventionalSubject.FindStringSubmatch(strings.TrimSpace(subject))
+	if m == nil {
+		return nil, false
+	}
+	cc := &ConventionalCommit{Type: strings.ToLower(m[1]), Scope: m[2], Description: m[4], Breaking: m[3] == "!"}
+	sc := bufio.NewScanner(strings.NewReader(body))
+	for sc.Scan() {
+		for _, token := range []string{"BREAKING CHANGE:", "BREAKING-CHANGE:"} {
+			if note, ok := strings.CutPrefix(sc.Text(), token); ok {
+				cc.Breaking, cc.BreakingFor = true, strings.TrimSpace(note)
+			}
+		}
+	}
+	return cc, true
+}
+
+// ChangelogEntry is a commit listed in a changelog.
+type ChangelogEntry struct {
+	Oid     string
+	Commit  *Commit
+	Author  Signature //the author mapped by the mailmap
+	Message *ConventionalCommit
+}
+
+// NewChangelogEntry makes the entry of the commit, with the author mapped by the mailmap.
+func NewChangelogEntry(oid string, c *Commit, mailmap Mailmap) *ChangelogEntry {
+	e := &ChangelogEntry{Oid: oid, Commit: c, Author: mailmap.Map(c.Author)}
+	e.Message, _ = ParseConventionalCommit(c.Msg)
+	return e
+}
+
+// returns the line of the entry in a list, like "- **scope:** description (abc1234)"
+func (e *ChangelogEntry) line(text string) string {
+	if e.Message != nil && e.Message.Scope != "" {
+		text = fmt.Sprintf("**%s:** %s", e.Message.Scope, text)
+	}
+	return fmt.Sprintf("- %s (%s)\n", text, abbrevOid(e.Oid))
+}
+
+// WriteChangelog writes the entries as Markdown under the title, in the sections of breaking changes,
+// features (feat), bug fixes (fix) and the other changes, followed by the contributors with the numbers
+// of their commits. A breaking change is listed in its own section as well as in that of its type.
+// Empty sections are left out.
+func WriteChangelog(buf *bytes.Buffer, title string, entries []*ChangelogEntry) {
+	fmt.Fprintf(buf, "## %s\n", title)
+	sections := []struct {
+		title string
+		lines []string
+	}{{title: "Breaking Changes"}, {title: "Features"}, {title: "Bug Fixes"}, {title: "Other Changes"}}
+	contributions := make(map[string]int)
+	for _, e := range entries {
+		contributions[e.Author.Name]++
+		cc := e.Message
+		switch {
+		case cc == nil:
+			sections[3].lines = append(sections[3].lines, e.line(e.Commit.Subject()))
+			continue
+		case cc.Type == "feat":
+			sections[1].lines = append(sections[1].lines, e.line(cc.Description))
+		case cc.Type == "fix":
+			sections[2].lines = append(sections[2].lines, e.line(cc.Description))
+		default:
+			sections[3].lines = append(sections[3].lines, e.line(cc.Type+": "+cc.Description))
+		}
+		if cc.Breaking {
+			note := cc.BreakingFor
+			if note == "" {
+				note = cc.Description
+			}
+			sections[0].lines = append(sections[0].lines, e.line(note))
+		}
+	}
+	for _, s := range sections {
+		if len(s.lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "\n### %s\n\n", s.title)
+		for _, l := range s.lines {
+			buf.WriteString(l)
+		}
+	}
+	if len(contributions) == 0 {
+		return
+	}
+	names := make([]string, 0, len(contributions))
+	for name := range contributions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf.WriteString("\n### Contributors\n\n")
+	for _, name := range names {
+		fmt.Fprintf(buf, "- %s (%d)\n", name, contributions[name])
+	}
+}
@@ -0,0 +1,91 @@
+package data_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	tests := []struct {
+		desc   string
+		msg    string
+		want   *data.ConventionalCommit
+		wantOK bool
+	}{
+		{desc: "01_type", msg: "fix: handle empty input", want: &data.ConventionalCommit{Type: "fix", Description: "handle empty input"}, wantOK: true},
+		{desc: "02_scope and bang", msg: "Feat(parser)!: accept arrays", want: &data.ConventionalCommit{Type: "feat", Scope: "parser", Description: "accept arrays", Breaking: true}, wantOK: true},
+		{
+			desc:   "03_footer",
+			msg:    "refactor: drop the old API\n\nsome details\n\nBREAKING-CHANGE: Old() is removed\n",
+			want:   &data.ConventionalCommit{Type: "refactor", Description: "drop the old API", Breaking: true, BreakingFor: "Old() is removed"},
+			wantOK: true,
+		},
+		{desc: "04_not conventional", msg: "Update README", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, ok := data.ParseConventionalCommit(tt.msg)
+
+			if ok != tt.wantOK {
+				t.Fatalf("ok should be %t: (got: %+v)", tt.wantOK, got)
+			}
+			if ok {
+				CmpStructs(t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteChangelog(t *testing.T) {
+	when := time.Unix(1700000000, 0)
+	taro := data.NewSignature("taro", "taro@old.example.com", when)
+	hanako := data.NewSignature("Hanako", "hanako@example.com", when)
+	mailmap := data.Mailmap{{Name: "Taro", CommitEmail: "taro@old.example.com"}}
+	commits := []struct {
+		oid string
+		c   *data.Commit
+	}{
+		{oid: "1111111aaa", c: &data.Commit{Author: taro, Msg: "feat(cli): add --verbose"}},
+		{oid: "2222222bbb", c: &data.Commit{Author: hanako, Msg: "fix: handle empty input"}},
+		{oid: "3333333ccc", c: &data.Commit{Author: taro, Msg: "refactor!: drop Old\n\nBREAKING CHANGE: Old() is removed"}},
+		{oid: "4444444ddd", c: &data.Commit{Author: hanako, Msg: "Update README"}},
+	}
+	var entries []*data.ChangelogEntry
+	for _, c := range commits {
+		entries = append(entries, data.NewChangelogEntry(c.oid, c.c, mailmap))
+	}
+	want := `## v1..v2
+
+### Breaking Changes
+
+- Old() is removed (3333333)
+
+### Features
+
+- **cli:** add --verbose (1111111)
+
+### Bug Fixes
+
+- handle empty input (2222222)
+
+### Other Changes
+
+- refactor: drop Old (3333333)
+- Update README (4444444)
+
+### Contributors
+
+- Hanako (2)
+- Taro (2)
+`
+	var buf bytes.Buffer
+
+	data.WriteChangelog(&buf, "v1..v2", entries)
+
+	if got := buf.String(); got != want {
+		t.Errorf("changelog should be equal: (got: %q, want: %q)", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ANSI escape sequences of the colors used in outputs
+const (
+	ColorReset  = "\x1b[m"
+	ColorBold   = "\x1b[1m"
+	ColorRed    = "\x1b[31m"
+	ColorGreen  = "\x1b[32m"
+	ColorYellow = "\x1b[33m"
+	ColorCyan   = "\x1b[36m"
+)
+
+// the colors of the parts of a diff, the same as the defaults of git
+const (
+	ColorMeta   = ColorBold   //the header lines of a patch
+	ColorFrag   = ColorCyan   //the header line of a hunk
+	ColorOld    = ColorRed    //deleted lines and words
+	ColorNew    = ColorGreen  //inserted lines and words
+	ColorCommit = ColorYellow //the "commit {oid}" line in logs
+)
+
+// ColorMode tells when outputs are colored.
+type ColorMode string
+
+const (
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+	//colors only when the output is a terminal
+	ColorAuto ColorMode = "auto"
+)
+
+// the config keys of the color mode. The one for diffs takes precedence over the one for everything.
+const (
+	ConfigColorUI   = "color.ui"
+	ConfigColorDiff = "color.diff"
+)
+
+var ErrUnknownColorMode = errors.New("unknown color mode")
+
+// Parses the color mode, accepting "true" and "false" for "always" and "never" as well.
+func ParseColorMode(s string) (ColorMode, error) {
+	switch m := ColorMode(strings.ToLower(s)); m {
+	case ColorAlways, ColorNever, ColorAuto:
+		return m, nil
+	case "true":
+		return ColorAlways, nil
+	case "false":
+		return ColorNever, nil
+	}
+	return "", fmt.Errorf("ParseColorMode: %w: %s", ErrUnknownColorMode, s)
+}
+
+// Wraps each line of the text with the color and the reset, leaving the "\n" and empty lines uncolored.
+func Paint(color string, text string) string {
+	var b strings.Builder
+	for _, line := range splitLines(text) {
+		body, nl := strings.CutSuffix(line, "\n")
+		if body != "" {
+			b.WriteString(color + body + ColorReset)
+		}
+		if nl {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// the same as Paint if o.Color is set, otherwise the text as it is
+func (o *DiffOptions) Paint(color string, text string) string {
+	if !o.Color {
+		return text
+	}
+	return Paint(color, text)
+}
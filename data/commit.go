@@ -0,0 +1,188 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the config keys of the identity recorded in commits
+const (
+	ConfigUserName  = "user.name"
+	ConfigUserEmail = "user.email"
+)
+
+// config key to sign every commit (= "true") without -S
+const ConfigCommitSign = "commit.sign"
+
+var ErrMalformedSignature = errors.New("malformed signature")
+
+// Signature is who made a commit and when, written in a commit like this:
+// -----------------
+// author Taro Pgit <taro@example.com> 1700000000 +0900
+// -----------------
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+func NewSignature(name string, email string, when time.Time) Signature {
+	return Signature{Name: name, Email: email, When: when}
+}
+
+// returns "{name} <{email}> {unix time} {zone}"
+func (s Signature) String() string {
+	return fmt.Sprintf("%s <%s> %d %s", s.Name, s.Email, s.When.Unix(), s.When.Format("-0700"))
+}
+
+// returns "{name} <{email}>"
+func (s Signature) Ident() string {
+	return fmt.Sprintf("%s <%s>", s.Name, s.Email)
+}
+
+// Parses the value of an author (or committer) line of a commit.
+func ParseSignature(s string) (Signature, error) {
+	open := strings.LastIndex(s, "<")
+	closing := strings.LastIndex(s, ">")
+	if open < 0 || closing < open {
+		return Signature{}, fmt.Errorf("ParseSignature: %w: %q", ErrMalformedSignature, s)
+	}
+	sig := Signature{Name: strings.TrimSpace(s[:open]), Email: s[open+1 : closing]}
+	fields := strings.Fields(s[closing+1:])
+	if len(fields) != 2 {
+		return Signature{}, fmt.Errorf("ParseSignature: %w: %q", ErrMalformedSignature, s)
+	}
+	sec, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return Signature{}, fmt.Errorf("ParseSignature: %w: %q", ErrMalformedSignature, s)
+	}
+	zone, err := time.Parse("-0700", fields[1])
+	if err != nil {
+		return Signature{}, fmt.Errorf("ParseSignature: %w: %q", ErrMalformedSignature, s)
+	}
+	sig.When = time.Unix(sec, 0).In(zone.Location())
+	return sig, nil
+}
+
+// Encode returns the content of the commit object. The author and the committer are left out
+// if they are empty, as in the commits made before they were recorded. The signature block of a signed
+// commit is the "signature" header after them, whose lines but the first are indented by a space.
+func (c *Commit) Encode() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s\n", ObjTypeTree, c.TreeOid)
+	for _, p := range c.Parents() {
+		fmt.Fprintf(&buf, "parent %s\n", p)
+	}
+	if c.Author.Name != "" {
+		fmt.Fprintf(&buf, "author %s\n", c.Author)
+	}
+	if c.Committer.Name != "" {
+		fmt.Fprintf(&buf, "committer %s\n", c.Committer)
+	}
+	if c.Signature != "" {
+		sig := strings.TrimRight(c.Signature, "\n")
+		fmt.Fprintf(&buf, "signature %s\n", strings.ReplaceAll(sig, "\n", "\n "))
+	}
+	buf.WriteString("\n")
+	buf.WriteString(c.Msg)
+	return buf.Bytes()
+}
+
+// Payload returns the content of the commit without the signature, which is what the signature is made for.
+// A saved commit is verified against its content as it is stored instead (see SplitCommitSignature).
+func (c *Commit) Payload() []byte {
+	unsigned := *c
+	unsigned.Signature = ""
+	return unsigned.Encode()
+}
+
+// SplitCommitSignature separates the "signature" header from the content of a commit object, returning
+// the rest of the content byte for byte and the signature block (empty if not signed).
+func SplitCommitSignature(content []byte) (payload []byte, signature string) {
+	//the header keeps the newline of its last line, and the message keeps the blank line before it
+	header, msg := content, []byte(nil)
+	if i := bytes.Index(content, []byte("\n\n")); i >= 0 {
+		header, msg = content[:i+1], content[i+1:]
+	}
+	var sig strings.Builder
+	inSig := false
+	for _, line := range bytes.SplitAfter(header, []byte("\n")) {
+		//the following lines of a header value are indented by a space
+		if rest, ok := bytes.CutPrefix(line, []byte(" ")); ok && inSig {
+			sig.Write(rest)
+			continue
+		}
+		if value, ok := bytes.CutPrefix(line, []byte("signature ")); ok && sig.Len() == 0 {
+			sig.Write(value)
+			inSig = true
+			continue
+		}
+		inSig = false
+		payload = append(payload, line...)
+	}
+	payload = append(payload, msg...)
+	if sig.Len() == 0 {
+		return payload, ""
+	}
+	return payload, strings.TrimRight(sig.String(), "\n") + "\n"
+}
+
+// GetCommitPayload reads the object of the commit (= .pgit/objects/{oid}), and returns its signature
+// with the content it is made for.
+func GetCommitPayload(oid string) (payload []byte, signature string, err error) {
+	b, err := ReadAllFileContent(filepath.Join(PgitDirBase, ObjDirBase, oid))
+	if err != nil {
+		return nil, "", fmt.Errorf("GetCommitPayload: %w", err)
+	}
+	payload, signature = SplitCommitSignature(b)
+	return payload, signature, nil
+}
+
+// returns all the parents, the first parent first
+func (c *Commit) Parents() []string {
+	if c.Parent == "" {
+		return nil
+	}
+	return append([]string{c.Parent}, c.MergeParents...)
+}
+
+// returns the first line of the message
+func (c *Commit) Subject() string {
+	subject, _, _ := strings.Cut(c.Msg, "\n")
+	return subject
+}
+
+// returns the message after the subject and the blank lines following it
+func (c *Commit) Body() string {
+	_, body, _ := strings.Cut(c.Msg, "\n")
+	return strings.TrimLeft(body, "\n")
+}
+
+// CommitsBetween returns the commits reachable from to but not from from (= from..to), the newest first.
+// An empty from means all the commits reachable from to.
+func CommitsBetween(from string, to string) ([]string, error) {
+	excluded, err := ReachableCommits(from)
+	if err != nil {
+		return nil, fmt.Errorf("CommitsBetween: %w", err)
+	}
+	seen := make(map[string]bool, len(excluded))
+	for _, oid := range excluded {
+		seen[oid] = true
+	}
+	reachable, err := ReachableCommits(to)
+	if err != nil {
+		return nil, fmt.Errorf("CommitsBetween: %w", err)
+	}
+	commits := reachable[:0]
+	for _, oid := range reachable {
+		if !seen[oid] {
+			commits = append(commits, oid)
+		}
+	}
+	return commits, nil
+}
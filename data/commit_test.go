@@ -0,0 +1,160 @@
+package data_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		desc  string
+		value string
+		want  data.Signature
+		fails bool
+	}{
+		{
+			desc:  "01_name, email, time and zone",
+			value: "Taro Pgit <taro@example.com> 1700000000 +0900",
+			want:  data.NewSignature("Taro Pgit", "taro@example.com", time.Unix(1700000000, 0)),
+		},
+		{
+			desc:  "02_no email",
+			value: "taro <> 1700000000 -0130",
+			want:  data.NewSignature("taro", "", time.Unix(1700000000, 0)),
+		},
+		{desc: "03_no time", value: "Taro Pgit <taro@example.com>", fails: true},
+		{desc: "04_no email brackets", value: "Taro Pgit 1700000000 +0900", fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := data.ParseSignature(tt.value)
+
+			if tt.fails {
+				if !errors.Is(err, data.ErrMalformedSignature) {
+					t.Errorf("error should be ErrMalformedSignature: { got: %v }", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("error should be nil:\nerror:%s\n", err)
+			}
+			CmpStructs(t, got, tt.want)
+			if got.String() != tt.value {
+				t.Errorf("should be written back as it was: { got: %q, want: %q }", got.String(), tt.value)
+			}
+		})
+	}
+}
+
+func TestCommitEncode(t *testing.T) {
+	when := time.Unix(1700000000, 0).In(time.FixedZone("", 9*60*60))
+	want := &data.Commit{
+		TreeOid:   "testTreeOid",
+		Parent:    "testParent",
+		Author:    data.NewSignature("Taro Pgit", "taro@example.com", when),
+		Committer: data.NewSignature("Hanako Pgit", "hanako@example.com", when.Add(time.Hour)),
+		Msg:       "subject\n\nbody",
+	}
+	tmpDir := filepath.Join(data.PgitDirBase, data.ObjDirBase)
+	if err := os.MkdirAll(tmpDir, os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(tmpDir)) })
+	if err := data.WriteFile(filepath.Join(tmpDir, "testoid"), want.Encode()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := data.GetCommit("testoid")
+
+	if err != nil {
+		t.Errorf("should be nil:\n{ error: %s }", err)
+	}
+	CmpStructs(t, got, want)
+	if got.Subject() != "subject" || got.Body() != "body" {
+		t.Errorf("should be split into the subject and the body: { subject: %q, body: %q }", got.Subject(), got.Body())
+	}
+}
+
+func TestSignedCommitEncode(t *testing.T) {
+	pemKey, line := newTestKey(t, 1, "taro@example.com")
+	key, err := data.ParseSigningKey(pemKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	when := time.Unix(1700000000, 0).In(time.FixedZone("", 9*60*60))
+	want := &data.Commit{
+		TreeOid:   "testTreeOid",
+		Author:    data.NewSignature("Taro Pgit", "taro@example.com", when),
+		Committer: data.NewSignature("Taro Pgit", "taro@example.com", when),
+		Msg:       "signed\n\nbody",
+	}
+	if want.Signature, err = data.Sign(key, want.Payload()); err != nil {
+		t.Fatal(err)
+	}
+	tmpDir := filepath.Join(data.PgitDirBase, data.ObjDirBase)
+	if err := os.MkdirAll(tmpDir, os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(tmpDir)) })
+	if err := data.WriteFile(filepath.Join(tmpDir, "testoid"), want.Encode()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := data.GetCommit("testoid")
+
+	if err != nil {
+		t.Errorf("should be nil:\n{ error: %s }", err)
+	}
+	CmpStructs(t, got, want)
+	keyring, err := data.ParseKeyring([]byte(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, err := data.GetCommitPayload("testoid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyring.Verify(payload, signature); err != nil {
+		t.Errorf("signature should be good: (error: %s)", err)
+	}
+}
+
+func TestSplitCommitSignature(t *testing.T) {
+	tests := []struct {
+		desc        string
+		content     string
+		wantPayload string
+		wantSig     string
+	}{
+		{desc: "01_unsigned", content: "tree t\n\nmsg\n", wantPayload: "tree t\n\nmsg\n"},
+		{
+			desc:        "02_signed",
+			content:     "tree t\nsignature -----BEGIN-----\n key\n -----END-----\n\nmsg\n\n",
+			wantPayload: "tree t\n\nmsg\n\n",
+			wantSig:     "-----BEGIN-----\nkey\n-----END-----\n",
+		},
+		{
+			desc:        "03_other headers kept",
+			content:     "tree t\nsignature s\nx-injected evil\n\nmsg",
+			wantPayload: "tree t\nx-injected evil\n\nmsg",
+			wantSig:     "s\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			payload, sig := data.SplitCommitSignature([]byte(tt.content))
+
+			if string(payload) != tt.wantPayload {
+				t.Errorf("payload should be equal:\n{ got: %q, want: %q }", payload, tt.wantPayload)
+			}
+			if sig != tt.wantSig {
+				t.Errorf("signature should be equal:\n{ got: %q, want: %q }", sig, tt.wantSig)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ConfigBase = "config"
+
+	//config key for the size (in bytes) above which diffs are skipped
+	ConfigBigFileThreshold = "core.bigFileThreshold"
+)
+
+// ".pgit/config"
+var ConfigPath = filepath.Join(PgitDirBase, ConfigBase)
+
+// Config holds repository settings written in the config file like this:
+// -----------------
+// key  value...
+// core.bigFileThreshold 512m
+// user.name Taro Pgit
+// .
+// .
+// -----------------
+// { key: config key, value: the rest of the line }
+type Config map[string]string
+
+// Reads the config file in the path. If there is no such a file, an empty Config is returned.
+func ReadConfig(path string) (Config, error) {
+	conf := make(Config)
+	c, err := ReadAllFileContent(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return conf, nil
+		}
+		return nil, fmt.Errorf("ReadConfig: %w", err)
+	}
+	sc := bufio.NewScanner(bytes.NewReader(c))
+	sc.Split(bufio.ScanLines)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		conf[key] = strings.TrimSpace(value)
+	}
+	return conf, nil
+}
+
+// Writes all the settings into the path, sorted by key.
+func (c Config) Write(path string) error {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s %s\n", k, c[k])
+	}
+	if err := WriteFile(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("Config Write: %w", err)
+	}
+	return nil
+}
+
+// Returns the value for the key, or def if the key is not set.
+func (c Config) Get(key string, def string) string {
+	if v, ok := c[key]; ok {
+		return v
+	}
+	return def
+}
+
+// Returns the value for the key as a size in bytes, or def if the key is not set.
+// See ParseSize for the accepted format.
+func (c Config) Size(key string, def int64) (int64, error) {
+	v, ok := c[key]
+	if !ok {
+		return def, nil
+	}
+	size, err := ParseSize(v)
+	if err != nil {
+		return 0, fmt.Errorf("Config Size: %s: %w", key, err)
+	}
+	return size, nil
+}
+
+// Returns the value for the key as an integer, or def if the key is not set.
+func (c Config) Int(key string, def int) (int, error) {
+	v, ok := c[key]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("Config Int: %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// converts a human-readable size into bytes.
+// e.g. "512" => 512, "4k" => 4096, "10m" => 10485760, "1g" => 1073741824
+func ParseSize(s string) (int64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(s, "k"):
+		unit = 1 << 10
+	case strings.HasSuffix(s, "m"):
+		unit = 1 << 20
+	case strings.HasSuffix(s, "g"):
+		unit = 1 << 30
+	}
+	if unit != 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ParseSize: invalid size: %s", s)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("ParseSize: negative size: %d", n)
+	}
+	return n * unit, nil
+}
@@ -0,0 +1,89 @@
+package data_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestReadConfig(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		tests := []struct {
+			desc    string
+			content string
+			want    data.Config
+		}{
+			{
+				desc:    "01_all set",
+				content: "core.bigFileThreshold 10m\n# comment\n\nuser.name Taro Pgit\n",
+				want: data.Config{
+					"core.bigFileThreshold": "10m",
+					"user.name":             "Taro Pgit",
+				},
+			},
+			{
+				desc:    "02_no config file",
+				content: "",
+				want:    data.Config{},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				path := filepath.Join(tmpDir, "config")
+				if tt.content != "" {
+					if err := data.WriteFile(path, []byte(tt.content)); err != nil {
+						t.Fatal(err)
+					}
+				} else {
+					path = filepath.Join(tmpDir, "nothing")
+				}
+
+				got, err := data.ReadConfig(path)
+
+				if err != nil {
+					t.Errorf("should be nil: \n{ error: %s }", err)
+				}
+				CmpStructs(t, got, tt.want)
+			})
+		}
+	})
+}
+
+func TestConfigWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	conf := data.Config{"user.name": "Taro Pgit", "core.bigFileThreshold": "1g"}
+
+	err := conf.Write(path)
+
+	if err != nil {
+		t.Errorf("should be nil: \n{ error: %s }", err)
+	}
+	CmpFileContent(t, path, []byte("core.bigFileThreshold 1g\nuser.name Taro Pgit\n"))
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{desc: "01_bytes", in: "512", want: 512},
+		{desc: "02_kilo", in: "4k", want: 4 << 10},
+		{desc: "03_mega", in: "10M", want: 10 << 20},
+		{desc: "04_giga", in: "1g", want: 1 << 30},
+		{desc: "05_invalid", in: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := data.ParseSize(tt.in)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: (error: %v, wantErr: %v)", err, tt.wantErr)
+			}
+			CmpStructs(t, got, tt.want)
+		})
+	}
+}
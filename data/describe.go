@@ -0,0 +1,103 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultAbbrev is the number of the hex digits of an abbreviated oid.
+const DefaultAbbrev = 7
+
+// TagRef is a tag under refs/tags with the commit it points to.
+type TagRef struct {
+	Name      string //the name without "refs/tags/", like "v1.0"
+	Oid       string //the commit the tag points to
+	Annotated bool   //the tag is a tag object with a message, not only a ref
+}
+
+// DescribeOptions controls which tags name a commit and how.
+type DescribeOptions struct {
+	Tags   bool     //lightweight tags name commits as well as annotated ones
+	Match  []string //only the tags whose names match any of the glob patterns, if set
+	Abbrev int      //the hex digits of the abbreviated oid, where zero prints the tag alone
+	Always bool     //prints the abbreviated oid when no tag names the commit
+}
+
+func NewDescribeOptions() *DescribeOptions {
+	return &DescribeOptions{Abbrev: DefaultAbbrev}
+}
+
+var ErrNoTags = errors.New("no tags can describe the commit")
+
+// Describe names the commit after the nearest tag reachable from it, like "v1.2.0-14-gabc1234"
+// (= 14 commits on top of v1.2.0, at the commit abc1234). The commit of a tag is named by the tag alone.
+// The nearest tag has the fewest commits between it and the commit, and ties go to the newer commit of a tag.
+func Describe(oid string, tags []TagRef, opts *DescribeOptions) (string, error) {
+	byCommit := make(map[string][]TagRef)
+	for _, t := range tags {
+		if (t.Annotated || opts.Tags) && matchTagName(t.Name, opts.Match) {
+			byCommit[t.Oid] = append(byCommit[t.Oid], t)
+		}
+	}
+	h, err := LoadHistory(oid)
+	if err != nil {
+		return "", fmt.Errorf("Describe: %w", err)
+	}
+	var best *TagRef
+	bestDepth := -1
+	for _, c := range h.Sorted() {
+		candidates := byCommit[c]
+		if len(candidates) == 0 {
+			continue
+		}
+		excluded, err := LoadHistory(c)
+		if err != nil {
+			return "", fmt.Errorf("Describe: %w", err)
+		}
+		depth := len(h.Commits) - len(excluded.Commits)
+		if best != nil && depth >= bestDepth {
+			continue
+		}
+		//annotated tags come before lightweight ones on the same commit
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].Annotated != candidates[j].Annotated {
+				return candidates[i].Annotated
+			}
+			return candidates[i].Name < candidates[j].Name
+		})
+		best, bestDepth = &candidates[0], depth
+	}
+
+	switch {
+	case best == nil && opts.Always:
+		return abbrevOidLen(oid, opts.Abbrev), nil
+	case best == nil:
+		return "", fmt.Errorf("Describe: %w: %s", ErrNoTags, oid)
+	case bestDepth == 0 || opts.Abbrev == 0:
+		return best.Name, nil
+	}
+	return fmt.Sprintf("%s-%d-g%s", best.Name, bestDepth, abbrevOidLen(oid, opts.Abbrev)), nil
+}
+
+// reports whether the tag name matches any of the glob patterns. No pattern matches every name.
+func matchTagName(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the first n hex digits of the oid, or the whole oid if n is not positive or too long
+func abbrevOidLen(oid string, n int) string {
+	if n <= 0 || n >= len(oid) {
+		return oid
+	}
+	return oid[:n]
+}
@@ -0,0 +1,50 @@
+package data_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestDescribe(t *testing.T) {
+	writeTestHistory(t)
+	tags := []data.TagRef{
+		{Name: "v1", Oid: "base"},
+		{Name: "v2", Oid: "a1", Annotated: true},
+		{Name: "v3", Oid: "b1"},
+	}
+	tests := []struct {
+		desc string
+		oid  string
+		set  func(o *data.DescribeOptions)
+		want string
+	}{
+		{desc: "01_annotated only", oid: "m", set: func(o *data.DescribeOptions) {}, want: "v2-3-gm"},
+		{desc: "02_tie to the newer commit", oid: "m", set: func(o *data.DescribeOptions) { o.Tags = true }, want: "v3-3-gm"},
+		{desc: "03_match", oid: "m", set: func(o *data.DescribeOptions) { o.Tags, o.Match = true, []string{"v1*"} }, want: "v1-4-gm"},
+		{desc: "04_tagged commit", oid: "a1", set: func(o *data.DescribeOptions) {}, want: "v2"},
+		{desc: "05_abbrev 0", oid: "a2", set: func(o *data.DescribeOptions) { o.Abbrev = 0 }, want: "v2"},
+		{desc: "06_always", oid: "base", set: func(o *data.DescribeOptions) { o.Always = true }, want: "base"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			opts := data.NewDescribeOptions()
+			tt.set(opts)
+
+			got, err := data.Describe(tt.oid, tags, opts)
+
+			if err != nil {
+				t.Fatalf("error should be nil: (error: %s)", err)
+			}
+			if got != tt.want {
+				t.Errorf("name should be equal: (got: %s, want: %s)", got, tt.want)
+			}
+		})
+	}
+	t.Run("07_no tags", func(t *testing.T) {
+		if _, err := data.Describe("base", tags, data.NewDescribeOptions()); !errors.Is(err, data.ErrNoTags) {
+			t.Errorf("error should be ErrNoTags: (error: %v)", err)
+		}
+	})
+}
@@ -3,77 +3,281 @@ package data
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"path/filepath"
-	"strings"
-
-	"github.com/sergi/go-diff/diffmatchpatch"
+	"regexp"
 )
 
+// DefaultBigFileThreshold is the default size (512 MiB) above which diffs are skipped.
+const DefaultBigFileThreshold int64 = 512 << 20
+
+// DefaultContext is the default number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+// the name standing for a missing side of a diff
+const DevNull = "/dev/null"
+
+// Files larger than BigFileThreshold (in bytes) are not loaded into memory for diffs.
+// A zero or negative value means no limit.
+var BigFileThreshold = DefaultBigFileThreshold
+
+// DiffOptions controls how the differences of file contents are generated.
+type DiffOptions struct {
+	Context   int      //number of unchanged lines shown around each change (= -U N)
+	Pathspecs []string //limits the diff to the paths matched by any of them (see MatchPathspec)
+	Renames   bool     //pairs deleted files with added ones as renames
+	Copies    bool     //finds the sources of added files among deleted and modified ones
+	//similarity (in percent) at or above which files are regarded as a rename or a copy
+	RenameThreshold int
+
+	Algorithm         DiffAlgorithm
+	IgnoreAllSpace    bool //lines differing only in whitespace are the same (= -w)
+	IgnoreSpaceChange bool //lines differing only in the amount of whitespace are the same (= -b)
+	IgnoreBlankLines  bool //changes of blank lines alone make no hunk
+	//tells binary files by the "binary" and "diff" attributes before their contents
+	Attributes Attributes
+	//writes binary files as binary patches (= --binary) instead of a notice that they differ
+	Binary bool
+	//colors the patches with ANSI escape sequences
+	Color bool
+	//writes the hunks as word diffs in the mode, with the words matched by WordRegex (nil for the default)
+	WordDiff  WordDiffMode
+	WordRegex *regexp.Regexp
+	//when set, the files on the new side are read from the working tree under this directory
+	//instead of the object storage
+	WorkDir string
+}
+
+func NewDiffOptions() *DiffOptions {
+	return &DiffOptions{
+		Context:         DefaultContext,
+		Renames:         true,
+		RenameThreshold: DefaultRenameThreshold,
+		Algorithm:       DefaultDiffAlgorithm,
+	}
+}
+
 type Diff struct {
 	Filename string
-	Diff     string
+	Diff     string //the whole patch of the file, including its headers
 }
 
 // comparing two trees and generating the differences in a clear way.
+// Every added, deleted or modified file is reported recursively with its path from the root of the trees.
+// Added (or deleted) files are compared with empty content.
 func DiffTrees(from Tree, to Tree, srcDir string) ([]*Diff, error) {
-	difs := make([]*Diff, 0, len(from)+len(to))
-	for name, fromElem := range from {
-		toElem, ok := to[name]
-		if !ok {
-			continue
+	return NewDiffOptions().DiffTrees(from, to, srcDir)
+}
+
+// the same as DiffTrees, but following the options
+func (o *DiffOptions) DiffTrees(from Tree, to Tree, srcDir string) ([]*Diff, error) {
+	entries, err := o.DiffTreeEntries(from, to, srcDir)
+	if err != nil {
+		return nil, fmt.Errorf("DiffTrees: %w", err)
+	}
+	difs, err := o.Patches(entries, srcDir)
+	if err != nil {
+		return nil, fmt.Errorf("DiffTrees: %w", err)
+	}
+	return difs, nil
+}
+
+// generates the whole patch of each entry, including its headers
+func (o *DiffOptions) Patches(entries []*DiffEntry, srcDir string) ([]*Diff, error) {
+	difs := make([]*Diff, 0, len(entries))
+	for _, e := range entries {
+		diff, err := o.DiffEntryContent(e, srcDir)
+		if err != nil {
+			return nil, fmt.Errorf("Patches: %w", err)
 		}
-		diff, err := DiffFiles(filepath.Join(srcDir, fromElem.Oid), filepath.Join(srcDir, toElem.Oid))
+		difs = append(difs, &Diff{Filename: e.Path, Diff: o.Paint(ColorMeta, PatchHeader(e)) + diff})
+	}
+	return difs, nil
+}
+
+// the same as DiffTreeEntries, but detecting renames (and copies) and limiting the paths
+// following the options. A rename is kept if either of its paths is matched by the pathspecs.
+func (o *DiffOptions) DiffTreeEntries(from Tree, to Tree, srcDir string) ([]*DiffEntry, error) {
+	entries := DiffTreeEntries(from, to)
+	if o.Renames {
+		var err error
+		entries, err = o.detectRenames(entries, srcDir)
 		if err != nil {
-			return nil, fmt.Errorf("DiffTrees: %w", err)
+			return nil, fmt.Errorf("DiffTreeEntries: %w", err)
 		}
-		if diff == "" {
-			continue
+	}
+	filtered := entries[:0]
+	for _, e := range entries {
+		if MatchPathspecs(e.Path, o.Pathspecs) || (e.OldPath != "" && MatchPathspecs(e.OldPath, o.Pathspecs)) {
+			filtered = append(filtered, e)
 		}
-		difs = append(difs, &Diff{Filename: name, Diff: diff})
 	}
-	return difs, nil
+	return filtered, nil
+}
+
+// generates the differences of the file contents of a tree entry as "--- a/path", "+++ b/path"
+// and hunks. A side that is not a file (i.e. missing or a directory) is regarded as empty content.
+func DiffEntryContent(e *DiffEntry, srcDir string) (diff string, err error) {
+	return NewDiffOptions().DiffEntryContent(e, srcDir)
+}
+
+// the same as DiffEntryContent, but following the options
+func (o *DiffOptions) DiffEntryContent(e *DiffEntry, srcDir string) (diff string, err error) {
+	fromPath, toPath := o.entryFilePaths(e, srcDir)
+	fromName, toName := DevNull, DevNull
+	if e.OldIsBlob() {
+		fromName = "a/" + e.SourcePath()
+	}
+	if e.NewIsBlob() {
+		toName = "b/" + e.Path
+	}
+	diff, err = o.diffFilePaths(fromPath, toPath, fromName, toName, e.Path)
+	if err != nil {
+		return "", fmt.Errorf("DiffEntryContent: %w", err)
+	}
+	return diff, nil
 }
 
-// comparing the content of files between fromPath and toPath, and generating an output of differences
+// returns the paths of the files on both sides of the entry, in srcDir (or in o.WorkDir for the new side).
+// The path of a side that is not a file is empty. When the new side is in the working tree, a pointer
+// on the old side is replaced with its real content, so that both sides are compared by their contents.
+func (o *DiffOptions) entryFilePaths(e *DiffEntry, srcDir string) (fromPath string, toPath string) {
+	if e.OldIsBlob() {
+		fromPath = filepath.Join(srcDir, e.OldOid)
+		if o.WorkDir != "" {
+			fromPath = smudgedPath(srcDir, e.OldOid)
+		}
+	}
+	if e.NewIsBlob() {
+		toPath = filepath.Join(srcDir, e.NewOid)
+		if o.WorkDir != "" {
+			toPath = filepath.Join(o.WorkDir, filepath.FromSlash(e.Path))
+		}
+	}
+	return fromPath, toPath
+}
+
+// comparing the content of files between fromPath and toPath, and generating a unified diff of them
+// with the paths in the "---" and "+++" lines. An empty string is returned for the same contents.
+// If either of the files is larger than BigFileThreshold, the diff is skipped and a notice is returned instead.
+// Binary files are not compared line by line, but reported with a notice that they differ.
 func DiffFiles(fromPath string, toPath string) (diff string, err error) {
-	from, err := ReadAllFileContent(fromPath)
+	return NewDiffOptions().DiffFiles(fromPath, toPath)
+}
+
+// the same as DiffFiles, but following the options
+func (o *DiffOptions) DiffFiles(fromPath string, toPath string) (diff string, err error) {
+	diff, err = o.diffFilePaths(fromPath, toPath, fromPath, toPath, "")
 	if err != nil {
 		return "", fmt.Errorf("DiffFiles: %w", err)
 	}
-	to, err := ReadAllFileContent(toPath)
+	return diff, nil
+}
+
+// the same as DiffFiles except that an empty path stands for empty content,
+// and that the names are printed in the "---" and "+++" lines.
+// relPath is the path the attributes are looked up with, or empty to tell binary files by their contents alone.
+func (o *DiffOptions) diffFilePaths(fromPath string, toPath string, fromName string, toName string, relPath string) (diff string, err error) {
+	big, err := isBigFile(fromPath, toPath)
 	if err != nil {
-		return "", fmt.Errorf("DiffFiles: %w", err)
+		return "", err
+	}
+	if big {
+		return fmt.Sprintf("Large files differ (diff skipped: over %d bytes)\n", BigFileThreshold), nil
 	}
-	dmp := diffmatchpatch.New()
-	fromChars, toChars, list := dmp.DiffLinesToChars(string(from), string(to))
-	diffs := dmp.DiffMain(fromChars, toChars, false)
-	diffs = dmp.DiffCharsToLines(diffs, list)
-
-	return diffReport(diffs), nil
-}
-
-// converting multiple diffs into a human-readable line-by-line report in the following way:
-// +this is a example text.
-// -this is a example test.
-// Note:
-// +(plus) represents inserted strings while -(minus) indicates deleted strings.
-func diffReport(diffs []diffmatchpatch.Diff) string {
-	var buff bytes.Buffer
-	for _, diff := range diffs {
-		text := diff.Text
-
-		switch diff.Type {
-		case diffmatchpatch.DiffInsert:
-			lines := strings.Split(text, "\n")
-			for _, line := range lines {
-				fmt.Fprintf(&buff, "+%s\n", line)
-			}
-		case diffmatchpatch.DiffDelete:
-			lines := strings.Split(text, "\n")
-			for _, line := range lines {
-				fmt.Fprintf(&buff, "-%s\n", line)
-			}
+	from, err := readFileOrEmpty(fromPath)
+	if err != nil {
+		return "", err
+	}
+	to, err := readFileOrEmpty(toPath)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if o.isBinary(relPath, from, to) {
+		if bytes.Equal(from, to) {
+			return "", nil
+		}
+		if !o.Binary {
+			writeBinaryNotice(&buf, fromName, toName)
+			return buf.String(), nil
+		}
+		if err := WriteBinaryPatch(&buf, from, to); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	hunks := o.DiffHunks(from, to)
+	if len(hunks) == 0 {
+		return "", nil
+	}
+	buf.WriteString(o.Paint(ColorMeta, fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName)))
+	o.WriteHunks(&buf, hunks)
+	return buf.String(), nil
+}
+
+// returns the header lines of the patch of a tree entry, written before its content diff:
+// -----------------
+// diff --git a/path b/path
+// new file mode 100644
+// index 0000000..abcdef0
+// -----------------
+// A rename (or a copy) is written with its similarity and paths instead of the mode line.
+func PatchHeader(e *DiffEntry) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "diff --git a/%s b/%s\n", e.SourcePath(), e.Path)
+	oldOid, newOid := zeroAbbrev, zeroAbbrev
+	switch {
+	case e.Status == StatusRenamed || e.Status == StatusCopied:
+		verb := "rename"
+		if e.Status == StatusCopied {
+			verb = "copy"
+		}
+		fmt.Fprintf(&buf, "similarity index %d%%\n%s from %s\n%s to %s\n", e.Score, verb, e.OldPath, verb, e.Path)
+		if e.OldOid == e.NewOid {
+			return buf.String()
+		}
+		oldOid, newOid = abbrevOid(e.OldOid), abbrevOid(e.NewOid)
+	case !e.OldIsBlob():
+		fmt.Fprintf(&buf, "new file mode %s\n", ModeBlob)
+		newOid = abbrevOid(e.NewOid)
+	case !e.NewIsBlob():
+		fmt.Fprintf(&buf, "deleted file mode %s\n", ModeBlob)
+		oldOid = abbrevOid(e.OldOid)
+	default:
+		oldOid, newOid = abbrevOid(e.OldOid), abbrevOid(e.NewOid)
+	}
+	fmt.Fprintf(&buf, "index %s..%s", oldOid, newOid)
+	if e.OldIsBlob() && e.NewIsBlob() {
+		fmt.Fprintf(&buf, " %s", ModeBlob)
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+func readFileOrEmpty(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return ReadAllFileContent(path)
+}
+
+// reports whether any of the files in the paths is larger than BigFileThreshold
+func isBigFile(paths ...string) (bool, error) {
+	if BigFileThreshold <= 0 {
+		return false, nil
+	}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		fi, err := os.Stat(p)
+		if err != nil {
+			return false, err
+		}
+		if fi.Size() > BigFileThreshold {
+			return true, nil
 		}
 	}
-	return buff.String()
+	return false, nil
 }
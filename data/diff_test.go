@@ -19,7 +19,10 @@ func TestDiffFiles(t *testing.T) {
 				desc:     "01_all set",
 				fromPath: "./test/diff/from",
 				toPath:   "./test/diff/to",
-				want:     "-The only difference is from.\n+The only difference is to.\n",
+				want: "--- ./test/diff/from\n+++ ./test/diff/to\n" +
+					"@@ -1,2 +1,2 @@\n This is a test message.\n" +
+					"-The only difference is from.\n\\ No newline at end of file\n" +
+					"+The only difference is to.\n\\ No newline at end of file\n",
 			},
 		}
 		for _, tt := range tests {
@@ -35,6 +38,19 @@ func TestDiffFiles(t *testing.T) {
 	})
 }
 
+func TestDiffFilesBigFile(t *testing.T) {
+	old := data.BigFileThreshold
+	data.BigFileThreshold = 8
+	t.Cleanup(func() { data.BigFileThreshold = old })
+
+	got, err := data.DiffFiles("./test/diff/from", "./test/diff/to")
+
+	if err != nil {
+		t.Errorf("error should be nil:\nerror:%s\n", err)
+	}
+	CmpStructs(t, got, "Large files differ (diff skipped: over 8 bytes)\n")
+}
+
 func TestDiffTrees(t *testing.T) {
 	srcDir := filepath.Join("./test", "difftrees")
 	t.Run("success", func(t *testing.T) {
@@ -67,7 +83,33 @@ func TestDiffTrees(t *testing.T) {
 				want: []*data.Diff{
 					{
 						Filename: "file_01",
-						Diff:     "-This is a test message.\n+This is a text message.\n",
+						Diff: "diff --git a/file_01 b/file_01\nindex testoid..testoid 100644\n" +
+							"--- a/file_01\n+++ b/file_01\n@@ -1 +1 @@\n" +
+							"-This is a test message.\n\\ No newline at end of file\n" +
+							"+This is a text message.\n\\ No newline at end of file\n",
+					},
+				},
+			},
+			{
+				desc:   "02_added file in a new directory",
+				srcDir: srcDir,
+				from:   data.Tree{},
+				to: data.Tree{
+					"dir": &data.TreeElem{
+						ObjType: data.ObjTypeTree,
+						Oid:     "treeoid",
+						Name:    "dir",
+						Child: data.Tree{
+							"file_01": &data.TreeElem{ObjType: data.ObjTypeBlob, Oid: "testoid_01", Name: "file_01"},
+						},
+					},
+				},
+				want: []*data.Diff{
+					{
+						Filename: "dir/file_01",
+						Diff: "diff --git a/dir/file_01 b/dir/file_01\nnew file mode 100644\nindex 0000000..testoid\n" +
+							"--- /dev/null\n+++ b/dir/file_01\n@@ -0,0 +1 @@\n" +
+							"+This is a test message.\n\\ No newline at end of file\n",
 					},
 				},
 			},
@@ -84,3 +126,65 @@ func TestDiffTrees(t *testing.T) {
 		}
 	})
 }
+
+func TestDiffTreeEntries(t *testing.T) {
+	blob := func(name string, oid string) *data.TreeElem {
+		return &data.TreeElem{ObjType: data.ObjTypeBlob, Oid: oid, Name: name}
+	}
+	tree := func(name string, oid string, child data.Tree) *data.TreeElem {
+		return &data.TreeElem{ObjType: data.ObjTypeTree, Oid: oid, Name: name, Child: child}
+	}
+	tests := []struct {
+		desc string
+		from data.Tree
+		to   data.Tree
+		want []*data.DiffEntry
+	}{
+		{
+			desc: "01_added, deleted and modified",
+			from: data.Tree{"a": blob("a", "oid_a"), "b": blob("b", "oid_b1"), "c": blob("c", "oid_c")},
+			to:   data.Tree{"b": blob("b", "oid_b2"), "c": blob("c", "oid_c"), "d": blob("d", "oid_d")},
+			want: []*data.DiffEntry{
+				{Path: "a", OldOid: "oid_a", OldMode: data.ModeBlob, Status: data.StatusDeleted},
+				{Path: "b", OldOid: "oid_b1", NewOid: "oid_b2", OldMode: data.ModeBlob, NewMode: data.ModeBlob, Status: data.StatusModified},
+				{Path: "d", NewOid: "oid_d", NewMode: data.ModeBlob, Status: data.StatusAdded},
+			},
+		},
+		{
+			desc: "02_nested changes",
+			from: data.Tree{"dir": tree("dir", "tree_1", data.Tree{
+				"sub": tree("sub", "tree_2", data.Tree{"x": blob("x", "oid_x1")}),
+				"old": blob("old", "oid_old"),
+			})},
+			to: data.Tree{"dir": tree("dir", "tree_3", data.Tree{
+				"sub": tree("sub", "tree_4", data.Tree{"x": blob("x", "oid_x2")}),
+			})},
+			want: []*data.DiffEntry{
+				{Path: "dir/old", OldOid: "oid_old", OldMode: data.ModeBlob, Status: data.StatusDeleted},
+				{Path: "dir/sub/x", OldOid: "oid_x1", NewOid: "oid_x2", OldMode: data.ModeBlob, NewMode: data.ModeBlob, Status: data.StatusModified},
+			},
+		},
+		{
+			desc: "03_unchanged subtree is skipped",
+			from: data.Tree{"dir": tree("dir", "tree_1", data.Tree{"x": blob("x", "oid_x1")})},
+			to:   data.Tree{"dir": tree("dir", "tree_1", data.Tree{"x": blob("x", "oid_x2")})},
+			want: nil,
+		},
+		{
+			desc: "04_file becomes a directory",
+			from: data.Tree{"p": blob("p", "oid_p")},
+			to:   data.Tree{"p": tree("p", "tree_p", data.Tree{"q": blob("q", "oid_q")})},
+			want: []*data.DiffEntry{
+				{Path: "p", OldOid: "oid_p", NewOid: "tree_p", OldMode: data.ModeBlob, NewMode: data.ModeTree, Status: data.StatusTypeChanged},
+				{Path: "p/q", NewOid: "oid_q", NewMode: data.ModeBlob, Status: data.StatusAdded},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := data.DiffTreeEntries(tt.from, tt.to)
+
+			CmpStructs(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DiffAlgorithm is the algorithm to find the common lines of two contents.
+type DiffAlgorithm string
+
+const (
+	//the shortest edit script by the O(ND) algorithm of Eugene W. Myers, in linear space
+	AlgorithmMyers DiffAlgorithm = "myers"
+	//lines unique in both contents anchor the diff, which keeps reordered blocks readable
+	AlgorithmPatience DiffAlgorithm = "patience"
+	//the rarest common lines anchor the diff, extending patience to lines that are not unique
+	AlgorithmHistogram DiffAlgorithm = "histogram"
+
+	DefaultDiffAlgorithm = AlgorithmMyers
+)
+
+const ConfigDiffAlgorithm = "diff.algorithm"
+
+// lines occurring more often than this are never used as anchors by the histogram algorithm
+const histogramMaxChain = 64
+
+var ErrUnknownAlgorithm = errors.New("unknown diff algorithm")
+
+func ParseDiffAlgorithm(s string) (DiffAlgorithm, error) {
+	switch a := DiffAlgorithm(strings.ToLower(s)); a {
+	case AlgorithmMyers, AlgorithmPatience, AlgorithmHistogram:
+		return a, nil
+	case "default", "":
+		return DefaultDiffAlgorithm, nil
+	}
+	return "", fmt.Errorf("ParseDiffAlgorithm: %w: %s", ErrUnknownAlgorithm, s)
+}
+
+// compares the contents line by line with the algorithm and the whitespace options.
+// Lines regarded as the same keep the text of the old side.
+func (o *DiffOptions) diffLines(from []byte, to []byte) []diffLine {
+	aLines, bLines := splitLines(string(from)), splitLines(string(to))
+	a, b := o.internLines(aLines, bLines)
+
+	lines := make([]diffLine, 0, len(aLines)+len(bLines))
+	i, j := 0, 0
+	for _, m := range o.matchSequences(a, b) {
+		for ; i < m.i; i++ {
+			lines = append(lines, diffLine{Op: LineDelete, Text: aLines[i]})
+		}
+		for ; j < m.j; j++ {
+			lines = append(lines, diffLine{Op: LineInsert, Text: bLines[j]})
+		}
+		if m.i < len(a) {
+			lines = append(lines, diffLine{Op: LineContext, Text: aLines[m.i]})
+			i, j = m.i+1, m.j+1
+		}
+	}
+	return lines
+}
+
+// finds the common elements of a and b with the algorithm, in increasing order.
+// The last match is always {len(a), len(b)}, which stands for the ends.
+func (o *DiffOptions) matchSequences(a []int, b []int) []lineMatch {
+	d := &lineDiffer{a: a, b: b}
+	switch o.Algorithm {
+	case AlgorithmPatience:
+		d.patience(0, len(a), 0, len(b))
+	case AlgorithmHistogram:
+		d.histogram(0, len(a), 0, len(b))
+	default:
+		d.myers(0, len(a), 0, len(b))
+	}
+	return append(d.matches, lineMatch{len(a), len(b)})
+}
+
+// converts the lines into ids, so that lines regarded as the same under the whitespace options
+// share an id
+func (o *DiffOptions) internLines(aLines []string, bLines []string) (a []int, b []int) {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		seq := make([]int, len(lines))
+		for i, line := range lines {
+			key := o.lineKey(line)
+			id, ok := ids[key]
+			if !ok {
+				id = len(ids)
+				ids[key] = id
+			}
+			seq[i] = id
+		}
+		return seq
+	}
+	return intern(aLines), intern(bLines)
+}
+
+// normalizes the line under the whitespace options
+func (o *DiffOptions) lineKey(line string) string {
+	switch {
+	case o.IgnoreAllSpace:
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, line)
+	case o.IgnoreSpaceChange:
+		//a run of whitespace counts as a single space, and trailing whitespace is ignored
+		return strings.Join(strings.Fields(line), " ")
+	}
+	return line
+}
+
+// reports whether the change of the line may be left out of hunks under --ignore-blank-lines
+func (o *DiffOptions) isIgnorable(l diffLine) bool {
+	return o.IgnoreBlankLines && l.Op != LineContext && strings.TrimSpace(l.Text) == ""
+}
+
+// lineMatch is a pair of lines regarded as the same, a[i] == b[j]
+type lineMatch struct {
+	i int
+	j int
+}
+
+// lineDiffer finds the common lines of a and b, collecting them in increasing order
+type lineDiffer struct {
+	a       []int
+	b       []int
+	matches []lineMatch
+}
+
+func (d *lineDiffer) match(i int, j int, n int) {
+	for k := 0; k < n; k++ {
+		d.matches = append(d.matches, lineMatch{i + k, j + k})
+	}
+}
+
+// strips the lines common at both ends of the ranges, matching the prefix right away.
+// Returns the rest of the ranges and the length of the common suffix to be matched after them.
+func (d *lineDiffer) trim(aLo, aHi, bLo, bHi int) (int, int, int, int, int) {
+	prefix := 0
+	for aLo+prefix < aHi && bLo+prefix < bHi && d.a[aLo+prefix] == d.b[bLo+prefix] {
+		prefix++
+	}
+	d.match(aLo, bLo, prefix)
+	aLo, bLo = aLo+prefix, bLo+prefix
+	suffix := 0
+	for aHi-suffix > aLo && bHi-suffix > bLo && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	return aLo, aHi - suffix, bLo, bHi - suffix, suffix
+}
+
+// finds the shortest edit script by bisecting the ranges at the middle snake recursively
+func (d *lineDiffer) myers(aLo, aHi, bLo, bHi int) {
+	aLo, aHi, bLo, bHi, suffix := d.trim(aLo, aHi, bLo, bHi)
+	if aLo < aHi && bLo < bHi {
+		if x, y, ok := d.middleSnake(aLo, aHi, bLo, bHi); ok {
+			d.myers(aLo, x, bLo, y)
+			d.myers(x, aHi, y, bHi)
+		}
+	}
+	d.match(aHi, bHi, suffix)
+}
+
+// walks the edit graph forward from the top-left and backward from the bottom-right at the same time,
+// and returns the point where the paths overlap. ok is false if they never do.
+func (d *lineDiffer) middleSnake(aLo, aHi, bLo, bHi int) (x int, y int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2*maxD + 2
+	v1 := make([]int, size)
+	v2 := make([]int, size)
+	for i := range v1 {
+		v1[i], v2[i] = -1, -1
+	}
+	v1[offset+1], v2[offset+1] = 0, 0
+	delta := n - m
+	//with an odd delta, the forward path meets the reverse path, and vice versa
+	front := delta%2 != 0
+	k1start, k1end, k2start, k2end := 0, 0, 0, 0
+	for dd := 0; dd < maxD; dd++ {
+		for k1 := -dd + k1start; k1 <= dd-k1end; k1 += 2 {
+			k1off := offset + k1
+			var x1 int
+			if k1 == -dd || (k1 != dd && v1[k1off-1] < v1[k1off+1]) {
+				x1 = v1[k1off+1]
+			} else {
+				x1 = v1[k1off-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && d.a[aLo+x1] == d.b[bLo+y1] {
+				x1++
+				y1++
+			}
+			v1[k1off] = x1
+			switch {
+			case x1 > n:
+				k1end += 2
+			case y1 > m:
+				k1start += 2
+			case front:
+				k2off := offset + delta - k1
+				if k2off >= 0 && k2off < size && v2[k2off] != -1 && x1 >= n-v2[k2off] {
+					return aLo + x1, bLo + y1, true
+				}
+			}
+		}
+		for k2 := -dd + k2start; k2 <= dd-k2end; k2 += 2 {
+			k2off := offset + k2
+			var x2 int
+			if k2 == -dd || (k2 != dd && v2[k2off-1] < v2[k2off+1]) {
+				x2 = v2[k2off+1]
+			} else {
+				x2 = v2[k2off-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && d.a[aHi-x2-1] == d.b[bHi-y2-1] {
+				x2++
+				y2++
+			}
+			v2[k2off] = x2
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				k1off := offset + delta - k2
+				if k1off >= 0 && k1off < size && v1[k1off] != -1 {
+					x1 := v1[k1off]
+					y1 := offset + x1 - k1off
+					if x1 >= n-x2 {
+						return aLo + x1, bLo + y1, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// anchors the diff at the lines occurring exactly once in both ranges, taking the longest
+// increasing sequence of them, and recurses between the anchors. Ranges without such lines
+// are left to myers.
+func (d *lineDiffer) patience(aLo, aHi, bLo, bHi int) {
+	aLo, aHi, bLo, bHi, suffix := d.trim(aLo, aHi, bLo, bHi)
+	defer d.match(aHi, bHi, suffix)
+	if aLo == aHi || bLo == bHi {
+		return
+	}
+	type occurrence struct {
+		aCount, bCount int
+		aPos, bPos     int
+	}
+	occ := make(map[int]*occurrence)
+	for i := aLo; i < aHi; i++ {
+		o, ok := occ[d.a[i]]
+		if !ok {
+			o = &occurrence{}
+			occ[d.a[i]] = o
+		}
+		o.aCount++
+		o.aPos = i
+	}
+	for j := bLo; j < bHi; j++ {
+		if o, ok := occ[d.b[j]]; ok {
+			o.bCount++
+			o.bPos = j
+		}
+	}
+	//unique lines in the order of a, with their positions in b
+	var uniques []lineMatch
+	for i := aLo; i < aHi; i++ {
+		if o := occ[d.a[i]]; o.aCount == 1 && o.bCount == 1 {
+			uniques = append(uniques, lineMatch{i, o.bPos})
+		}
+	}
+	if len(uniques) == 0 {
+		d.myers(aLo, aHi, bLo, bHi)
+		return
+	}
+	i, j := aLo, bLo
+	for _, anchor := range longestIncreasing(uniques) {
+		d.patience(i, anchor.i, j, anchor.j)
+		d.match(anchor.i, anchor.j, 1)
+		i, j = anchor.i+1, anchor.j+1
+	}
+	d.patience(i, aHi, j, bHi)
+}
+
+// returns the longest subsequence of the matches increasing in j by patience sorting
+func longestIncreasing(matches []lineMatch) []lineMatch {
+	var tops []int //indices of the matches on top of each pile
+	prev := make([]int, len(matches))
+	for k, m := range matches {
+		lo, hi := 0, len(tops)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if matches[tops[mid]].j < m.j {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		prev[k] = -1
+		if lo > 0 {
+			prev[k] = tops[lo-1]
+		}
+		if lo == len(tops) {
+			tops = append(tops, k)
+		} else {
+			tops[lo] = k
+		}
+	}
+	seq := make([]lineMatch, len(tops))
+	for k, idx := len(tops)-1, tops[len(tops)-1]; k >= 0; k, idx = k-1, prev[idx] {
+		seq[k] = matches[idx]
+	}
+	return seq
+}
+
+// anchors the diff at the longest common region around the rarest line in a that b also has,
+// and recurses on both sides of it. Ranges whose common lines are all too frequent are left to myers.
+func (d *lineDiffer) histogram(aLo, aHi, bLo, bHi int) {
+	aLo, aHi, bLo, bHi, suffix := d.trim(aLo, aHi, bLo, bHi)
+	defer d.match(aHi, bHi, suffix)
+	if aLo == aHi || bLo == bHi {
+		return
+	}
+	positions := make(map[int][]int) //{ key: line id, value: positions in a }
+	for i := aLo; i < aHi; i++ {
+		positions[d.a[i]] = append(positions[d.a[i]], i)
+	}
+	bestCount := histogramMaxChain + 1
+	bestLen, bestA, bestB := 0, 0, 0
+	for j := bLo; j < bHi; {
+		next := j + 1
+		ps := positions[d.b[j]]
+		if len(ps) == 0 || len(ps) > bestCount {
+			j = next
+			continue
+		}
+		for _, i := range ps {
+			sa, sb, ea, eb := i, j, i+1, j+1
+			for sa > aLo && sb > bLo && d.a[sa-1] == d.b[sb-1] {
+				sa--
+				sb--
+			}
+			for ea < aHi && eb < bHi && d.a[ea] == d.b[eb] {
+				ea++
+				eb++
+			}
+			if len(ps) < bestCount || ea-sa > bestLen {
+				bestCount, bestLen, bestA, bestB = len(ps), ea-sa, sa, sb
+			}
+			next = max(next, eb)
+		}
+		j = next
+	}
+	if bestLen == 0 {
+		d.myers(aLo, aHi, bLo, bHi)
+		return
+	}
+	d.histogram(aLo, bestA, bLo, bestB)
+	d.match(bestA, bestB, bestLen)
+	d.histogram(bestA+bestLen, aHi, bestB+bestLen, bHi)
+}
@@ -0,0 +1,234 @@
+package data_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+var algorithms = []data.DiffAlgorithm{data.AlgorithmMyers, data.AlgorithmPatience, data.AlgorithmHistogram}
+
+// generates a content of n lines drawn from a small vocabulary, so that lines repeat
+func randomContent(r *rand.Rand, n int, vocab int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "line %d\n", r.Intn(vocab))
+	}
+	return b.String()
+}
+
+// rebuilds both sides from the hunks, filling the gaps with the lines of the old side
+func applyHunks(t *testing.T, from string, hunks []*data.Hunk) string {
+	t.Helper()
+	old := strings.SplitAfter(from, "\n")
+	var out strings.Builder
+	next := 1
+	for _, h := range hunks {
+		start := h.OldStart
+		if h.OldLines == 0 {
+			start++
+		}
+		for ; next < start; next++ {
+			out.WriteString(old[next-1])
+		}
+		for _, line := range h.Lines {
+			switch line[0] {
+			case data.LineContext:
+				if line[1:] != old[next-1] {
+					t.Fatalf("context should match the old side: { got: %q, want: %q }", line[1:], old[next-1])
+				}
+				out.WriteString(line[1:])
+				next++
+			case data.LineDelete:
+				next++
+			case data.LineInsert:
+				out.WriteString(line[1:])
+			}
+		}
+	}
+	for ; next <= len(old); next++ {
+		out.WriteString(old[next-1])
+	}
+	return out.String()
+}
+
+// counts the changed lines of the hunks
+func countChanges(hunks []*data.Hunk) int {
+	n := 0
+	for _, h := range hunks {
+		for _, line := range h.Lines {
+			if line[0] != data.LineContext {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// the number of changes of the shortest edit script, by dynamic programming of the LCS
+func shortestEdit(a []string, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func TestDiffAlgorithms(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, algo := range algorithms {
+		t.Run(string(algo), func(t *testing.T) {
+			for n := 0; n < 200; n++ {
+				from := randomContent(r, r.Intn(30), 8)
+				to := randomContent(r, r.Intn(30), 8)
+				opts := data.NewDiffOptions()
+				opts.Algorithm = algo
+
+				hunks := opts.DiffHunks([]byte(from), []byte(to))
+
+				if got := applyHunks(t, from, hunks); got != to {
+					t.Fatalf("hunks should turn from into to:\n{ from: %q, to: %q, got: %q }", from, to, got)
+				}
+				if algo != data.AlgorithmMyers {
+					continue
+				}
+				want := shortestEdit(strings.SplitAfter(from, "\n"), strings.SplitAfter(to, "\n"))
+				if got := countChanges(hunks); got != want {
+					t.Fatalf("myers should find the shortest edit:\n{ from: %q, to: %q, got: %d, want: %d }", from, to, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffAlgorithmsUniqueAnchor(t *testing.T) {
+	//the closing lines repeat, while the moved header is the only unique line
+	from := "}\n}\n}\nfunc main() {\n"
+	to := "func main() {\n}\n}\n}\n"
+	tests := []struct {
+		algo data.DiffAlgorithm
+		want string
+	}{
+		{
+			algo: data.AlgorithmMyers,
+			want: "@@ -1,4 +1,4 @@\n+func main() {\n }\n }\n }\n-func main() {\n",
+		},
+		{
+			algo: data.AlgorithmPatience,
+			want: "@@ -1,4 +1,4 @@\n-}\n-}\n-}\n func main() {\n+}\n+}\n+}\n",
+		},
+		{
+			algo: data.AlgorithmHistogram,
+			want: "@@ -1,4 +1,4 @@\n-}\n-}\n-}\n func main() {\n+}\n+}\n+}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.algo), func(t *testing.T) {
+			opts := data.NewDiffOptions()
+			opts.Algorithm = tt.algo
+
+			var buf bytes.Buffer
+			data.WriteHunks(&buf, opts.DiffHunks([]byte(from), []byte(to)))
+
+			CmpStructs(t, buf.String(), tt.want)
+		})
+	}
+}
+
+func TestDiffWhitespaceOptions(t *testing.T) {
+	tests := []struct {
+		desc string
+		set  func(o *data.DiffOptions)
+		from string
+		to   string
+		want string
+	}{
+		{
+			desc: "01_ignore all space",
+			set:  func(o *data.DiffOptions) { o.IgnoreAllSpace = true },
+			from: "a b\nc\n",
+			to:   "ab\nd\n",
+			want: "@@ -1,2 +1,2 @@\n a b\n-c\n+d\n",
+		},
+		{
+			desc: "02_ignore space change",
+			set:  func(o *data.DiffOptions) { o.IgnoreSpaceChange = true },
+			from: "a  b \nc\n",
+			to:   "a b\nab\n",
+			want: "@@ -1,2 +1,2 @@\n a  b \n-c\n+ab\n",
+		},
+		{
+			desc: "03_ignore blank lines",
+			set:  func(o *data.DiffOptions) { o.IgnoreBlankLines = true; o.Context = 0 },
+			from: "a\nb\nc\nd\ne\n",
+			to:   "a\n\nb\nc\nd\nE\n",
+			want: "@@ -5 +6 @@\n-e\n+E\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			opts := data.NewDiffOptions()
+			tt.set(opts)
+
+			var buf bytes.Buffer
+			data.WriteHunks(&buf, opts.DiffHunks([]byte(tt.from), []byte(tt.to)))
+
+			CmpStructs(t, buf.String(), tt.want)
+		})
+	}
+}
+
+func TestParseDiffAlgorithm(t *testing.T) {
+	got, err := data.ParseDiffAlgorithm("Patience")
+	if err != nil || got != data.AlgorithmPatience {
+		t.Errorf("should be patience: { got: %s, error: %v }", got, err)
+	}
+	if _, err := data.ParseDiffAlgorithm("minimal"); err == nil {
+		t.Errorf("error should not be nil")
+	}
+}
+
+// a large file, and a copy of it with about 1% of the lines edited and blocks moved
+func benchmarkContents() ([]byte, []byte) {
+	r := rand.New(rand.NewSource(42))
+	const n = 20000
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("statement %d(%d)\n", i, r.Intn(1000))
+	}
+	edited := append([]string{}, lines...)
+	for i := 0; i < n/100; i++ {
+		edited[r.Intn(n)] = fmt.Sprintf("edited %d\n", i)
+	}
+	//move a block of 500 lines from the middle to the end
+	block := append([]string{}, edited[n/2:n/2+500]...)
+	edited = append(append(edited[:n/2], edited[n/2+500:]...), block...)
+	return []byte(strings.Join(lines, "")), []byte(strings.Join(edited, ""))
+}
+
+func BenchmarkDiffAlgorithms(b *testing.B) {
+	from, to := benchmarkContents()
+	for _, algo := range algorithms {
+		b.Run(string(algo), func(b *testing.B) {
+			opts := data.NewDiffOptions()
+			opts.Algorithm = algo
+			b.SetBytes(int64(len(from) + len(to)))
+			for b.Loop() {
+				opts.DiffHunks(from, to)
+			}
+		})
+	}
+}
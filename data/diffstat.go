@@ -0,0 +1,162 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultStatWidth is the default width of the lines of --stat, including the histogram bar.
+const DefaultStatWidth = 80
+
+// FileStat is the number of inserted and deleted lines of a file in a diff.
+type FileStat struct {
+	Path    string
+	OldPath string //the path the file is renamed (or copied) from, empty for other changes
+	Added   int
+	Deleted int
+	Skipped bool //the lines are not counted, since the file is larger than BigFileThreshold
+	Binary  bool //the lines are not counted, since the file is binary. The sizes are counted instead.
+	OldSize int
+	NewSize int
+}
+
+// returns the path to print, like "old => new" for a rename
+func (s *FileStat) Name() string {
+	if s.OldPath != "" {
+		return s.OldPath + " => " + s.Path
+	}
+	return s.Path
+}
+
+// counts the inserted and deleted lines of each entry
+func (o *DiffOptions) DiffStats(entries []*DiffEntry, srcDir string) ([]*FileStat, error) {
+	stats := make([]*FileStat, 0, len(entries))
+	for _, e := range entries {
+		s := &FileStat{Path: e.Path, OldPath: e.OldPath}
+		fromPath, toPath := o.entryFilePaths(e, srcDir)
+		big, err := isBigFile(fromPath, toPath)
+		if err != nil {
+			return nil, fmt.Errorf("DiffStats: %w", err)
+		}
+		if big {
+			s.Skipped = true
+			stats = append(stats, s)
+			continue
+		}
+		from, err := readFileOrEmpty(fromPath)
+		if err != nil {
+			return nil, fmt.Errorf("DiffStats: %w", err)
+		}
+		to, err := readFileOrEmpty(toPath)
+		if err != nil {
+			return nil, fmt.Errorf("DiffStats: %w", err)
+		}
+		if o.isBinary(e.Path, from, to) {
+			s.Binary, s.OldSize, s.NewSize = true, len(from), len(to)
+			stats = append(stats, s)
+			continue
+		}
+		for _, l := range o.diffLines(from, to) {
+			switch l.Op {
+			case LineInsert:
+				s.Added++
+			case LineDelete:
+				s.Deleted++
+			}
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// writes the stats in the format of --numstat, separated by tabs: "{added}\t{deleted}\t{path}".
+// The counts of skipped and binary files are written as "-".
+func WriteNumstat(buf *bytes.Buffer, stats []*FileStat) {
+	for _, s := range stats {
+		if s.Skipped || s.Binary {
+			fmt.Fprintf(buf, "-\t-\t%s\n", s.Name())
+			continue
+		}
+		fmt.Fprintf(buf, "%d\t%d\t%s\n", s.Added, s.Deleted, s.Name())
+	}
+}
+
+// writes the stats in the format of --stat, with a histogram bar of "+" and "-" scaled to fit
+// in the width, followed by the summary of --shortstat:
+// -----------------
+// dir/file | 12 ++++++----
+// other    |  1 +
+// image    | Bin 120 -> 96 bytes
+// 3 files changed, 9 insertions(+), 4 deletions(-)
+// -----------------
+func WriteStat(buf *bytes.Buffer, stats []*FileStat, width int) {
+	nameWidth, countWidth, maxChanges := 0, 1, 0
+	for _, s := range stats {
+		nameWidth = max(nameWidth, len(s.Name()))
+		countWidth = max(countWidth, len(strconv.Itoa(s.Added+s.Deleted)))
+		if s.Binary {
+			countWidth = max(countWidth, len("Bin"))
+		}
+		maxChanges = max(maxChanges, s.Added+s.Deleted)
+	}
+	//" name | count bar"
+	barWidth := max(width-nameWidth-countWidth-4, 10)
+	for _, s := range stats {
+		if s.Skipped {
+			fmt.Fprintf(buf, " %-*s | %*s\n", nameWidth, s.Name(), countWidth, "Skipped")
+			continue
+		}
+		if s.Binary {
+			fmt.Fprintf(buf, " %-*s | %*s %d -> %d bytes\n", nameWidth, s.Name(), countWidth, "Bin", s.OldSize, s.NewSize)
+			continue
+		}
+		added, deleted := scaleBar(s.Added, s.Deleted, maxChanges, barWidth)
+		fmt.Fprintf(buf, " %-*s | %*d %s%s\n", nameWidth, s.Name(), countWidth, s.Added+s.Deleted,
+			strings.Repeat("+", added), strings.Repeat("-", deleted))
+	}
+	WriteShortstat(buf, stats)
+}
+
+// scales the numbers of lines to the bar, keeping at least one mark for a non-zero number
+func scaleBar(added int, deleted int, maxChanges int, barWidth int) (int, int) {
+	if maxChanges <= barWidth {
+		return added, deleted
+	}
+	scale := func(n int) int {
+		if n == 0 {
+			return 0
+		}
+		return max(n*barWidth/maxChanges, 1)
+	}
+	return scale(added), scale(deleted)
+}
+
+// writes the total of the stats in the format of --shortstat:
+// " 2 files changed, 9 insertions(+), 4 deletions(-)". Nothing is written for no file.
+func WriteShortstat(buf *bytes.Buffer, stats []*FileStat) {
+	if len(stats) == 0 {
+		return
+	}
+	added, deleted := 0, 0
+	for _, s := range stats {
+		added += s.Added
+		deleted += s.Deleted
+	}
+	fmt.Fprintf(buf, " %d %s changed", len(stats), plural(len(stats), "file", "files"))
+	if added > 0 || deleted == 0 {
+		fmt.Fprintf(buf, ", %d %s(+)", added, plural(added, "insertion", "insertions"))
+	}
+	if deleted > 0 || added == 0 {
+		fmt.Fprintf(buf, ", %d %s(-)", deleted, plural(deleted, "deletion", "deletions"))
+	}
+	buf.WriteString("\n")
+}
+
+func plural(n int, one string, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
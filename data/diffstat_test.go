@@ -0,0 +1,63 @@
+package data_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestDiffStats(t *testing.T) {
+	opts := data.NewDiffOptions()
+	entries := []*data.DiffEntry{
+		{Path: "file_01", OldOid: "from", NewOid: "to", OldMode: data.ModeBlob, NewMode: data.ModeBlob, Status: data.StatusModified},
+	}
+
+	got, err := opts.DiffStats(entries, "./test/diff")
+
+	if err != nil {
+		t.Errorf("error should be nil:\nerror:%s\n", err)
+	}
+	CmpStructs(t, got, []*data.FileStat{{Path: "file_01", Added: 1, Deleted: 1}})
+}
+
+func TestWriteStat(t *testing.T) {
+	stats := []*data.FileStat{
+		{Path: "dir/file", Added: 30, Deleted: 10},
+		{Path: "new", OldPath: "old", Added: 1},
+		{Path: "big", Skipped: true},
+	}
+	tests := []struct {
+		desc  string
+		write func(buf *bytes.Buffer)
+		want  string
+	}{
+		{
+			desc:  "01_stat scaled to the width",
+			write: func(buf *bytes.Buffer) { data.WriteStat(buf, stats, 30) },
+			want: " dir/file   | 40 ++++++++++---\n" +
+				" old => new |  1 +\n" +
+				" big        | Skipped\n" +
+				" 3 files changed, 31 insertions(+), 10 deletions(-)\n",
+		},
+		{
+			desc:  "02_numstat",
+			write: func(buf *bytes.Buffer) { data.WriteNumstat(buf, stats) },
+			want:  "30\t10\tdir/file\n1\t0\told => new\n-\t-\tbig\n",
+		},
+		{
+			desc:  "03_shortstat of a single file",
+			write: func(buf *bytes.Buffer) { data.WriteShortstat(buf, stats[1:2]) },
+			want:  " 1 file changed, 1 insertion(+)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			tt.write(&buf)
+
+			CmpStructs(t, buf.String(), tt.want)
+		})
+	}
+}
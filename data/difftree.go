@@ -0,0 +1,167 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DiffStatus tells how a path has changed between two trees.
+type DiffStatus byte
+
+const (
+	StatusAdded       DiffStatus = 'A'
+	StatusDeleted     DiffStatus = 'D'
+	StatusModified    DiffStatus = 'M'
+	StatusTypeChanged DiffStatus = 'T' //a file became a directory, or vice versa
+	StatusRenamed     DiffStatus = 'R'
+	StatusCopied      DiffStatus = 'C'
+)
+
+// modes recorded for tree elements, following the notation of git
+const (
+	ModeBlob       = "100644"
+	ModeExecutable = "100755" //only appears in patches, since trees do not record the executable bit
+	ModeTree       = "040000"
+)
+
+// DiffEntry is a change of a single path between two trees. The old (or new) side is empty
+// when the path is added (or deleted).
+type DiffEntry struct {
+	Path    string //slash-separated path from the root of the trees
+	OldOid  string
+	NewOid  string
+	OldMode string
+	NewMode string
+	Status  DiffStatus
+	OldPath string //the path the file is renamed (or copied) from, empty for other changes
+	Score   int    //similarity (0-100) of the contents of a rename or a copy
+}
+
+// returns the path of the old side, which differs from Path for a rename or a copy
+func (e *DiffEntry) SourcePath() string {
+	if e.OldPath != "" {
+		return e.OldPath
+	}
+	return e.Path
+}
+
+// returns the status letter, followed by the similarity for a rename or a copy (e.g. "M", "R087")
+func (e *DiffEntry) StatusCode() string {
+	if e.Status == StatusRenamed || e.Status == StatusCopied {
+		return fmt.Sprintf("%c%03d", e.Status, e.Score)
+	}
+	return string(e.Status)
+}
+
+// returns the entry in the format of --name-status, separated by tabs (e.g. "M\tpath", "R087\told\tnew")
+func (e *DiffEntry) NameStatus() string {
+	if e.OldPath != "" {
+		return fmt.Sprintf("%s\t%s\t%s", e.StatusCode(), e.OldPath, e.Path)
+	}
+	return fmt.Sprintf("%s\t%s", e.StatusCode(), e.Path)
+}
+
+// reports whether the old side of the entry is a file whose content can be compared
+func (e *DiffEntry) OldIsBlob() bool {
+	return e.OldMode == ModeBlob
+}
+
+// reports whether the new side of the entry is a file whose content can be compared
+func (e *DiffEntry) NewIsBlob() bool {
+	return e.NewMode == ModeBlob
+}
+
+// DiffTreeEntries compares two trees recursively and returns a change for every file that is added,
+// deleted or modified, sorted by path. When a file and a directory swap places, the path itself is
+// reported as type-changed, and the files under the directory are reported as added (or deleted).
+// Subtrees with the same non-empty oid are skipped without being walked.
+func DiffTreeEntries(from Tree, to Tree) []*DiffEntry {
+	var entries []*DiffEntry
+	diffTreeEntries(from, to, "", &entries)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+func diffTreeEntries(from Tree, to Tree, dir string, entries *[]*DiffEntry) {
+	for _, name := range unionNames(from, to) {
+		path := joinRelPath(dir, name)
+		fromElem, toElem := from[name], to[name]
+		switch {
+		case toElem == nil:
+			collectEntries(fromElem, path, StatusDeleted, entries)
+		case fromElem == nil:
+			collectEntries(toElem, path, StatusAdded, entries)
+		case fromElem.ObjType != toElem.ObjType:
+			*entries = append(*entries, &DiffEntry{
+				Path:    path,
+				OldOid:  fromElem.Oid,
+				NewOid:  toElem.Oid,
+				OldMode: modeOf(fromElem),
+				NewMode: modeOf(toElem),
+				Status:  StatusTypeChanged,
+			})
+			if fromElem.ObjType == ObjTypeTree {
+				collectChildren(fromElem, path, StatusDeleted, entries)
+			} else {
+				collectChildren(toElem, path, StatusAdded, entries)
+			}
+		case fromElem.ObjType == ObjTypeTree:
+			if fromElem.Oid != "" && fromElem.Oid == toElem.Oid {
+				continue
+			}
+			diffTreeEntries(fromElem.Child, toElem.Child, path, entries)
+		case fromElem.Oid != toElem.Oid:
+			*entries = append(*entries, &DiffEntry{
+				Path:    path,
+				OldOid:  fromElem.Oid,
+				NewOid:  toElem.Oid,
+				OldMode: ModeBlob,
+				NewMode: ModeBlob,
+				Status:  StatusModified,
+			})
+		}
+	}
+}
+
+// records the element (or every file under it if it is a directory) as added or deleted
+func collectEntries(elem *TreeElem, path string, status DiffStatus, entries *[]*DiffEntry) {
+	if elem.ObjType == ObjTypeTree {
+		collectChildren(elem, path, status, entries)
+		return
+	}
+	e := &DiffEntry{Path: path, Status: status}
+	if status == StatusAdded {
+		e.NewOid, e.NewMode = elem.Oid, ModeBlob
+	} else {
+		e.OldOid, e.OldMode = elem.Oid, ModeBlob
+	}
+	*entries = append(*entries, e)
+}
+
+func collectChildren(elem *TreeElem, path string, status DiffStatus, entries *[]*DiffEntry) {
+	for _, name := range unionNames(elem.Child, nil) {
+		collectEntries(elem.Child[name], joinRelPath(path, name), status, entries)
+	}
+}
+
+func modeOf(elem *TreeElem) string {
+	if elem.ObjType == ObjTypeTree {
+		return ModeTree
+	}
+	return ModeBlob
+}
+
+// returns the names in either of the trees in sorted order
+func unionNames(a Tree, b Tree) []string {
+	names := make([]string, 0, len(a)+len(b))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,529 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// kinds of problems fsck reports
+const (
+	FsckMissing   = "missing"   //an object referred to does not exist
+	FsckCorrupt   = "corrupt"   //stored bytes do not match the oid
+	FsckMistyped  = "mistyped"  //an object is not of the type it is referred to as
+	FsckMalformed = "malformed" //an object cannot be parsed
+	FsckDangling  = "dangling"  //an object no ref can reach
+	FsckBrokenRef = "broken-ref"
+)
+
+type FsckProblem struct {
+	Kind    string `json:"kind"`
+	ObjType string `json:"type,omitempty"`
+	Oid     string `json:"oid,omitempty"`
+	Ref     string `json:"ref,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+func (p *FsckProblem) String() string {
+	if p.Kind == FsckBrokenRef {
+		return fmt.Sprintf("%s %s: %s", p.Kind, p.Ref, p.Detail)
+	}
+	s := fmt.Sprintf("%s %s %s", p.Kind, p.ObjType, p.Oid)
+	if p.Detail != "" {
+		s += ": " + p.Detail
+	}
+	return s
+}
+
+type FsckReport struct {
+	Refs     int            `json:"refs"`
+	Tags     int            `json:"tags"`
+	Commits  int            `json:"commits"`
+	Trees    int            `json:"trees"`
+	Blobs    int            `json:"blobs"`
+	Objects  int            `json:"objects"`
+	Problems []*FsckProblem `json:"problems"`
+}
+
+// reports whether the repository has no problems except dangling objects
+func (r *FsckReport) OK() bool {
+	for _, p := range r.Problems {
+		if p.Kind != FsckDangling {
+			return false
+		}
+	}
+	return true
+}
+
+type fsck struct {
+	objDirPath       string
+	connectivityOnly bool
+	report           *FsckReport
+	reached          map[string]bool //{ key: "objType oid" }
+	touched          map[string]bool //{ key: oid } reached as any type
+	verified         map[string]bool
+}
+
+// Fsck walks all the refs (including HEAD) under pgitDirPath, and every tag, commit, tree and blob reachable
+// from them or staged in the index, and reports missing, corrupt, mistyped, malformed and dangling objects and broken refs.
+// If connectivityOnly is true, the content of objects is not hashed again, which makes the check faster.
+func Fsck(pgitDirPath string, connectivityOnly bool) (*FsckReport, error) {
+	f := &fsck{
+		objDirPath:       filepath.Join(pgitDirPath, ObjDirBase),
+		connectivityOnly: connectivityOnly,
+		report:           &FsckReport{Problems: []*FsckProblem{}},
+		reached:          make(map[string]bool),
+		touched:          make(map[string]bool),
+		verified:         make(map[string]bool),
+	}
+	roots, err := f.checkRefs(pgitDirPath)
+	if err != nil {
+		return nil, fmt.Errorf("Fsck: %w", err)
+	}
+	if roots, err = f.checkTags(roots); err != nil {
+		return nil, fmt.Errorf("Fsck: %w", err)
+	}
+	if err := f.checkCommits(roots); err != nil {
+		return nil, fmt.Errorf("Fsck: %w", err)
+	}
+	if err := f.checkIndex(pgitDirPath); err != nil {
+		return nil, fmt.Errorf("Fsck: %w", err)
+	}
+	if err := f.checkAllObjects(); err != nil {
+		return nil, fmt.Errorf("Fsck: %w", err)
+	}
+	return f.report, nil
+}
+
+func (f *fsck) addProblem(kind string, objType string, oid string, detail string) {
+	f.report.Problems = append(f.report.Problems, &FsckProblem{Kind: kind, ObjType: objType, Oid: oid, Detail: detail})
+}
+
+// checks HEAD and all the refs under the ref directory, and returns the commit oids they point to
+func (f *fsck) checkRefs(pgitDirPath string) (roots []string, err error) {
+	paths := []string{filepath.Join(pgitDirPath, HEAD)}
+	refDir := filepath.Join(pgitDirPath, RefDirBase)
+	err = filepath.WalkDir(refDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("checkRefs: %w", err)
+	}
+	for _, path := range paths {
+		name, _ := filepath.Rel(pgitDirPath, path)
+		name = filepath.ToSlash(name)
+		f.report.Refs++
+		oid, detail, err := f.resolveRef(path)
+		if err != nil {
+			return nil, fmt.Errorf("checkRefs: %w", err)
+		}
+		if detail != "" {
+			f.report.Problems = append(f.report.Problems, &FsckProblem{Kind: FsckBrokenRef, Ref: name, Detail: detail})
+			continue
+		}
+		if oid == "" {
+			continue
+		}
+		if !isOid(oid) {
+			f.report.Problems = append(f.report.Problems, &FsckProblem{Kind: FsckBrokenRef, Ref: name, Detail: fmt.Sprintf("invalid oid %q", oid)})
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(f.objDirPath, oid)); err != nil {
+			f.report.Problems = append(f.report.Problems, &FsckProblem{Kind: FsckBrokenRef, Ref: name, Detail: fmt.Sprintf("points to missing object %s", oid)})
+			continue
+		}
+		roots = append(roots, oid)
+	}
+	return roots, nil
+}
+
+// follows the ref in the path, and returns the oid at the end of it.
+// If the ref is broken, a non-empty detail describes why.
+func (f *fsck) resolveRef(path string) (oid string, detail string, err error) {
+	seen := make(map[string]bool)
+	for {
+		if seen[path] {
+			return "", "symbolic ref loop", nil
+		}
+		seen[path] = true
+		ref, err := NewRef(path)
+		if err != nil {
+			return "", "", err
+		}
+		if ref == nil {
+			return "", fmt.Sprintf("points to missing ref %s", path), nil
+		}
+		if !ref.IsSymbolic {
+			return strings.TrimSpace(ref.Oid), "", nil
+		}
+		if ref.Next == "" {
+			return "", "empty symbolic ref", nil
+		}
+		path = ref.Next
+	}
+}
+
+// checks the tags among the roots and the objects they point to, and returns the commits among the roots
+// with the tags replaced by the commits they point to. The other objects the roots point to are checked here.
+func (f *fsck) checkTags(roots []string) ([]string, error) {
+	var commits []string
+	for _, oid := range roots {
+		peeled := false
+		for {
+			content, err := ReadAllFileContent(filepath.Join(f.objDirPath, oid))
+			if err != nil {
+				return nil, fmt.Errorf("checkTags: %w", err)
+			}
+			if !isTagContent(content) {
+				//an annotated tag of a commit declares the type, while a ref (e.g. a lightweight tag)
+				//may point to an object of any type
+				objType := ObjTypeCommit
+				if !peeled {
+					objType = guessContentType(content)
+				}
+				switch objType {
+				case ObjTypeCommit:
+					commits = append(commits, oid)
+				case ObjTypeTree:
+					if err := f.checkTree(oid); err != nil {
+						return nil, fmt.Errorf("checkTags: %w", err)
+					}
+				default:
+					if err := f.checkBlob(oid); err != nil {
+						return nil, fmt.Errorf("checkTags: %w", err)
+					}
+				}
+				break
+			}
+			if f.reach(ObjTypeTag, oid) {
+				break
+			}
+			if _, ok, err := f.readObject(ObjTypeTag, oid); err != nil {
+				return nil, fmt.Errorf("checkTags: %w", err)
+			} else if !ok {
+				break
+			}
+			f.report.Tags++
+			t, err := ParseTag(content)
+			if err != nil {
+				f.addProblem(FsckMalformed, ObjTypeTag, oid, err.Error())
+				break
+			}
+			if _, err := os.Stat(filepath.Join(f.objDirPath, t.Object)); err != nil {
+				f.addProblem(FsckMissing, t.Type, t.Object, "")
+				break
+			}
+			switch t.Type {
+			case ObjTypeTree:
+				if err := f.checkTree(t.Object); err != nil {
+					return nil, fmt.Errorf("checkTags: %w", err)
+				}
+			case ObjTypeBlob:
+				if err := f.checkBlob(t.Object); err != nil {
+					return nil, fmt.Errorf("checkTags: %w", err)
+				}
+			case ObjTypeTag, ObjTypeCommit:
+				oid, peeled = t.Object, true
+				continue
+			default:
+				f.addProblem(FsckMalformed, ObjTypeTag, oid, fmt.Sprintf("unknown type %q", t.Type))
+			}
+			break
+		}
+	}
+	return commits, nil
+}
+
+// walks the commit history back from the roots, checking each commit and its tree
+func (f *fsck) checkCommits(roots []string) error {
+	queue := append([]string{}, roots...)
+	for len(queue) > 0 {
+		oid := queue[0]
+		queue = queue[1:]
+		if f.reach(ObjTypeCommit, oid) {
+			continue
+		}
+		content, ok, err := f.readObject(ObjTypeCommit, oid)
+		if err != nil {
+			return fmt.Errorf("checkCommits: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		f.report.Commits++
+		treeOid, parents, ok := parseCommitHeader(content)
+		if !ok {
+			f.addProblem(FsckMistyped, ObjTypeCommit, oid, "not a commit object")
+			continue
+		}
+		if err := f.checkTree(treeOid); err != nil {
+			return fmt.Errorf("checkCommits: %w", err)
+		}
+		for _, p := range parents {
+			if !isOid(p) {
+				f.addProblem(FsckMalformed, ObjTypeCommit, oid, fmt.Sprintf("invalid parent %q", p))
+				continue
+			}
+			queue = append(queue, p)
+		}
+	}
+	return nil
+}
+
+// checks the blobs staged in the index, which are not committed yet but reachable as gc keeps them
+func (f *fsck) checkIndex(pgitDirPath string) error {
+	idx, err := ReadIndex(filepath.Join(pgitDirPath, IndexBase))
+	if err != nil {
+		return fmt.Errorf("checkIndex: %w", err)
+	}
+	for _, p := range idx.Paths() {
+		if err := f.checkBlob(idx[p]); err != nil {
+			return fmt.Errorf("checkIndex: %w", err)
+		}
+	}
+	return nil
+}
+
+// checks the tree and everything in it recursively
+func (f *fsck) checkTree(oid string) error {
+	if f.reach(ObjTypeTree, oid) {
+		return nil
+	}
+	content, ok, err := f.readObject(ObjTypeTree, oid)
+	if err != nil {
+		return fmt.Errorf("checkTree: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	f.report.Trees++
+	if _, _, isCommit := parseCommitHeader(content); isCommit {
+		f.addProblem(FsckMistyped, ObjTypeTree, oid, "a commit is referred to as a tree")
+		return nil
+	}
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Split(bufio.ScanLines)
+	for sc.Scan() {
+		objType, entryOid, name, err := parseTreeLine(sc.Text())
+		if err != nil {
+			f.addProblem(FsckMalformed, ObjTypeTree, oid, err.Error())
+			continue
+		}
+		if !isOid(entryOid) {
+			f.addProblem(FsckMalformed, ObjTypeTree, oid, fmt.Sprintf("invalid oid %q for %s", entryOid, name))
+			continue
+		}
+		switch objType {
+		case ObjTypeTree:
+			if err := f.checkTree(entryOid); err != nil {
+				return err
+			}
+		case ObjTypeBlob:
+			if err := f.checkBlob(entryOid); err != nil {
+				return fmt.Errorf("checkTree: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// checks the blob unless it has already been reached
+func (f *fsck) checkBlob(oid string) error {
+	if f.reach(ObjTypeBlob, oid) {
+		return nil
+	}
+	if _, ok, err := f.readObject(ObjTypeBlob, oid); err != nil {
+		return fmt.Errorf("checkBlob: %w", err)
+	} else if ok {
+		f.report.Blobs++
+	}
+	return nil
+}
+
+// marks the object as reached, and reports whether it had already been reached as the type
+func (f *fsck) reach(objType string, oid string) (already bool) {
+	key := objType + " " + oid
+	already = f.reached[key]
+	f.reached[key] = true
+	f.touched[oid] = true
+	return already
+}
+
+// reads an object and verifies its content against the oid unless connectivityOnly is set.
+// ok is false if the object is missing or corrupt, in which case the problem has been recorded.
+// Blobs are never loaded into memory; their content is returned as nil.
+func (f *fsck) readObject(objType string, oid string) (content []byte, ok bool, err error) {
+	path := filepath.Join(f.objDirPath, oid)
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			f.addProblem(FsckMissing, objType, oid, "")
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("readObject: %w", err)
+	}
+	if !f.connectivityOnly && !f.verify(objType, oid) {
+		return nil, false, nil
+	}
+	if objType == ObjTypeBlob {
+		return nil, true, nil
+	}
+	content, err = ReadAllFileContent(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("readObject: %w", err)
+	}
+	return content, true, nil
+}
+
+// hashes the object again and records a problem if it does not match the oid
+func (f *fsck) verify(objType string, oid string) bool {
+	if ok, done := f.verified[oid]; done {
+		return ok
+	}
+	ok := true
+	got, err := hashObjectFile(filepath.Join(f.objDirPath, oid))
+	if err != nil || got != oid {
+		ok = false
+		f.addProblem(FsckCorrupt, objType, oid, fmt.Sprintf("content hashes to %s", got))
+	}
+	f.verified[oid] = ok
+	return ok
+}
+
+// goes through all the objects in the storage, verifying unreached ones and reporting them as dangling
+func (f *fsck) checkAllObjects() error {
+	ents, err := os.ReadDir(f.objDirPath)
+	if err != nil {
+		return fmt.Errorf("checkAllObjects: %w", err)
+	}
+	sort.Slice(ents, func(i, j int) bool { return ents[i].Name() < ents[j].Name() })
+	for _, ent := range ents {
+		oid := ent.Name()
+		if ent.IsDir() || strings.HasPrefix(oid, "tmp_") {
+			continue
+		}
+		f.report.Objects++
+		if f.touched[oid] {
+			continue
+		}
+		if !f.connectivityOnly && !f.verify("", oid) {
+			continue
+		}
+		objType, err := guessObjType(filepath.Join(f.objDirPath, oid))
+		if err != nil {
+			return fmt.Errorf("checkAllObjects: %w", err)
+		}
+		f.addProblem(FsckDangling, objType, oid, "")
+	}
+	return nil
+}
+
+// hashes the content of an object file in the same way as SaveBlobObj
+func hashObjectFile(path string) (oid string, err error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("hashObjectFile: %w", err)
+	}
+	defer r.Close()
+	oid, err = IssueObjIDFrom(newBlobHeaderReader(r))
+	if err != nil {
+		return "", fmt.Errorf("hashObjectFile: %w", err)
+	}
+	return oid, nil
+}
+
+// Objects larger than this are taken for blobs without reading them, so that a dangling big file is never
+// loaded into memory. Commits, tags and trees are far smaller in practice.
+const maxGuessedObjSize = 200 << 10
+
+// Since every object is saved with the same header, the type of an object can only be guessed from its content.
+func guessObjType(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if fi.Size() > maxGuessedObjSize {
+		return ObjTypeBlob, nil
+	}
+	content, err := ReadAllFileContent(path)
+	if err != nil {
+		return "", err
+	}
+	return guessContentType(content), nil
+}
+
+// guesses the type of an object from its content
+func guessContentType(content []byte) string {
+	if _, _, ok := parseCommitHeader(content); ok {
+		return ObjTypeCommit
+	}
+	if isTagContent(content) {
+		return ObjTypeTag
+	}
+	if isTreeContent(content) {
+		return ObjTypeTree
+	}
+	return ObjTypeBlob
+}
+
+// reports whether every line of the content is a valid tree line with a valid oid
+func isTreeContent(content []byte) bool {
+	if len(content) == 0 {
+		return false
+	}
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Split(bufio.ScanLines)
+	for sc.Scan() {
+		_, oid, _, err := parseTreeLine(sc.Text())
+		if err != nil || !isOid(oid) {
+			return false
+		}
+	}
+	return true
+}
+
+// reads the header lines of a commit. ok is false unless the first line is "tree {oid}".
+func parseCommitHeader(content []byte) (treeOid string, parents []string, ok bool) {
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Split(bufio.ScanLines)
+	first := true
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			break
+		}
+		key, value, _ := strings.Cut(line, " ")
+		if first {
+			if key != "tree" || !isOid(value) {
+				return "", nil, false
+			}
+			treeOid, first = value, false
+			continue
+		}
+		if key == "parent" {
+			parents = append(parents, value)
+		}
+	}
+	return treeOid, parents, !first
+}
+
+// reports whether s looks like an oid (= 40 hex characters)
+func isOid(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
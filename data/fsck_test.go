@@ -0,0 +1,253 @@
+package data_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+// builds a repository with a single commit in a temporary directory, and returns the pgit directory
+// together with the oids of the commit, the tree and the blob in it.
+func newFsckRepo(t *testing.T) (pgitDir string, commitOid string, treeOid string, blobOid string) {
+	t.Helper()
+
+	root := t.TempDir()
+	pgitDir = filepath.Join(root, data.PgitDirBase)
+	objDir := filepath.Join(pgitDir, data.ObjDirBase)
+	headDir := filepath.Join(pgitDir, data.RefDirBase, data.HeadDirBase)
+	for _, dir := range []string{objDir, headDir} {
+		if err := os.MkdirAll(dir, os.ModeDir|0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blobOid, err := data.SaveBlobObj(objDir, []byte("fsck test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeOid, err = data.SaveBlobObj(objDir, fmt.Appendf(nil, "blob %s file_01\n", blobOid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitOid, err = data.SaveBlobObj(objDir, fmt.Appendf(nil, "tree %s\n\nfirst commit", treeOid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	master := filepath.Join(headDir, "master")
+	if err := data.WriteFile(master, []byte(commitOid)); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.WriteFile(filepath.Join(pgitDir, data.HEAD), fmt.Appendf(nil, "ref: %s <- HEAD\n", master)); err != nil {
+		t.Fatal(err)
+	}
+	return pgitDir, commitOid, treeOid, blobOid
+}
+
+func TestFsck(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		pgitDir, _, _, _ := newFsckRepo(t)
+
+		report, err := data.Fsck(pgitDir, false)
+
+		if err != nil {
+			t.Errorf("should be nil: \n{ error: %s }", err)
+		}
+		if !report.OK() || len(report.Problems) != 0 {
+			t.Errorf("should have no problems: (got: %v)", report.Problems)
+		}
+		if report.Commits != 1 || report.Trees != 1 || report.Blobs != 1 {
+			t.Errorf("counts should be equal: (got: %+v)", report)
+		}
+	})
+
+	t.Run("annotated tag", func(t *testing.T) {
+		pgitDir, commitOid, _, _ := newFsckRepo(t)
+		tag := &data.Tag{Object: commitOid, Type: data.ObjTypeCommit, Name: "v1", Tagger: data.NewSignature("Taro", "taro@example.com", time.Unix(1700000000, 0)), Msg: "v1"}
+		tagOid, err := data.SaveBlobObj(filepath.Join(pgitDir, data.ObjDirBase), tag.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagDir := filepath.Join(pgitDir, data.RefDirBase, data.TagDirBase)
+		if err := os.MkdirAll(tagDir, os.ModeDir|0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := data.WriteFile(filepath.Join(tagDir, "v1"), []byte(tagOid)); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := data.Fsck(pgitDir, false)
+
+		if err != nil {
+			t.Errorf("should be nil: \n{ error: %s }", err)
+		}
+		if !report.OK() || len(report.Problems) != 0 {
+			t.Errorf("should have no problems: (got: %v)", report.Problems)
+		}
+		if report.Tags != 1 || report.Commits != 1 {
+			t.Errorf("counts should be equal: (got: %+v)", report)
+		}
+	})
+
+	t.Run("lightweight tag", func(t *testing.T) {
+		tests := []struct {
+			desc   string
+			target func(treeOid, blobOid string) string
+		}{
+			{desc: "01_tree", target: func(treeOid, blobOid string) string { return treeOid }},
+			{desc: "02_blob", target: func(treeOid, blobOid string) string { return blobOid }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				pgitDir, _, treeOid, blobOid := newFsckRepo(t)
+				tagDir := filepath.Join(pgitDir, data.RefDirBase, data.TagDirBase)
+				if err := os.MkdirAll(tagDir, os.ModeDir|0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := data.WriteFile(filepath.Join(tagDir, "v1"), []byte(tt.target(treeOid, blobOid))); err != nil {
+					t.Fatal(err)
+				}
+
+				report, err := data.Fsck(pgitDir, false)
+
+				if err != nil {
+					t.Errorf("should be nil: \n{ error: %s }", err)
+				}
+				if !report.OK() || len(report.Problems) != 0 {
+					t.Errorf("should have no problems: (got: %v)", report.Problems)
+				}
+				if report.Commits != 1 || report.Trees != 1 || report.Blobs != 1 {
+					t.Errorf("counts should be equal: (got: %+v)", report)
+				}
+			})
+		}
+	})
+
+	t.Run("staged blob", func(t *testing.T) {
+		pgitDir, _, _, blobOid := newFsckRepo(t)
+		staged, err := data.SaveBlobObj(filepath.Join(pgitDir, data.ObjDirBase), []byte("staged"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx := data.Index{"file_01": blobOid, "file_02": staged}
+		if err := idx.Write(filepath.Join(pgitDir, data.IndexBase)); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := data.Fsck(pgitDir, false)
+
+		if err != nil {
+			t.Errorf("should be nil: \n{ error: %s }", err)
+		}
+		if !report.OK() || len(report.Problems) != 0 {
+			t.Errorf("should have no problems: (got: %v)", report.Problems)
+		}
+		if report.Blobs != 2 {
+			t.Errorf("counts should be equal: (got: %+v)", report)
+		}
+	})
+
+	t.Run("problems", func(t *testing.T) {
+		tests := []struct {
+			desc             string
+			connectivityOnly bool
+			breakRepo        func(t *testing.T, objDir string, commitOid, treeOid, blobOid string)
+			want             []*data.FsckProblem
+		}{
+			{
+				desc: "01_missing blob",
+				breakRepo: func(t *testing.T, objDir string, commitOid, treeOid, blobOid string) {
+					os.Remove(filepath.Join(objDir, blobOid))
+				},
+				want: []*data.FsckProblem{{Kind: data.FsckMissing, ObjType: data.ObjTypeBlob}},
+			},
+			{
+				desc: "02_corrupt blob",
+				breakRepo: func(t *testing.T, objDir string, commitOid, treeOid, blobOid string) {
+					data.WriteFile(filepath.Join(objDir, blobOid), []byte("tampered"))
+				},
+				want: []*data.FsckProblem{{Kind: data.FsckCorrupt, ObjType: data.ObjTypeBlob}},
+			},
+			{
+				desc:             "03_corrupt blob is not noticed with connectivity only",
+				connectivityOnly: true,
+				breakRepo: func(t *testing.T, objDir string, commitOid, treeOid, blobOid string) {
+					data.WriteFile(filepath.Join(objDir, blobOid), []byte("tampered"))
+				},
+				want: []*data.FsckProblem{},
+			},
+			{
+				desc: "04_tampered tree leaves its blob dangling",
+				breakRepo: func(t *testing.T, objDir string, commitOid, treeOid, blobOid string) {
+					data.WriteFile(filepath.Join(objDir, treeOid), []byte("blob"))
+				},
+				want: []*data.FsckProblem{
+					{Kind: data.FsckCorrupt, ObjType: data.ObjTypeTree},
+					{Kind: data.FsckDangling, ObjType: data.ObjTypeBlob},
+				},
+			},
+			{
+				desc: "05_malformed tree",
+				breakRepo: func(t *testing.T, objDir string, commitOid, treeOid, blobOid string) {
+					malformed, _ := data.SaveBlobObj(objDir, fmt.Appendf(nil, "blob %s\n", blobOid))
+					c, _ := data.SaveBlobObj(objDir, fmt.Appendf(nil, "tree %s\nparent %s\n\nsecond commit", malformed, commitOid))
+					data.WriteFile(filepath.Join(filepath.Dir(objDir), data.RefDirBase, data.HeadDirBase, "master"), []byte(c))
+				},
+				want: []*data.FsckProblem{{Kind: data.FsckMalformed, ObjType: data.ObjTypeTree}},
+			},
+			{
+				desc: "06_commit referred to as a tree",
+				breakRepo: func(t *testing.T, objDir string, commitOid, treeOid, blobOid string) {
+					c, _ := data.SaveBlobObj(objDir, fmt.Appendf(nil, "tree %s\nparent %s\n\nsecond commit", commitOid, commitOid))
+					data.WriteFile(filepath.Join(filepath.Dir(objDir), data.RefDirBase, data.HeadDirBase, "master"), []byte(c))
+				},
+				want: []*data.FsckProblem{{Kind: data.FsckMistyped, ObjType: data.ObjTypeTree}},
+			},
+			{
+				desc: "07_dangling object",
+				breakRepo: func(t *testing.T, objDir string, commitOid, treeOid, blobOid string) {
+					data.SaveBlobObj(objDir, []byte("nobody refers to me"))
+				},
+				want: []*data.FsckProblem{{Kind: data.FsckDangling, ObjType: data.ObjTypeBlob}},
+			},
+			{
+				desc: "08_broken ref",
+				breakRepo: func(t *testing.T, objDir string, commitOid, treeOid, blobOid string) {
+					branch := filepath.Join(filepath.Dir(objDir), data.RefDirBase, data.HeadDirBase, "broken")
+					data.WriteFile(branch, []byte("0123456789012345678901234567890123456789"))
+				},
+				want: []*data.FsckProblem{{Kind: data.FsckBrokenRef}},
+			},
+			{
+				desc: "09_annotated tag of a commit pointing to a tree",
+				breakRepo: func(t *testing.T, objDir string, commitOid, treeOid, blobOid string) {
+					tag := &data.Tag{Object: treeOid, Type: data.ObjTypeCommit, Name: "v1", Tagger: data.NewSignature("Taro", "taro@example.com", time.Unix(1700000000, 0)), Msg: "v1"}
+					tagOid, _ := data.SaveBlobObj(objDir, tag.Encode())
+					tagDir := filepath.Join(filepath.Dir(objDir), data.RefDirBase, data.TagDirBase)
+					os.MkdirAll(tagDir, os.ModeDir|0755)
+					data.WriteFile(filepath.Join(tagDir, "v1"), []byte(tagOid))
+				},
+				want: []*data.FsckProblem{{Kind: data.FsckMistyped, ObjType: data.ObjTypeCommit}},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				pgitDir, commitOid, treeOid, blobOid := newFsckRepo(t)
+				tt.breakRepo(t, filepath.Join(pgitDir, data.ObjDirBase), commitOid, treeOid, blobOid)
+
+				report, err := data.Fsck(pgitDir, tt.connectivityOnly)
+
+				if err != nil {
+					t.Errorf("should be nil: \n{ error: %s }", err)
+				}
+				got := make([]*data.FsckProblem, 0, len(report.Problems))
+				for _, p := range report.Problems {
+					got = append(got, &data.FsckProblem{Kind: p.Kind, ObjType: p.ObjType})
+				}
+				CmpStructs(t, got, tt.want)
+			})
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPruneExpire is the default grace period of gc, during which unreachable objects are kept.
+const DefaultPruneExpire = "2.weeks.ago"
+
+// config key for the grace period of gc
+const ConfigPruneExpire = "gc.pruneExpire"
+
+// Marks every object reachable from the roots (= commit or tag oids), following tags, parents, trees and blobs.
+// A missing object is an error, since pruning a broken repository may lose even more.
+func ReachableObjects(objDirPath string, roots []string) (map[string]bool, error) {
+	reachable := make(map[string]bool)
+	queue := append([]string{}, roots...)
+	for len(queue) > 0 {
+		oid := queue[0]
+		queue = queue[1:]
+		if oid == "" || reachable[oid] {
+			continue
+		}
+		content, err := ReadAllFileContent(filepath.Join(objDirPath, oid))
+		if err != nil {
+			return nil, fmt.Errorf("ReachableObjects: commit %s: %w", oid, err)
+		}
+		reachable[oid] = true
+		if isTagContent(content) {
+			t, err := ParseTag(content)
+			if err != nil {
+				return nil, fmt.Errorf("ReachableObjects: %w", err)
+			}
+			switch t.Type {
+			case ObjTypeTree:
+				if err := markTree(objDirPath, t.Object, reachable); err != nil {
+					return nil, fmt.Errorf("ReachableObjects: %w", err)
+				}
+			case ObjTypeBlob:
+				if _, err := os.Stat(filepath.Join(objDirPath, t.Object)); err != nil {
+					return nil, fmt.Errorf("ReachableObjects: blob %s: %w", t.Object, err)
+				}
+				reachable[t.Object] = true
+			default:
+				queue = append(queue, t.Object)
+			}
+			continue
+		}
+		treeOid, parents, ok := parseCommitHeader(content)
+		if !ok {
+			return nil, fmt.Errorf("ReachableObjects: %s is not a commit", oid)
+		}
+		if err := markTree(objDirPath, treeOid, reachable); err != nil {
+			return nil, fmt.Errorf("ReachableObjects: %w", err)
+		}
+		queue = append(queue, parents...)
+	}
+	return reachable, nil
+}
+
+// marks the tree and everything in it as reachable
+func markTree(objDirPath string, treeOid string, reachable map[string]bool) error {
+	if reachable[treeOid] {
+		return nil
+	}
+	content, err := ReadAllFileContent(filepath.Join(objDirPath, treeOid))
+	if err != nil {
+		return fmt.Errorf("markTree: tree %s: %w", treeOid, err)
+	}
+	reachable[treeOid] = true
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Split(bufio.ScanLines)
+	for sc.Scan() {
+		objType, oid, _, err := parseTreeLine(sc.Text())
+		if err != nil {
+			return fmt.Errorf("markTree: %w", err)
+		}
+		if objType == ObjTypeTree {
+			if err := markTree(objDirPath, oid, reachable); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(objDirPath, oid)); err != nil {
+			return fmt.Errorf("markTree: blob %s: %w", oid, err)
+		}
+		reachable[oid] = true
+	}
+	return nil
+}
+
+type PruneReport struct {
+	Oids    []string //pruned (or to be pruned in a dry run) objects
+	Objects int      //number of pruned objects
+	Bytes   int64    //reclaimed space
+	Kept    int      //unreachable objects kept because of the grace period
+}
+
+// Deletes the objects in the storage that are not reachable and were last written before expire.
+// Temporary files left by interrupted writes are deleted in the same way. Nothing is deleted in a dry run.
+func PruneObjects(objDirPath string, reachable map[string]bool, expire time.Time, dryRun bool) (*PruneReport, error) {
+	ents, err := os.ReadDir(objDirPath)
+	if err != nil {
+		return nil, fmt.Errorf("PruneObjects: %w", err)
+	}
+	report := &PruneReport{}
+	for _, ent := range ents {
+		name := ent.Name()
+		if ent.IsDir() || reachable[name] {
+			continue
+		}
+		fi, err := ent.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("PruneObjects: %w", err)
+		}
+		if !fi.ModTime().Before(expire) {
+			report.Kept++
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(filepath.Join(objDirPath, name)); err != nil {
+				return nil, fmt.Errorf("PruneObjects: %w", err)
+			}
+		}
+		report.Oids = append(report.Oids, name)
+		report.Objects++
+		report.Bytes += fi.Size()
+	}
+	return report, nil
+}
+
+// converts an expiry date into a time relative to now. Accepted formats are:
+// "now", "never", "{n}.{unit}.ago" (unit: seconds, minutes, hours, days, weeks), a duration like "72h",
+// or a date like "2025-01-31".
+func ParseExpire(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "now", "all":
+		//objects written in this very second are still pruned
+		return now.Add(time.Second), nil
+	case "never":
+		return time.Time{}, nil
+	}
+	if rest, ok := strings.CutSuffix(s, ".ago"); ok {
+		num, unit, _ := strings.Cut(rest, ".")
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("ParseExpire: invalid expiry: %s", s)
+		}
+		d, ok := expireUnits[strings.TrimSuffix(unit, "s")]
+		if !ok {
+			return time.Time{}, fmt.Errorf("ParseExpire: unknown unit: %s", unit)
+		}
+		return now.Add(-time.Duration(n) * d), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("ParseExpire: invalid expiry: %s", s)
+}
+
+var expireUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+}
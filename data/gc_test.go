@@ -0,0 +1,109 @@
+package data_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestPruneObjects(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tests := []struct {
+			desc       string
+			expire     func(now time.Time) time.Time
+			dryRun     bool
+			wantPruned int
+			wantKept   int
+			wantExists bool
+		}{
+			{desc: "01_pruned", expire: func(now time.Time) time.Time { return now.Add(time.Hour) }, wantPruned: 1, wantExists: false},
+			{desc: "02_kept within the grace period", expire: func(now time.Time) time.Time { return now.Add(-time.Hour) }, wantKept: 1, wantExists: true},
+			{desc: "03_dry run", expire: func(now time.Time) time.Time { return now.Add(time.Hour) }, dryRun: true, wantPruned: 1, wantExists: true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				pgitDir, commitOid, _, _ := newFsckRepo(t)
+				objDir := filepath.Join(pgitDir, data.ObjDirBase)
+				garbage, err := data.SaveBlobObj(objDir, []byte("garbage"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				reachable, err := data.ReachableObjects(objDir, []string{commitOid})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				report, err := data.PruneObjects(objDir, reachable, tt.expire(time.Now()), tt.dryRun)
+
+				if err != nil {
+					t.Errorf("should be nil: \n{ error: %s }", err)
+				}
+				if report.Objects != tt.wantPruned || report.Kept != tt.wantKept {
+					t.Errorf("report should be equal: (got: %+v)", report)
+				}
+				_, err = os.Stat(filepath.Join(objDir, garbage))
+				if (err == nil) != tt.wantExists {
+					t.Errorf("garbage existence should be %v: (error: %v)", tt.wantExists, err)
+				}
+				for oid := range reachable {
+					if _, err := os.Stat(filepath.Join(objDir, oid)); err != nil {
+						t.Errorf("reachable object should be kept: (oid: %s)", oid)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestParseExpire(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		desc    string
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{desc: "01_now", in: "now", want: now.Add(time.Second)},
+		{desc: "02_never", in: "never", want: time.Time{}},
+		{desc: "03_weeks ago", in: "2.weeks.ago", want: now.Add(-14 * 24 * time.Hour)},
+		{desc: "04_duration", in: "72h", want: now.Add(-72 * time.Hour)},
+		{desc: "05_date", in: "2025-01-01", want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{desc: "06_invalid", in: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := data.ParseExpire(tt.in, now)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: (error: %v, wantErr: %v)", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("should be equal: (got: %s, want: %s)", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), data.LockBase)
+	lock, err := data.AcquireLock(path)
+	if err != nil {
+		t.Fatalf("should be nil: \n{ error: %s }", err)
+	}
+
+	_, err = data.AcquireLock(path)
+
+	if !errors.Is(err, data.ErrLocked) {
+		t.Errorf("error should be equal: (got: %v, want: %v)", err, data.ErrLocked)
+	}
+	if err := lock.Release(); err != nil {
+		t.Errorf("should be nil: \n{ error: %s }", err)
+	}
+	if _, err := data.AcquireLock(path); err != nil {
+		t.Errorf("lock should be acquired again: \n{ error: %s }", err)
+	}
+}
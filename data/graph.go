@@ -0,0 +1,196 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Graph draws the lines of a history in ASCII beside the log entries, like this:
+// -----------------
+// *   merge
+// |\
+// | * side
+// * | main
+// |/
+// * base
+// -----------------
+// The commits must be written in an order where every commit comes before its parents (see History.Sorted).
+type Graph struct {
+	columns []string //the oids of the commits each line is heading for, from the left
+}
+
+// Write writes the text of the commit beside the graph. The first line of the text is put beside
+// the commit, and the rest beside the lines heading for its parents.
+func (g *Graph) Write(buf *bytes.Buffer, oid string, parents []string, text string) {
+	idx := slices.Index(g.columns, oid)
+	if idx < 0 {
+		g.columns = append(g.columns, oid)
+		idx = len(g.columns) - 1
+	}
+	rows := []string{g.commitRow(idx)}
+	next := g.nextColumns(idx, parents)
+	if row, ok := transitionRow(g.columns, idx, parents, next); ok {
+		rows = append(rows, row)
+	}
+	g.columns = next
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	width := 0
+	for _, row := range append(rows, g.Padding()) {
+		width = max(width, len(row))
+	}
+	for i := 0; i < len(rows) || i < len(lines); i++ {
+		row := g.Padding()
+		if i < len(rows) {
+			row = rows[i]
+		}
+		if i >= len(lines) || lines[i] == "" {
+			buf.WriteString(strings.TrimRight(row, " ") + "\n")
+			continue
+		}
+		buf.WriteString(row + strings.Repeat(" ", width-len(row)) + " " + lines[i] + "\n")
+	}
+}
+
+// returns the lines heading for the commits to come, like "| |"
+func (g *Graph) Padding() string {
+	return strings.TrimSuffix(strings.Repeat("| ", len(g.columns)), " ")
+}
+
+// returns the row of the commit in the column, like "| * |"
+func (g *Graph) commitRow(idx int) string {
+	cells := make([]string, len(g.columns))
+	for i := range cells {
+		cells[i] = "|"
+	}
+	cells[idx] = "*"
+	return strings.Join(cells, " ")
+}
+
+// returns the columns after the commit in the column idx, where the first parent takes over the column
+// and the other parents get new columns on its right. A parent that some column is already heading for
+// joins that column instead.
+func (g *Graph) nextColumns(idx int, parents []string) []string {
+	next := slices.Clone(g.columns)
+	at := idx + 1
+	if len(parents) == 0 || slices.Contains(next, parents[0]) {
+		next = slices.Delete(next, idx, idx+1)
+		at = idx
+	} else {
+		next[idx] = parents[0]
+	}
+	for _, p := range parents[min(1, len(parents)):] {
+		if !slices.Contains(next, p) {
+			next = slices.Insert(next, at, p)
+			at++
+		}
+	}
+	return next
+}
+
+// returns the row drawing the lines from the old columns to the new ones, like "|\" for a merge and
+// "|/" for a fork, or false if all the lines go straight.
+func transitionRow(old []string, idx int, parents []string, next []string) (string, bool) {
+	type edge struct{ from, to int }
+	var edges []edge
+	for i, oid := range old {
+		if i != idx {
+			edges = append(edges, edge{i, slices.Index(next, oid)})
+		}
+	}
+	for _, p := range parents {
+		edges = append(edges, edge{idx, slices.Index(next, p)})
+	}
+	cells := bytes.Repeat([]byte(" "), 2*max(len(old), len(next)))
+	straight := true
+	for _, e := range edges {
+		switch {
+		case e.to == e.from:
+			cells[2*e.from] = '|'
+		case e.to > e.from:
+			straight = false
+			cells[2*e.from+1] = '\\'
+			fillCells(cells, 2*e.from+2, 2*e.to-1)
+		default:
+			straight = false
+			cells[2*e.from-1] = '/'
+			fillCells(cells, 2*e.to+1, 2*e.from-2)
+		}
+	}
+	return strings.TrimRight(string(cells), " "), !straight
+}
+
+// draws a horizontal line in the blank cells from start to end (exclusive)
+func fillCells(cells []byte, start int, end int) {
+	for i := start; i < end; i++ {
+		if cells[i] == ' ' {
+			cells[i] = '_'
+		}
+	}
+}
+
+// the formats of the whole history graph (see WriteGraph)
+const (
+	GraphFormatDOT     = "dot"
+	GraphFormatMermaid = "mermaid"
+)
+
+// WriteGraph writes the history and the refs pointing to it as a Graphviz DOT or Mermaid flowchart,
+// where each commit points to its parents and each ref points to its commit.
+// refs is { key: ref name like "refs/heads/master" or "HEAD", value: oid }.
+func WriteGraph(buf *bytes.Buffer, format string, h *History, refs map[string]string) error {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	oids := h.Sorted()
+
+	switch format {
+	case GraphFormatDOT:
+		buf.WriteString("digraph pgit {\n")
+		buf.WriteString("\tnode [shape=box];\n")
+		for _, oid := range oids {
+			label := abbrevOid(oid) + "\n" + h.Commits[oid].Subject()
+			fmt.Fprintf(buf, "\t%q [label=%q];\n", oid, label)
+			for _, p := range h.Commits[oid].Parents() {
+				fmt.Fprintf(buf, "\t%q -> %q;\n", oid, p)
+			}
+		}
+		for _, name := range names {
+			fmt.Fprintf(buf, "\t%q [label=%q, shape=ellipse];\n", name, shortRefName(name))
+			fmt.Fprintf(buf, "\t%q -> %q [style=dashed];\n", name, refs[name])
+		}
+		buf.WriteString("}\n")
+	case GraphFormatMermaid:
+		buf.WriteString("flowchart TD\n")
+		for _, oid := range oids {
+			fmt.Fprintf(buf, "\tc%s[\"%s %s\"]\n", oid, abbrevOid(oid), mermaidEscape(h.Commits[oid].Subject()))
+			for _, p := range h.Commits[oid].Parents() {
+				fmt.Fprintf(buf, "\tc%s --> c%s\n", oid, p)
+			}
+		}
+		for i, name := range names {
+			fmt.Fprintf(buf, "\tref%d([\"%s\"]) -.-> c%s\n", i, mermaidEscape(shortRefName(name)), refs[name])
+		}
+	default:
+		return fmt.Errorf("WriteGraph: %w: %s", ErrUnknownFormat, format)
+	}
+	return nil
+}
+
+// returns the name of a ref without "refs/heads/" or "refs/" like "master" and "tags/v1.0"
+func shortRefName(name string) string {
+	if s, ok := strings.CutPrefix(name, "refs/heads/"); ok {
+		return s
+	}
+	return strings.TrimPrefix(name, "refs/")
+}
+
+// escapes the characters breaking a quoted label of Mermaid
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s)
+}
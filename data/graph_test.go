@@ -0,0 +1,109 @@
+package data_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestGraph(t *testing.T) {
+	tests := []struct {
+		desc    string
+		commits [][]string //oid followed by its parents
+		want    string
+	}{
+		{
+			desc:    "01_linear",
+			commits: [][]string{{"c", "b"}, {"b", "a"}, {"a"}},
+			want:    "* c\n* b\n* a\n",
+		},
+		{
+			desc:    "02_merge",
+			commits: [][]string{{"m", "p", "s"}, {"s", "base"}, {"p", "base"}, {"base"}},
+			want:    "*   m\n|\\\n| * s\n* | p\n|/\n* base\n",
+		},
+		{
+			desc:    "03_forks",
+			commits: [][]string{{"x", "base"}, {"y", "base"}, {"z", "base"}, {"base"}},
+			want:    "* x\n| * y\n|/\n| * z\n|/\n* base\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var g data.Graph
+			var buf bytes.Buffer
+
+			for _, c := range tt.commits {
+				g.Write(&buf, c[0], c[1:], c[0])
+			}
+
+			CmpStructs(t, buf.String(), tt.want)
+		})
+	}
+	t.Run("the rest of the text is put beside the lines", func(t *testing.T) {
+		var g data.Graph
+		var buf bytes.Buffer
+
+		g.Write(&buf, "m", []string{"p", "s"}, "merge\nAuthor: a\n\n    message\n")
+
+		CmpStructs(t, buf.String(), "*   merge\n|\\  Author: a\n| |\n| |     message\n")
+	})
+}
+
+func TestWriteGraph(t *testing.T) {
+	tmpDir := filepath.Join(data.PgitDirBase, data.ObjDirBase)
+	if err := os.MkdirAll(tmpDir, os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(tmpDir)) })
+	when := time.Unix(1700000000, 0)
+	commits := map[string]*data.Commit{
+		"1111111111": {TreeOid: "t", Committer: data.NewSignature("a", "", when), Msg: "first"},
+		"2222222222": {TreeOid: "t", Parent: "1111111111", Committer: data.NewSignature("a", "", when.Add(time.Hour)), Msg: `say "hi"`},
+	}
+	for oid, c := range commits {
+		if err := data.WriteFile(filepath.Join(tmpDir, oid), c.Encode()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := data.LoadHistory("2222222222")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := map[string]string{"HEAD": "2222222222", "refs/tags/v1": "1111111111"}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: data.GraphFormatDOT,
+			want: "digraph pgit {\n\tnode [shape=box];\n" +
+				"\t\"2222222222\" [label=\"2222222\\nsay \\\"hi\\\"\"];\n\t\"2222222222\" -> \"1111111111\";\n" +
+				"\t\"1111111111\" [label=\"1111111\\nfirst\"];\n" +
+				"\t\"HEAD\" [label=\"HEAD\", shape=ellipse];\n\t\"HEAD\" -> \"2222222222\" [style=dashed];\n" +
+				"\t\"refs/tags/v1\" [label=\"tags/v1\", shape=ellipse];\n\t\"refs/tags/v1\" -> \"1111111111\" [style=dashed];\n}\n",
+		},
+		{
+			format: data.GraphFormatMermaid,
+			want: "flowchart TD\n\tc2222222222[\"2222222 say #quot;hi#quot;\"]\n\tc2222222222 --> c1111111111\n" +
+				"\tc1111111111[\"1111111 first\"]\n" +
+				"\tref0([\"HEAD\"]) -.-> c2222222222\n\tref1([\"tags/v1\"]) -.-> c1111111111\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := data.WriteGraph(&buf, tt.format, h, refs)
+
+			if err != nil {
+				t.Errorf("error should be nil:\nerror:%s\n", err)
+			}
+			CmpStructs(t, buf.String(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	LineContext = ' '
+	LineDelete  = '-'
+	LineInsert  = '+'
+)
+
+// the marker following a line that has no "\n" at the end of a file
+const NoNewlineMarker = `\ No newline at end of file`
+
+// the abbreviated oid of a missing side in a patch
+const zeroAbbrev = "0000000"
+
+// diffLine is a line of a diff. Text keeps its trailing "\n" unless it is the last line of a
+// file without a newline at the end.
+type diffLine struct {
+	Op   byte //LineContext, LineDelete or LineInsert
+	Text string
+}
+
+// Hunk is a group of changes together with the unchanged lines around them, represented in a
+// unified diff like this:
+// -----------------
+// @@ -1,3 +1,3 @@
+// unchanged
+// -deleted
+// +inserted
+// -----------------
+// A start is the line number where the hunk begins, or the line before it when the hunk has no
+// lines on that side (e.g. "-0,0" for an empty file).
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []string //each begins with LineContext, LineDelete or LineInsert and keeps its "\n" if any
+}
+
+func (h *Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+// the count is omitted when it is 1, following the notation of diff(1)
+func hunkRange(start int, n int) string {
+	if n == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// splits the text into lines, each of which keeps its trailing "\n".
+// Unlike strings.Split, no empty line is produced after the last "\n".
+func splitLines(text string) []string {
+	var lines []string
+	for text != "" {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:i+1])
+		text = text[i+1:]
+	}
+	return lines
+}
+
+// DiffHunks compares the contents line by line and returns the hunks of the changes with up to
+// context unchanged lines around them. No hunk is returned for the same contents.
+func DiffHunks(from []byte, to []byte, context int) []*Hunk {
+	o := NewDiffOptions()
+	o.Context = context
+	return o.DiffHunks(from, to)
+}
+
+// the same as DiffHunks, but following the options
+func (o *DiffOptions) DiffHunks(from []byte, to []byte) []*Hunk {
+	return o.makeHunks(o.diffLines(from, to))
+}
+
+// groups the changed lines into hunks with up to o.Context unchanged lines around them.
+// Changes separated by no more than 2*o.Context unchanged lines share a hunk. Changes that are
+// ignorable (see isIgnorable) never make a hunk by themselves, but are shown within others.
+func (o *DiffOptions) makeHunks(lines []diffLine) []*Hunk {
+	context := max(o.Context, 0)
+	quiet := func(i int) bool {
+		return lines[i].Op == LineContext || o.isIgnorable(lines[i])
+	}
+	//line numbers of each line on both sides, with one more for the end
+	oldAt := make([]int, len(lines)+1)
+	newAt := make([]int, len(lines)+1)
+	oldNo, newNo := 1, 1
+	for i, l := range lines {
+		oldAt[i], newAt[i] = oldNo, newNo
+		if l.Op != LineInsert {
+			oldNo++
+		}
+		if l.Op != LineDelete {
+			newNo++
+		}
+	}
+	oldAt[len(lines)], newAt[len(lines)] = oldNo, newNo
+
+	var hunks []*Hunk
+	for i := 0; i < len(lines); {
+		if quiet(i) {
+			i++
+			continue
+		}
+		start := max(0, i-context)
+		end := i
+		for {
+			for end < len(lines) && !quiet(end) {
+				end++
+			}
+			next := end
+			for next < len(lines) && quiet(next) {
+				next++
+			}
+			if next < len(lines) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end = min(end+context, next)
+			break
+		}
+		h := &Hunk{
+			OldStart: oldAt[start],
+			OldLines: oldAt[end] - oldAt[start],
+			NewStart: newAt[start],
+			NewLines: newAt[end] - newAt[start],
+		}
+		if h.OldLines == 0 {
+			h.OldStart--
+		}
+		if h.NewLines == 0 {
+			h.NewStart--
+		}
+		for _, l := range lines[start:end] {
+			h.Lines = append(h.Lines, string(l.Op)+l.Text)
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+// writes the hunks in the unified format, marking a line without "\n" with NoNewlineMarker
+func WriteHunks(buf *bytes.Buffer, hunks []*Hunk) {
+	(&DiffOptions{}).WriteHunks(buf, hunks)
+}
+
+// the same as WriteHunks, but coloring the lines if o.Color is set, or writing word diffs if o.WordDiff is set
+func (o *DiffOptions) WriteHunks(buf *bytes.Buffer, hunks []*Hunk) {
+	if o.WordDiff != WordDiffNone {
+		o.writeWordDiff(buf, hunks)
+		return
+	}
+	for _, h := range hunks {
+		buf.WriteString(o.Paint(ColorFrag, h.Header()))
+		buf.WriteString("\n")
+		for _, line := range h.Lines {
+			switch line[0] {
+			case LineDelete:
+				buf.WriteString(o.Paint(ColorOld, line))
+			case LineInsert:
+				buf.WriteString(o.Paint(ColorNew, line))
+			default:
+				buf.WriteString(line)
+			}
+			if !strings.HasSuffix(line, "\n") {
+				buf.WriteString("\n" + NoNewlineMarker + "\n")
+			}
+		}
+	}
+}
+
+// returns the first 7 characters of the oid, as used in patches
+func abbrevOid(oid string) string {
+	if len(oid) > len(zeroAbbrev) {
+		return oid[:len(zeroAbbrev)]
+	}
+	return oid
+}
@@ -0,0 +1,77 @@
+package data_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestDiffHunks(t *testing.T) {
+	tests := []struct {
+		desc    string
+		from    string
+		to      string
+		context int
+		want    string
+	}{
+		{
+			desc:    "01_same contents",
+			from:    "a\nb\n",
+			to:      "a\nb\n",
+			context: 3,
+			want:    "",
+		},
+		{
+			desc:    "02_context around a change",
+			from:    "1\n2\n3\n4\n5\n6\n7\n",
+			to:      "1\n2\n3\nfour\n5\n6\n7\n",
+			context: 1,
+			want:    "@@ -3,3 +3,3 @@\n 3\n-4\n+four\n 5\n",
+		},
+		{
+			desc:    "03_distant changes make separate hunks",
+			from:    "1\n2\n3\n4\n5\n6\n7\n",
+			to:      "one\n2\n3\n4\n5\n6\nseven\n",
+			context: 1,
+			want:    "@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -6,2 +6,2 @@\n 6\n-7\n+seven\n",
+		},
+		{
+			desc:    "04_close changes share a hunk",
+			from:    "1\n2\n3\n4\n5\n",
+			to:      "one\n2\n3\nfour\n5\n",
+			context: 1,
+			want:    "@@ -1,5 +1,5 @@\n-1\n+one\n 2\n 3\n-4\n+four\n 5\n",
+		},
+		{
+			desc:    "05_added to an empty file",
+			from:    "",
+			to:      "a\nb\n",
+			context: 3,
+			want:    "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			desc:    "06_newline added at the end",
+			from:    "a\nb",
+			to:      "a\nb\n",
+			context: 3,
+			want:    "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			desc:    "07_no context",
+			from:    "1\n2\n3\n",
+			to:      "1\n3\n",
+			context: 0,
+			want:    "@@ -2 +1,0 @@\n-2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			hunks := data.DiffHunks([]byte(tt.from), []byte(tt.to), tt.context)
+
+			var buf bytes.Buffer
+			data.WriteHunks(&buf, hunks)
+			CmpStructs(t, buf.String(), tt.want)
+		})
+	}
+}
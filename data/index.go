@@ -0,0 +1,285 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const IndexBase = "index"
+
+// ".pgit/index"
+var IndexPath = filepath.Join(PgitDirBase, IndexBase)
+
+// Index is the staging area between the working tree and the next commit, which is a flat list of
+// the files to be committed. { key: slash-separated path, value: blob oid }
+// It is saved with the same line format as trees, but every line is a blob with its full path:
+// -----------------
+// blob {oid} {path}
+// -----------------
+type Index map[string]string
+
+// Reads the index in the path. Returns nil with no error if there is no index, in which case
+// the index is regarded as the same as the tree of HEAD.
+func ReadIndex(path string) (Index, error) {
+	c, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ReadIndex: %w", err)
+	}
+	idx := make(Index)
+	sc := bufio.NewScanner(bytes.NewReader(c))
+	sc.Split(bufio.ScanLines)
+	for sc.Scan() {
+		objType, oid, path, err := parseTreeLine(sc.Text())
+		if err != nil || objType != ObjTypeBlob {
+			return nil, fmt.Errorf("ReadIndex: %w: %q", ErrMalformedTree, sc.Text())
+		}
+		idx[path] = oid
+	}
+	return idx, nil
+}
+
+// writes the index into the path, sorted by path
+func (idx Index) Write(path string) error {
+	var buf bytes.Buffer
+	for _, p := range idx.Paths() {
+		fmt.Fprintf(&buf, "%s %s %s\n", ObjTypeBlob, idx[p], p)
+	}
+	if err := WriteFile(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("Index.Write: %w", err)
+	}
+	return nil
+}
+
+// returns the paths in the index in sorted order
+func (idx Index) Paths() []string {
+	paths := make([]string, 0, len(idx))
+	for p := range idx {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Converts a tree into an index, taking every file in it recursively.
+func IndexFromTree(tree Tree) Index {
+	idx := make(Index)
+	WalkTree(tree, func(path string, elem *TreeElem) error {
+		if elem.ObjType == ObjTypeBlob {
+			idx[path] = elem.Oid
+		}
+		return nil
+	})
+	return idx
+}
+
+// Converts the index into a tree. Subtrees are not saved in the object storage, so their oids are empty.
+func (idx Index) Tree() Tree {
+	tree := make(Tree)
+	for p, oid := range idx {
+		addTreePath(tree, p, oid)
+	}
+	return tree
+}
+
+// WriteTree saves the index as trees in the object storage (= objDirPath), a tree for each directory
+// as WriteTree of the working tree does, and returns the oid of the root tree.
+// The blobs are expected to be saved already by Add.
+func (idx Index) WriteTree(objDirPath string) (treeOid string, err error) {
+	treeOid, err = saveIndexTree(idx.Tree(), objDirPath)
+	if err != nil {
+		return "", fmt.Errorf("Index.WriteTree: %w", err)
+	}
+	return treeOid, nil
+}
+
+// saves the tree and its subtrees, sorted by name, and fills the oids of the subtrees
+func saveIndexTree(tree Tree, objDirPath string) (string, error) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		elem := tree[name]
+		if elem.ObjType == ObjTypeTree {
+			oid, err := saveIndexTree(elem.Child, objDirPath)
+			if err != nil {
+				return "", err
+			}
+			elem.Oid = oid
+		}
+		fmt.Fprintf(&buf, "%s %s %s\n", elem.ObjType, elem.Oid, name)
+	}
+	return SaveBlobObj(objDirPath, buf.Bytes())
+}
+
+// puts a blob into the tree at the slash-separated path, creating the subtrees on the way
+func addTreePath(tree Tree, path string, oid string) {
+	dir, rest, found := strings.Cut(path, "/")
+	if !found {
+		tree[path] = &TreeElem{ObjType: ObjTypeBlob, Oid: oid, Name: path}
+		return
+	}
+	elem, ok := tree[dir]
+	if !ok || elem.ObjType != ObjTypeTree {
+		elem = &TreeElem{ObjType: ObjTypeTree, Name: dir, Child: make(Tree)}
+		tree[dir] = elem
+	}
+	addTreePath(elem.Child, rest, oid)
+}
+
+// Returns the tree of the files in the working tree (= rootPath) that are in the index, so that
+// untracked files are left out. A file missing in the working tree is missing in the tree as well.
+// The oids are computed without saving the files in the object storage, and the files stored
+// in the large file store by the attributes get the oids of their pointers as Add does.
+func (idx Index) WorkingTree(rootPath string, attrs Attributes) (Tree, error) {
+	tree := make(Tree)
+	for _, p := range idx.Paths() {
+		f, err := os.Open(filepath.Join(rootPath, filepath.FromSlash(p)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("Index.WorkingTree: %w", err)
+		}
+		fi, err := f.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			f.Close()
+			continue
+		}
+		oid, err := workingFileOid(f, attrs.IsLFS(p))
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Index.WorkingTree: %w", err)
+		}
+		addTreePath(tree, p, oid)
+	}
+	return tree, nil
+}
+
+// issues the oid of the blob saveFileObj would save for the file, which is its pointer if lfs is true
+func workingFileOid(f *os.File, lfs bool) (string, error) {
+	if !lfs {
+		return IssueObjIDFrom(newBlobHeaderReader(f))
+	}
+	p, err := NewLFSPointerFrom(f)
+	if err != nil {
+		return "", fmt.Errorf("workingFileOid: %w", err)
+	}
+	return IssueObjIDFrom(newBlobHeaderReader(bytes.NewReader(p.Encode())))
+}
+
+// Returns the files in the working tree (= rootPath) that are not in the index, in sorted order.
+func (idx Index) Untracked(rootPath string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(rootPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isExcluded(d.Name()) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(rootPath, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if _, ok := idx[rel]; !ok {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Index.Untracked: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Stages the file or the directory in the path (relative to the root of the working tree), saving
+// the files in the object storage (= objDirPath). A path missing in the working tree is removed from
+// the index together with everything under it, and so are the files missing under a directory.
+func (idx Index) Add(objDirPath string, path string, attrs Attributes) error {
+	relPath := filepath.ToSlash(filepath.Clean(path))
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		found := false
+		for p := range idx {
+			if MatchPathspec(p, relPath) {
+				delete(idx, p)
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("Index.Add: pathspec did not match any files: %s", path)
+		}
+		return nil
+	}
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isExcluded(d.Name()) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel := filepath.ToSlash(filepath.Clean(p))
+		oid, err := saveFileObj(p, objDirPath, attrs.IsLFS(rel))
+		if err != nil {
+			return err
+		}
+		idx[rel] = oid
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Index.Add: %w", err)
+	}
+	//the files deleted under the directory are staged as deletions
+	for p := range idx {
+		if !MatchPathspec(p, relPath) {
+			continue
+		}
+		if _, err := os.Stat(filepath.FromSlash(p)); os.IsNotExist(err) {
+			delete(idx, p)
+		}
+	}
+	return nil
+}
+
+// Reports whether the slash-separated path is matched by the pathspec. A pathspec matches the path
+// itself, the files under it as a directory, or the path as a glob pattern. "." matches everything.
+func MatchPathspec(path string, spec string) bool {
+	spec = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(spec)), "/")
+	if spec == "." || spec == path || strings.HasPrefix(path, spec+"/") {
+		return true
+	}
+	ok, _ := filepath.Match(spec, path)
+	return ok
+}
+
+// reports whether any of the pathspecs matches the path. No pathspec matches every path.
+func MatchPathspecs(path string, specs []string) bool {
+	if len(specs) == 0 {
+		return true
+	}
+	for _, spec := range specs {
+		if MatchPathspec(path, spec) {
+			return true
+		}
+	}
+	return false
+}
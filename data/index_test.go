@@ -0,0 +1,105 @@
+package data_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestReadIndex(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "index")
+		idx := data.Index{"dir/sub/file_02": "oid_02", "file_01": "oid_01", "file 03": "oid_03"}
+		if err := idx.Write(path); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := data.ReadIndex(path)
+
+		if err != nil {
+			t.Errorf("error should be nil:\nerror:%s\n", err)
+		}
+		CmpStructs(t, got, idx)
+		CmpStructs(t, data.IndexFromTree(got.Tree()), idx)
+	})
+	t.Run("no index", func(t *testing.T) {
+		got, err := data.ReadIndex(filepath.Join(t.TempDir(), "nothing"))
+
+		if err != nil {
+			t.Errorf("error should be nil:\nerror:%s\n", err)
+		}
+		if got != nil {
+			t.Errorf("index should be nil: %v", got)
+		}
+	})
+	t.Run("fail", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "index")
+		if err := data.WriteFile(path, []byte("tree oid dir\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := data.ReadIndex(path)
+
+		if err == nil {
+			t.Errorf("error should not be nil")
+		}
+	})
+}
+
+func TestIndexWriteTree(t *testing.T) {
+	srcDir := t.TempDir()
+	objDir := t.TempDir()
+	for path, content := range map[string]string{"file_01": "01\n", "dir/file_02": "02\n", "dir/sub/file 03": "03\n", "dir.txt": "04\n"} {
+		path = filepath.Join(srcDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := data.WriteFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := data.WriteTree(srcDir, objDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := data.ParseTreeFile(filepath.Join(objDir, want))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := data.IndexFromTree(tree).WriteTree(objDir)
+
+	if err != nil {
+		t.Errorf("error should be nil:\nerror:%s\n", err)
+	}
+	if got != want {
+		t.Errorf("tree oid should be the same as the one of the working tree: (got: %s, want: %s)", got, want)
+	}
+}
+
+func TestMatchPathspec(t *testing.T) {
+	tests := []struct {
+		desc string
+		path string
+		spec string
+		want bool
+	}{
+		{desc: "01_same path", path: "dir/file", spec: "dir/file", want: true},
+		{desc: "02_directory", path: "dir/sub/file", spec: "dir/", want: true},
+		{desc: "03_glob", path: "dir/file.go", spec: "dir/*.go", want: true},
+		{desc: "04_everything", path: "dir/file", spec: ".", want: true},
+		{desc: "05_prefix of a name", path: "dirs/file", spec: "dir", want: false},
+		{desc: "06_glob in another directory", path: "sub/file.go", spec: "*.go", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := data.MatchPathspec(tt.path, tt.spec)
+
+			if got != tt.want {
+				t.Errorf("should be equal: { got: %v, want: %v }", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	LFSDirBase = "lfs"
+
+	LFSPointerVersion = "version https://pgit/lfs/v1"
+	//pointer blobs are never larger than this size
+	LFSPointerMaxSize = 200
+)
+
+// ".pgit/lfs/objects"
+var LFSObjPath = filepath.Join(PgitDirBase, LFSDirBase, ObjDirBase)
+
+// LFSPointer is stored in a tree in place of a large file, and the real content lives in the
+// large file store (= .pgit/lfs/objects/{Oid}). The pointer is encoded like this:
+// -----------------
+// version https://pgit/lfs/v1
+// oid sha256:{Oid}
+// size {Size}
+// -----------------
+type LFSPointer struct {
+	Oid  string //sha256 of the content
+	Size int64
+}
+
+func (p *LFSPointer) Encode() []byte {
+	return fmt.Appendf(nil, "%s\noid sha256:%s\nsize %d\n", LFSPointerVersion, p.Oid, p.Size)
+}
+
+// Parses the content of a blob as a pointer. If the content is not a pointer, ok is false.
+func ParseLFSPointer(content []byte) (p *LFSPointer, ok bool) {
+	if len(content) > LFSPointerMaxSize || !bytes.HasPrefix(content, []byte(LFSPointerVersion+"\n")) {
+		return nil, false
+	}
+	p = &LFSPointer{}
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Split(bufio.ScanLines)
+	for sc.Scan() {
+		key, value, _ := strings.Cut(sc.Text(), " ")
+		switch key {
+		case "oid":
+			p.Oid = strings.TrimPrefix(value, "sha256:")
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, false
+			}
+			p.Size = size
+		}
+	}
+	if len(p.Oid) != sha256.Size*2 {
+		return nil, false
+	}
+	return p, true
+}
+
+// returns the large file store next to the object storage
+// e.g. { objDirPath: .pgit/objects, storeDir: .pgit/lfs/objects }
+func lfsStoreDir(objDirPath string) string {
+	return filepath.Join(filepath.Dir(objDirPath), LFSDirBase, ObjDirBase)
+}
+
+// Saves the content of r in the large file store (= storeDir/{sha256}) and returns a pointer to it.
+// The content is streamed, so that it is never held in memory as a whole.
+func SaveLFSObj(storeDir string, r io.Reader) (*LFSPointer, error) {
+	if err := os.MkdirAll(storeDir, os.ModeDir|0755); err != nil {
+		return nil, fmt.Errorf("SaveLFSObj: %w", err)
+	}
+	tmp, err := os.CreateTemp(storeDir, "tmp_lfs_")
+	if err != nil {
+		return nil, fmt.Errorf("SaveLFSObj: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("SaveLFSObj: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("SaveLFSObj: %w", err)
+	}
+	p := &LFSPointer{Oid: hex.EncodeToString(h.Sum(nil)), Size: size}
+	if err := os.Rename(tmp.Name(), filepath.Join(storeDir, p.Oid)); err != nil {
+		return nil, fmt.Errorf("SaveLFSObj: %w", err)
+	}
+	return p, nil
+}
+
+// Makes the pointer to the content of r without saving it, which is the same as SaveLFSObj returns.
+func NewLFSPointerFrom(r io.Reader) (*LFSPointer, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return nil, fmt.Errorf("NewLFSPointerFrom: %w", err)
+	}
+	return &LFSPointer{Oid: hex.EncodeToString(h.Sum(nil)), Size: size}, nil
+}
+
+// returns the path of the real content of a blob (= objDirPath/{oid}) in the large file store
+// if the blob is a pointer and the content exists locally, or the path of the blob itself otherwise
+func smudgedPath(objDirPath string, oid string) string {
+	p, ok, err := ReadLFSPointer(objDirPath, oid)
+	if err == nil && ok {
+		storeDir := lfsStoreDir(objDirPath)
+		if HasLFSObj(storeDir, p) {
+			return filepath.Join(storeDir, p.Oid)
+		}
+	}
+	return filepath.Join(objDirPath, oid)
+}
+
+// reports whether the content of the pointer exists in the large file store
+func HasLFSObj(storeDir string, p *LFSPointer) bool {
+	fi, err := os.Stat(filepath.Join(storeDir, p.Oid))
+	return err == nil && fi.Size() == p.Size
+}
+
+// Copies the content of the pointer from srcStore to trgStore, verifying its sha256 on the way.
+// Nothing is copied if trgStore already has it.
+func CopyLFSObj(srcStore string, trgStore string, p *LFSPointer) (copied bool, err error) {
+	if HasLFSObj(trgStore, p) {
+		return false, nil
+	}
+	f, err := os.Open(filepath.Join(srcStore, p.Oid))
+	if err != nil {
+		return false, fmt.Errorf("CopyLFSObj: %w", err)
+	}
+	defer f.Close()
+	saved, err := SaveLFSObj(trgStore, f)
+	if err != nil {
+		return false, fmt.Errorf("CopyLFSObj: %w", err)
+	}
+	if saved.Oid != p.Oid {
+		os.Remove(filepath.Join(trgStore, saved.Oid))
+		return false, fmt.Errorf("CopyLFSObj: corrupt object: { want: %s, got: %s }", p.Oid, saved.Oid)
+	}
+	return true, nil
+}
+
+// Reads a blob (= objDirPath/{oid}) and returns a pointer if the blob is one.
+func ReadLFSPointer(objDirPath string, oid string) (p *LFSPointer, ok bool, err error) {
+	path := filepath.Join(objDirPath, oid)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("ReadLFSPointer: %w", err)
+	}
+	if fi.Size() > LFSPointerMaxSize {
+		return nil, false, nil
+	}
+	c, err := ReadAllFileContent(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("ReadLFSPointer: %w", err)
+	}
+	p, ok = ParseLFSPointer(c)
+	return p, ok, nil
+}
+
+// Walks the tree (= objDirPath/{treeOid}) recursively and collects all the pointers in it.
+// { key: slash-separated path, value: pointer }
+func CollectLFSPointers(objDirPath string, treeOid string) (map[string]*LFSPointer, error) {
+	tree, err := ParseTreeFile(filepath.Join(objDirPath, treeOid))
+	if err != nil {
+		return nil, fmt.Errorf("CollectLFSPointers: %w", err)
+	}
+	pointers := make(map[string]*LFSPointer)
+	err = WalkTree(tree, func(path string, elem *TreeElem) error {
+		if elem.ObjType != ObjTypeBlob {
+			return nil
+		}
+		p, ok, err := ReadLFSPointer(objDirPath, elem.Oid)
+		if err != nil {
+			return err
+		}
+		if ok {
+			pointers[path] = p
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CollectLFSPointers: %w", err)
+	}
+	return pointers, nil
+}
+
+// writes the content of a blob into w. If the blob is a pointer and its content exists in the
+// large file store, the real content is written instead (= smudge).
+func smudgeObject(w io.Writer, objDirPath string, oid string) error {
+	r, err := OpenObject(objDirPath, oid)
+	if err != nil {
+		return fmt.Errorf("smudgeObject: %w", err)
+	}
+	defer r.Close()
+	br := bufio.NewReaderSize(r, LFSPointerMaxSize+1)
+	head, err := br.Peek(LFSPointerMaxSize + 1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("smudgeObject: %w", err)
+	}
+	if p, ok := ParseLFSPointer(head); ok {
+		storeDir := lfsStoreDir(objDirPath)
+		if HasLFSObj(storeDir, p) {
+			f, err := os.Open(filepath.Join(storeDir, p.Oid))
+			if err != nil {
+				return fmt.Errorf("smudgeObject: %w", err)
+			}
+			defer f.Close()
+			if _, err := io.Copy(w, f); err != nil {
+				return fmt.Errorf("smudgeObject: %w", err)
+			}
+			return nil
+		}
+	}
+	if _, err := io.Copy(w, br); err != nil {
+		return fmt.Errorf("smudgeObject: %w", err)
+	}
+	return nil
+}
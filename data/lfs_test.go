@@ -0,0 +1,82 @@
+package data_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestParseLFSPointer(t *testing.T) {
+	p := &data.LFSPointer{Oid: strings.Repeat("ab", 32), Size: 1024}
+	tests := []struct {
+		desc    string
+		content []byte
+		want    *data.LFSPointer
+		wantOk  bool
+	}{
+		{desc: "01_encoded pointer", content: p.Encode(), want: p, wantOk: true},
+		{desc: "02_ordinary content", content: []byte("this is not a pointer\n"), want: nil, wantOk: false},
+		{desc: "03_broken oid", content: []byte(data.LFSPointerVersion + "\noid sha256:xyz\nsize 3\n"), want: nil, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, ok := data.ParseLFSPointer(tt.content)
+
+			if ok != tt.wantOk {
+				t.Errorf("ok should be equal: (got: %v, want: %v)", ok, tt.wantOk)
+			}
+			CmpStructs(t, got, tt.want)
+		})
+	}
+}
+
+func TestWriteTreeWithLFS(t *testing.T) {
+	srcDir := t.TempDir()
+	pgitDir := t.TempDir()
+	objDir := filepath.Join(pgitDir, data.ObjDirBase)
+	trgDir := t.TempDir()
+	if err := os.MkdirAll(objDir, os.ModeDir|0755); err != nil {
+		t.Fatal(err)
+	}
+	bigContent := []byte(strings.Repeat("binary asset ", 100))
+	files := map[string][]byte{
+		data.AttributesFile:     []byte("*.bin filter=lfs -diff\n"),
+		"readme.txt":            []byte("hello"),
+		"assets/image.bin":      bigContent,
+		"assets/nested/doc.txt": []byte("nested"),
+	}
+	for name, content := range files {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModeDir|0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := data.WriteFile(path, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	treeOid, err := data.WriteTree(srcDir, objDir)
+
+	if err != nil {
+		t.Fatalf("should be nil: \n{ error: %s }", err)
+	}
+	pointers, err := data.CollectLFSPointers(objDir, treeOid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := pointers["assets/image.bin"]
+	if !ok || len(pointers) != 1 {
+		t.Fatalf("only assets/image.bin should be a pointer: (got: %v)", pointers)
+	}
+	CmpFileContent(t, filepath.Join(pgitDir, data.LFSDirBase, data.ObjDirBase, p.Oid), bigContent)
+
+	if err := data.ReadTree(treeOid, objDir, trgDir); err != nil {
+		t.Fatalf("should be nil: \n{ error: %s }", err)
+	}
+	for name, content := range files {
+		CmpFileContent(t, filepath.Join(trgDir, name), content)
+	}
+}
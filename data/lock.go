@@ -0,0 +1,43 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const LockBase = "pgit.lock"
+
+// ".pgit/pgit.lock"
+var LockPath = filepath.Join(PgitDirBase, LockBase)
+
+var ErrLocked = errors.New("repository is locked by another pgit process")
+
+// Lock is a repository-wide lock held as a file. Only one process can create the file at a time.
+type Lock struct {
+	Path string
+}
+
+// Takes the lock by creating the lock file exclusively. If another process holds it, ErrLocked is returned.
+// A lock file left behind by a crashed process has to be removed by hand.
+func AcquireLock(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("AcquireLock: %w (remove %s if no pgit process is running)", ErrLocked, path)
+		}
+		return nil, fmt.Errorf("AcquireLock: %w", err)
+	}
+	defer f.Close()
+	f.WriteString(strconv.Itoa(os.Getpid()))
+	return &Lock{Path: path}, nil
+}
+
+func (l *Lock) Release() error {
+	if err := os.Remove(l.Path); err != nil {
+		return fmt.Errorf("Lock Release: %w", err)
+	}
+	return nil
+}
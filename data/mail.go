@@ -0,0 +1,150 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// the fixed date of the "From " line separating the mails, which tells that the mailbox is made of patches
+const mboxMagicDate = "Mon Sep 17 00:00:00 2001"
+
+var ErrMalformedMail = errors.New("malformed mail")
+
+var (
+	// "From {commit oid} Mon Sep 17 00:00:00 2001"
+	mboxSeparatorRegex = regexp.MustCompile(`(?m)^From [0-9a-f]{40} ` + mboxMagicDate + `\n`)
+	// "[PATCH]", "[PATCH 1/3]", "[PATCH v2 1/3]" and so on
+	patchPrefixRegex = regexp.MustCompile(`^(?:\[[^\]]*\]\s*)+`)
+)
+
+// Mail is a commit written as an email of a mailbox (= mbox) like this:
+// -----------------
+// From {commit oid} Mon Sep 17 00:00:00 2001
+// From: Taro Pgit <taro@example.com>
+// Date: Tue, 14 Nov 2023 22:13:20 +0000
+// Subject: [PATCH 1/2] {subject}
+//
+// {body}
+// ---
+// {diffstat}
+//
+// {patches}
+// --
+// pgit
+// -----------------
+type Mail struct {
+	Oid     string //the commit the mail is made from
+	Author  Signature
+	Subject string //without the "[PATCH n/m]" prefix
+	Body    string //the message after the subject
+	Patch   string //everything between "---" and the signature, that is, the diffstat and the patches
+}
+
+// returns the commit message of the mail
+func (m *Mail) Message() string {
+	if m.Body == "" {
+		return m.Subject
+	}
+	return m.Subject + "\n\n" + m.Body
+}
+
+// Write writes the mail as the n-th of the total patches. A single patch is marked just "[PATCH]".
+func (m *Mail) Write(buf *bytes.Buffer, n int, total int) {
+	prefix := "[PATCH]"
+	if total > 1 {
+		prefix = fmt.Sprintf("[PATCH %d/%d]", n, total)
+	}
+	fmt.Fprintf(buf, "From %s %s\n", m.Oid, mboxMagicDate)
+	fmt.Fprintf(buf, "From: %s <%s>\n", mime.QEncoding.Encode("utf-8", m.Author.Name), m.Author.Email)
+	fmt.Fprintf(buf, "Date: %s\n", m.Author.When.Format(time.RFC1123Z))
+	fmt.Fprintf(buf, "Subject: %s\n", mime.QEncoding.Encode("utf-8", prefix+" "+m.Subject))
+	buf.WriteString("\n")
+	if m.Body != "" {
+		buf.WriteString(strings.TrimRight(m.Body, "\n") + "\n")
+	}
+	buf.WriteString("---\n")
+	buf.WriteString(m.Patch)
+	buf.WriteString("-- \npgit\n\n")
+}
+
+// ParseMailbox splits the content into the mails separated by "From {oid} Mon Sep 17 00:00:00 2001",
+// and parses each of them. Content without the separators is parsed as a single mail.
+func ParseMailbox(content []byte) ([]*Mail, error) {
+	locs := mboxSeparatorRegex.FindAllIndex(content, -1)
+	if len(locs) == 0 {
+		m, err := parseMail("", content)
+		if err != nil {
+			return nil, fmt.Errorf("ParseMailbox: %w", err)
+		}
+		return []*Mail{m}, nil
+	}
+	mails := make([]*Mail, 0, len(locs))
+	for i, loc := range locs {
+		end := len(content)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		oid := strings.Fields(string(content[loc[0]:loc[1]]))[1]
+		m, err := parseMail(oid, content[loc[1]:end])
+		if err != nil {
+			return nil, fmt.Errorf("ParseMailbox: %w", err)
+		}
+		mails = append(mails, m)
+	}
+	return mails, nil
+}
+
+// parses the headers and the body of a mail following its "From " line
+func parseMail(oid string, content []byte) (*Mail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMail, err)
+	}
+	m := &Mail{Oid: oid}
+	dec := new(mime.WordDecoder)
+	from, err := dec.DecodeHeader(msg.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: From: %w", ErrMalformedMail, err)
+	}
+	open, closing := strings.LastIndex(from, "<"), strings.LastIndex(from, ">")
+	if open < 0 || closing < open {
+		return nil, fmt.Errorf("%w: From: %q", ErrMalformedMail, from)
+	}
+	m.Author.Name = strings.Trim(strings.TrimSpace(from[:open]), `"`)
+	m.Author.Email = from[open+1 : closing]
+	if m.Author.When, err = msg.Header.Date(); err != nil {
+		return nil, fmt.Errorf("%w: Date: %w", ErrMalformedMail, err)
+	}
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: Subject: %w", ErrMalformedMail, err)
+	}
+	m.Subject = patchPrefixRegex.ReplaceAllString(subject, "")
+
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMail, err)
+	}
+	text := "\n" + string(body)
+	message, patch, ok := strings.Cut(text, "\n---\n")
+	if !ok {
+		//no separator, so that the patches (if any) follow the message right away
+		message, patch = text, text
+		if k := strings.Index(text, "\ndiff "); k >= 0 {
+			message, patch = text[:k], text[k+1:]
+		}
+	}
+	if k := strings.LastIndex(patch, "\n-- \n"); k >= 0 {
+		patch = patch[:k+1]
+	}
+	m.Body = strings.Trim(message, "\n")
+	m.Patch = patch
+	return m, nil
+}
@@ -0,0 +1,61 @@
+package data_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestMailRoundTrip(t *testing.T) {
+	when := time.Unix(1700000000, 0).In(time.FixedZone("", 9*60*60))
+	mails := []*data.Mail{
+		{
+			Oid:     "1111111111111111111111111111111111111111",
+			Author:  data.NewSignature("Taro Pgit", "taro@example.com", when),
+			Subject: "change f",
+			Body:    "the reason\nof the change",
+			Patch:   " f | 2 +-\n 1 file changed, 1 insertion(+), 1 deletion(-)\n\ndiff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1 +1 @@\n-1\n+one\n",
+		},
+		{
+			Oid:     "2222222222222222222222222222222222222222",
+			Author:  data.NewSignature("山田 太郎", "", when.Add(time.Minute)),
+			Subject: "héllo wörld",
+			Patch:   " g | 1 +\n 1 file changed, 1 insertion(+)\n\ndiff --git a/g b/g\n--- /dev/null\n+++ b/g\n@@ -0,0 +1 @@\n+g\n",
+		},
+	}
+	var buf bytes.Buffer
+	for i, m := range mails {
+		m.Write(&buf, i+1, len(mails))
+	}
+
+	got, err := data.ParseMailbox(buf.Bytes())
+
+	if err != nil {
+		t.Errorf("error should be nil:\nerror:%s\n", err)
+	}
+	CmpStructs(t, got, mails)
+	if got[1].Message() != "héllo wörld" || got[0].Message() != "change f\n\nthe reason\nof the change" {
+		t.Errorf("messages should be restored: { got: %q, %q }", got[0].Message(), got[1].Message())
+	}
+}
+
+func TestParseMailbox(t *testing.T) {
+	t.Run("a mail without the separator", func(t *testing.T) {
+		content := "From: Taro Pgit <taro@example.com>\nDate: Tue, 14 Nov 2023 22:13:20 +0000\n" +
+			"Subject: [PATCH v2 3/5] fix the typo\n\ndiff --git a/f b/f\n"
+
+		got, err := data.ParseMailbox([]byte(content))
+
+		if err != nil {
+			t.Errorf("error should be nil:\nerror:%s\n", err)
+		}
+		want := []*data.Mail{{
+			Author:  data.NewSignature("Taro Pgit", "taro@example.com", time.Unix(1700000000, 0)),
+			Subject: "fix the typo",
+			Patch:   "diff --git a/f b/f\n",
+		}}
+		CmpStructs(t, got, want)
+	})
+}
@@ -0,0 +1,114 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	MailmapFile = ".mailmap"
+
+	//config key for the path of the mailmap file used instead of MailmapFile
+	ConfigMailmapFile = "mailmap.file"
+)
+
+// MailmapEntry is a line of the mailmap file mapping the identities recorded in commits to the proper ones:
+// -----------------
+// Proper Name <commit@email>
+// <proper@email> <commit@email>
+// Proper Name <proper@email> <commit@email>
+// Proper Name <proper@email> Commit Name <commit@email>
+// -----------------
+// An entry with the commit name maps only the identities with both the name and the email.
+type MailmapEntry struct {
+	Name        string //the proper name, empty to keep the recorded one
+	Email       string //the proper email, empty to keep the recorded one
+	CommitName  string //the name to match, empty to match any name
+	CommitEmail string //the email to match, regardless of case
+}
+
+// Mailmap holds the entries in the order of the mailmap file.
+type Mailmap []*MailmapEntry
+
+var ErrMalformedMailmap = errors.New("malformed mailmap line")
+
+// Reads the mailmap file in the path. If there is no such a file, an empty Mailmap is returned.
+func ReadMailmap(path string) (Mailmap, error) {
+	c, err := ReadAllFileContent(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Mailmap{}, nil
+		}
+		return nil, fmt.Errorf("ReadMailmap: %w", err)
+	}
+	m, err := ParseMailmap(c)
+	if err != nil {
+		return nil, fmt.Errorf("ReadMailmap: %w", err)
+	}
+	return m, nil
+}
+
+// Parses the content of a mailmap file. Blank lines and comments after "#" are ignored.
+func ParseMailmap(content []byte) (Mailmap, error) {
+	var m Mailmap
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		//the pairs of a name (possibly empty) and the email in angle brackets following it
+		var names, emails []string
+		rest := line
+		for {
+			open := strings.IndexByte(rest, '<')
+			if open < 0 {
+				break
+			}
+			end := strings.IndexByte(rest[open:], '>')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: %q", ErrMalformedMailmap, sc.Text())
+			}
+			names = append(names, strings.TrimSpace(rest[:open]))
+			emails = append(emails, strings.TrimSpace(rest[open+1:open+end]))
+			rest = rest[open+end+1:]
+		}
+		if strings.TrimSpace(rest) != "" || len(emails) == 0 || len(emails) > 2 {
+			return nil, fmt.Errorf("%w: %q", ErrMalformedMailmap, sc.Text())
+		}
+		e := &MailmapEntry{Name: names[0], CommitEmail: emails[0]}
+		if len(emails) == 2 {
+			e.Email, e.CommitName, e.CommitEmail = emails[0], names[1], emails[1]
+		}
+		m = append(m, e)
+	}
+	return m, nil
+}
+
+// Map returns the signature with the proper name and email of the entry matching it. Entries with
+// the commit name take precedence over those without it, and later entries over earlier ones.
+func (m Mailmap) Map(sig Signature) Signature {
+	for _, named := range []bool{true, false} {
+		for i := len(m) - 1; i >= 0; i-- {
+			e := m[i]
+			if (e.CommitName != "") != named || !strings.EqualFold(e.CommitEmail, sig.Email) {
+				continue
+			}
+			if named && e.CommitName != sig.Name {
+				continue
+			}
+			if e.Name != "" {
+				sig.Name = e.Name
+			}
+			if e.Email != "" {
+				sig.Email = e.Email
+			}
+			return sig
+		}
+	}
+	return sig
+}
@@ -0,0 +1,48 @@
+package data_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestMailmap(t *testing.T) {
+	content := `# comment
+Taro Pgit <taro@example.com>
+<hanako@example.com> <hanako@old.example.com>
+Jiro Pgit <jiro@example.com> jiro <JIRO@old.example.com> # trailing comment
+Jiro Pgit <jiro@example.com> <jiro@old.example.com>
+Saburo <saburo@example.com> <saburo@old.example.com>
+Saburo Pgit <saburo@old.example.com>
+`
+	m, err := data.ParseMailmap([]byte(content))
+	if err != nil {
+		t.Fatalf("error should be nil: (error: %s)", err)
+	}
+	when := time.Unix(1700000000, 0)
+	tests := []struct {
+		desc string
+		sig  data.Signature
+		want data.Signature
+	}{
+		{desc: "01_name only", sig: data.NewSignature("taro", "taro@example.com", when), want: data.NewSignature("Taro Pgit", "taro@example.com", when)},
+		{desc: "02_email only", sig: data.NewSignature("Hanako", "hanako@old.example.com", when), want: data.NewSignature("Hanako", "hanako@example.com", when)},
+		{desc: "03_named entry first", sig: data.NewSignature("jiro", "jiro@old.example.com", when), want: data.NewSignature("Jiro Pgit", "jiro@example.com", when)},
+		{desc: "04_later entry first", sig: data.NewSignature("saburo", "saburo@old.example.com", when), want: data.NewSignature("Saburo Pgit", "saburo@old.example.com", when)},
+		{desc: "05_no match", sig: data.NewSignature("Shiro", "shiro@example.com", when), want: data.NewSignature("Shiro", "shiro@example.com", when)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := m.Map(tt.sig)
+
+			CmpStructs(t, got, tt.want)
+		})
+	}
+	t.Run("06_malformed", func(t *testing.T) {
+		if _, err := data.ParseMailmap([]byte("Taro <taro@example.com\n")); !errors.Is(err, data.ErrMalformedMailmap) {
+			t.Errorf("error should be ErrMalformedMailmap: (error: %v)", err)
+		}
+	})
+}
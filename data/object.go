@@ -5,10 +5,13 @@ import (
 	"bytes"
 	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -20,6 +23,8 @@ var (
 const (
 	ObjTypeBlob = "blob"
 	ObjTypeTree = "tree"
+	//commits are saved in the same way as blobs, so this type is only used for reporting
+	ObjTypeCommit = "commit"
 )
 
 type Object struct {
@@ -61,23 +66,124 @@ func IssueObjID(data []byte) (oid string) {
 	return oid
 }
 
+// The streaming version of IssueObjID. The content of r is hashed chunk by chunk,
+// so that the whole content never has to be held in memory.
+func IssueObjIDFrom(r io.Reader) (oid string, err error) {
+	h := sha1.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("IssueObjIDFrom: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// prepends the blob header to r, so that hashing it issues the same oid as SaveBlobObj does
+func newBlobHeaderReader(r io.Reader) io.Reader {
+	header := append([]byte(ObjTypeBlob), IdentBlob...)
+	return io.MultiReader(bytes.NewReader(header), r)
+}
+
 // converts content in byte into a blob object under the hood, and
 // save it as a file with an oid in the dirPath
 // e.g. { dirPath: .pgit/objects, savedfile: .pgit/objects/{oid} }
 func SaveBlobObj(dirPath string, content []byte) (oid string, err error) {
-	obj := NewObject(ObjTypeBlob, IdentBlob, content)
-	oid = IssueObjID(obj.Encode())
-	if err := WriteFile(filepath.Join(dirPath, oid), content); err != nil {
+	oid, err = SaveBlobObjFrom(dirPath, bytes.NewReader(content))
+	if err != nil {
 		return "", fmt.Errorf("SaveHashObj: %w", err)
 	}
 	return oid, nil
 }
 
+// The streaming version of SaveBlobObj. The content of r is written into a temporary file
+// in the dirPath while being hashed, and the file is renamed to its oid at the end.
+// The oid is the same as the one SaveBlobObj issues for the same content.
+func SaveBlobObjFrom(dirPath string, r io.Reader) (oid string, err error) {
+	tmp, err := os.CreateTemp(dirPath, "tmp_obj_")
+	if err != nil {
+		return "", fmt.Errorf("SaveBlobObjFrom: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha1.New()
+	h.Write([]byte(ObjTypeBlob))
+	h.Write(IdentBlob)
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("SaveBlobObjFrom: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("SaveBlobObjFrom: %w", err)
+	}
+	oid = hex.EncodeToString(h.Sum(nil))
+	if err := os.Rename(tmp.Name(), filepath.Join(dirPath, oid)); err != nil {
+		return "", fmt.Errorf("SaveBlobObjFrom: %w", err)
+	}
+	return oid, nil
+}
+
+var (
+	ErrObjNotFound  = errors.New("object not found")
+	ErrAmbiguousOid = errors.New("ambiguous object id")
+)
+
+// Returns the oid of the object in dirPath that begins with the prefix (e.g. an abbreviated oid in a patch).
+func ResolveOidPrefix(dirPath string, prefix string) (string, error) {
+	if len(prefix) == sha1.Size*2 {
+		if _, err := os.Stat(filepath.Join(dirPath, prefix)); err != nil {
+			return "", fmt.Errorf("ResolveOidPrefix: %w: %s", ErrObjNotFound, prefix)
+		}
+		return prefix, nil
+	}
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return "", fmt.Errorf("ResolveOidPrefix: %w", err)
+	}
+	var found string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || len(name) != sha1.Size*2 || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if found != "" {
+			return "", fmt.Errorf("ResolveOidPrefix: %w: %s", ErrAmbiguousOid, prefix)
+		}
+		found = name
+	}
+	if found == "" {
+		return "", fmt.Errorf("ResolveOidPrefix: %w: %s", ErrObjNotFound, prefix)
+	}
+	return found, nil
+}
+
+// Opens an object (= dirPath/{oid}) for reading. The caller is responsible for closing it.
+func OpenObject(dirPath string, oid string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(dirPath, oid))
+	if err != nil {
+		return nil, fmt.Errorf("OpenObject: %w", err)
+	}
+	return f, nil
+}
+
+// Copies the content of an object (= dirPath/{oid}) into w without loading it into memory.
+func CopyObject(w io.Writer, dirPath string, oid string) (written int64, err error) {
+	r, err := OpenObject(dirPath, oid)
+	if err != nil {
+		return 0, fmt.Errorf("CopyObject: %w", err)
+	}
+	defer r.Close()
+	written, err = io.Copy(w, r)
+	if err != nil {
+		return written, fmt.Errorf("CopyObject: %w", err)
+	}
+	return written, nil
+}
+
 // "Tree object" represents a directory in the whole package and the real stuff is just a file.
 // WriteTree walks through the srcDirPath and do the following things for each file (or directory):
 // ・convert each file to a hashed-object, save its oid in the trgDirPath, and record it in a new file (= tree)
 // ・if the given file is a directory, then recursively do the same
 // ・at the end, save the whole directory (i.e. srcDir) as a hashed-object (= tree object) in the trgDirPath
+// Files marked with "filter=lfs" in srcDirPath/.pgitattributes are saved in the large file store
+// instead, and only their pointers are recorded in the tree (= clean).
 func WriteTree(srcDirPath string, trgDirPath string) (treeOid string, err error) {
 	if !filepath.IsAbs(trgDirPath) {
 		trgDirPath, err = filepath.Abs(trgDirPath)
@@ -85,9 +191,15 @@ func WriteTree(srcDirPath string, trgDirPath string) (treeOid string, err error)
 			return "", fmt.Errorf("WriteTree: %s", err)
 		}
 	}
+	attrs, err := ReadAttributes(filepath.Join(srcDirPath, AttributesFile))
 	if err != nil {
 		return "", fmt.Errorf("WriteTree: %w", err)
 	}
+	return writeTree(srcDirPath, "", trgDirPath, attrs)
+}
+
+// relDir is the slash-separated path of srcDirPath relative to the root directory of WriteTree.
+func writeTree(srcDirPath string, relDir string, trgDirPath string, attrs Attributes) (treeOid string, err error) {
 	var buf bytes.Buffer
 	err = filepath.WalkDir(srcDirPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -99,21 +211,18 @@ func WriteTree(srcDirPath string, trgDirPath string) (treeOid string, err error)
 		if isExcluded(d.Name()) {
 			return filepath.SkipDir
 		}
+		relPath := joinRelPath(relDir, d.Name())
 		if d.IsDir() {
-			oid, err := WriteTree(path, trgDirPath)
+			oid, err := writeTree(path, relPath, trgDirPath, attrs)
 			if err != nil {
 				return err
 			}
 			if _, err := fmt.Fprintf(&buf, "%s %s %s\n", ObjTypeTree, oid, d.Name()); err != nil {
 				return err
 			}
-			return nil
-		}
-		b, err := ReadAllFileContent(path)
-		if err != nil {
-			return fmt.Errorf("writeTree: %w", err)
+			return filepath.SkipDir
 		}
-		oid, err := SaveBlobObj(trgDirPath, b)
+		oid, err := saveFileObj(path, trgDirPath, attrs.IsLFS(relPath))
 		if err != nil {
 			return fmt.Errorf("writeTree: %w", err)
 		}
@@ -130,12 +239,38 @@ func WriteTree(srcDirPath string, trgDirPath string) (treeOid string, err error)
 	return treeOid, nil
 }
 
+// saves a file in the path as a blob object. If lfs is true, the content goes to the large file store
+// and the blob holds a pointer to it.
+func saveFileObj(path string, trgDirPath string, lfs bool) (oid string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("saveFileObj: %w", err)
+	}
+	defer f.Close()
+	if !lfs {
+		return SaveBlobObjFrom(trgDirPath, f)
+	}
+	p, err := SaveLFSObj(lfsStoreDir(trgDirPath), f)
+	if err != nil {
+		return "", fmt.Errorf("saveFileObj: %w", err)
+	}
+	return SaveBlobObj(trgDirPath, p.Encode())
+}
+
+func joinRelPath(dir string, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
 func isExcluded(baseName string) bool {
 	return baseName == PgitDirBase
 }
 
 // ReadTree reads the content of a file (= srcDirPath/{treeOid}) and
 // lays out all the files and directories in the target directory.
+// Pointers to the large file store are replaced with their real content if it exists locally (= smudge).
 func ReadTree(treeOid string, srcDirPath string, trgDirPath string) error {
 	treeFilePath := filepath.Join(srcDirPath, treeOid)
 	treeContent, err := ReadAllFileContent(treeFilePath)
@@ -145,56 +280,127 @@ func ReadTree(treeOid string, srcDirPath string, trgDirPath string) error {
 	sc := bufio.NewScanner(bytes.NewReader(treeContent))
 	sc.Split(bufio.ScanLines)
 	for sc.Scan() {
-		line := sc.Bytes()
-		sep := bytes.Split(line, []byte{' '}) //separated bytes hold "objType, oid, filename"
-		if len(sep) < 3 {
-			return fmt.Errorf("ReadTree: invalid data: { object: %s }", sep)
-		}
-		_, oid, filename := sep[0], sep[1], sep[2]
-		fc, err := ReadAllFileContent(filepath.Join(srcDirPath, string(oid)))
+		objType, oid, filename, err := parseTreeLine(sc.Text())
 		if err != nil {
-			return fmt.Errorf("ReadTree: %w", err)
+			return fmt.Errorf("ReadTree: invalid data: %w", err)
 		}
-		f, err := os.Create(filepath.Join(trgDirPath, string(filename)))
+		if objType == ObjTypeTree {
+			dirPath := filepath.Join(trgDirPath, filename)
+			if err := os.MkdirAll(dirPath, os.ModeDir|0755); err != nil {
+				return fmt.Errorf("ReadTree: %w", err)
+			}
+			if err := ReadTree(oid, srcDirPath, dirPath); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := os.Create(filepath.Join(trgDirPath, filename))
 		if err != nil {
 			return err
 		}
-		f.Write(fc)
+		err = smudgeObject(f, srcDirPath, oid)
 		f.Close()
+		if err != nil {
+			return fmt.Errorf("ReadTree: %w", err)
+		}
 	}
 	return nil
 }
 
 type Commit struct {
-	TreeOid string
-	Parent  string
-	Msg     string
+	TreeOid      string
+	Parent       string    //the first parent, empty for a root commit
+	MergeParents []string  //the other parents of a merge commit
+	Author       Signature //who wrote the change, which is kept when the change is applied by someone else
+	Committer    Signature //who made the commit
+	Msg          string
+	Signature    string //the signature block (see Sign) of the commit without it, empty if not signed
 }
 
 // Read a content of a file (= .pgit/objects/{oid}), and convert it to Commit struct.
+// The header lines come first, and the message follows a blank line.
 func GetCommit(oid string) (*Commit, error) {
 	c := &Commit{}
 	b, err := ReadAllFileContent(filepath.Join(PgitDirBase, ObjDirBase, oid))
 	if err != nil {
 		return nil, fmt.Errorf("GetCommit: %w", err)
 	}
-	sc := bufio.NewScanner(bytes.NewReader(b))
-	sc.Split(bufio.ScanLines)
-	for sc.Scan() {
-		line := sc.Text()
-		sep := strings.Split(line, " ")
-		switch sep[0] {
+	payload, signature := SplitCommitSignature(b)
+	c.Signature = signature
+	header, msg, _ := strings.Cut(string(payload), "\n\n")
+	for _, line := range strings.Split(header, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
 		case "tree":
-			c.TreeOid = sep[1]
+			c.TreeOid = value
 		case "parent":
-			c.Parent = sep[1]
-		default:
-			c.Msg = strings.Join(sep, " ")
+			if c.Parent == "" {
+				c.Parent = value
+			} else {
+				c.MergeParents = append(c.MergeParents, value)
+			}
+		case "author", "committer":
+			sig, err := ParseSignature(value)
+			if err != nil {
+				return nil, fmt.Errorf("GetCommit: %w", err)
+			}
+			if key == "author" {
+				c.Author = sig
+			} else {
+				c.Committer = sig
+			}
 		}
 	}
+	c.Msg = strings.TrimRight(msg, "\n")
 	return c, nil
 }
 
+// Walks the commit history back from the given oids and returns all the reachable commit oids,
+// each of which appears only once.
+func ReachableCommits(oids ...string) ([]string, error) {
+	seen := make(map[string]bool)
+	var reachable []string
+	queue := append([]string{}, oids...)
+	for len(queue) > 0 {
+		oid := queue[0]
+		queue = queue[1:]
+		if oid == "" || seen[oid] {
+			continue
+		}
+		seen[oid] = true
+		c, err := GetCommit(oid)
+		if err != nil {
+			return nil, fmt.Errorf("ReachableCommits: %w", err)
+		}
+		reachable = append(reachable, oid)
+		queue = append(queue, c.Parents()...)
+	}
+	return reachable, nil
+}
+
+// MergeBase returns the nearest commit reachable from both a and b, or an empty string
+// if they share no history.
+func MergeBase(a string, b string) (string, error) {
+	fromA, err := ReachableCommits(a)
+	if err != nil {
+		return "", fmt.Errorf("MergeBase: %w", err)
+	}
+	ancestors := make(map[string]bool, len(fromA))
+	for _, oid := range fromA {
+		ancestors[oid] = true
+	}
+	fromB, err := ReachableCommits(b)
+	if err != nil {
+		return "", fmt.Errorf("MergeBase: %w", err)
+	}
+	for _, oid := range fromB {
+		if ancestors[oid] {
+			return oid, nil
+		}
+	}
+	return "", nil
+}
+
 type TreeElem struct {
 	ObjType string //blob or tree
 	Oid     string
@@ -205,7 +411,8 @@ type TreeElem struct {
 // { key: filename, value: TreeElem }
 type Tree map[string]*TreeElem
 
-// Parse tree files existing in the path specified, and convert them into type Tree
+// Parse tree files existing in the path specified, and convert them into type Tree.
+// A malformed line makes the whole tree invalid.
 func ParseTreeFile(path string) (Tree, error) {
 	c, err := ReadAllFileContent(path)
 	if err != nil {
@@ -215,12 +422,10 @@ func ParseTreeFile(path string) (Tree, error) {
 	sc := bufio.NewScanner(bytes.NewReader(c))
 	sc.Split(bufio.ScanLines)
 	for sc.Scan() {
-		line := sc.Text()
-		sep := strings.Split(line, " ")
-		if len(sep) < 3 {
-			continue
+		objType, oid, name, err := parseTreeLine(sc.Text())
+		if err != nil {
+			return nil, fmt.Errorf("ParseTree: %s: %w", path, err)
 		}
-		objType, oid, name := sep[0], sep[1], sep[2]
 		elm := &TreeElem{
 			ObjType: objType,
 			Oid:     oid,
@@ -228,7 +433,7 @@ func ParseTreeFile(path string) (Tree, error) {
 			Child:   nil,
 		}
 		if objType == ObjTypeTree {
-			elm.Child, err = ParseTreeFile(filepath.Join(filepath.Dir(path), name))
+			elm.Child, err = ParseTreeFile(filepath.Join(filepath.Dir(path), oid))
 			if err != nil {
 				return nil, fmt.Errorf("ParseTree: %w", err)
 			}
@@ -240,51 +445,44 @@ func ParseTreeFile(path string) (Tree, error) {
 	return tree, nil
 }
 
-// The behavior of this method is quite similar to WriteTree except that this func is NOT expected to
-// save an actual tree object in the object storage (= .pgit/objects/{treeOid}). The primary goal of
-// this func is to obtain information of the working tree.
-func GetWorkingTree(rootPath string) (Tree, error) {
-	tree := make(Tree)
-	err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
+// ErrMalformedTree is returned when a line of a tree object is not in the form of "objType oid name".
+var ErrMalformedTree = errors.New("malformed tree line")
+
+// separates a line of a tree object into "objType, oid, name". The name may contain spaces.
+func parseTreeLine(line string) (objType string, oid string, name string, err error) {
+	sep := strings.SplitN(line, " ", 3)
+	if len(sep) < 3 || sep[1] == "" || sep[2] == "" {
+		return "", "", "", fmt.Errorf("%w: %q", ErrMalformedTree, line)
+	}
+	if sep[0] != ObjTypeBlob && sep[0] != ObjTypeTree {
+		return "", "", "", fmt.Errorf("%w: unknown object type: %q", ErrMalformedTree, line)
+	}
+	return sep[0], sep[1], sep[2], nil
+}
+
+// Walks the tree in the order of names, calling fn for each element with its slash-separated path.
+// Subtrees are visited right after their own element.
+func WalkTree(tree Tree, fn func(path string, elem *TreeElem) error) error {
+	return walkTree(tree, "", fn)
+}
+
+func walkTree(tree Tree, dir string, fn func(path string, elem *TreeElem) error) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		elem := tree[name]
+		path := joinRelPath(dir, name)
+		if err := fn(path, elem); err != nil {
 			return err
 		}
-		if path == rootPath {
-			return nil
-		}
-		if isExcluded(d.Name()) {
-			return filepath.SkipDir
-		}
-		if d.IsDir() {
-			name := d.Name()
-			elm := &TreeElem{
-				ObjType: ObjTypeTree,
-				Name:    name,
-			}
-			child, err := GetWorkingTree(path)
-			if err != nil {
+		if elem.Child != nil {
+			if err := walkTree(elem.Child, path, fn); err != nil {
 				return err
 			}
-			elm.Child = child
-			tree[name] = elm
-			return nil
-		}
-		name := d.Name()
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return err
 		}
-		oid := IssueObjID(content)
-		tree[name] = &TreeElem{
-			ObjType: ObjTypeBlob,
-			Oid:     oid,
-			Name:    name,
-			Child:   nil,
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("GetWorkingTree: %w", err)
 	}
-	return tree, nil
+	return nil
 }
@@ -1,9 +1,10 @@
 package data_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
@@ -69,6 +70,63 @@ func TestSaveBlobObj(t *testing.T) {
 	})
 }
 
+func TestSaveBlobObjFrom(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		tests := []struct {
+			desc    string
+			trgPath string
+			content []byte
+		}{
+			{
+				desc:    "01_same oid as SaveBlobObj",
+				trgPath: tmpDir,
+				content: []byte("test message"),
+			},
+			{
+				desc:    "02_empty content",
+				trgPath: tmpDir,
+				content: []byte{},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				want := data.IssueObjID(data.NewObject(data.ObjTypeBlob, data.IdentBlob, tt.content).Encode())
+
+				oid, err := data.SaveBlobObjFrom(tt.trgPath, bytes.NewReader(tt.content))
+
+				if err != nil {
+					t.Errorf("should be nil: \nerror: %s", err)
+				}
+				CmpStructs(t, oid, want)
+				CmpFileContent(t, filepath.Join(tt.trgPath, oid), tt.content)
+			})
+		}
+	})
+}
+
+func TestCopyObject(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		content := []byte("test message for copy")
+		oid, err := data.SaveBlobObj(tmpDir, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+
+		n, err := data.CopyObject(&buf, tmpDir, oid)
+
+		if err != nil {
+			t.Errorf("should be nil: \nerror: %s", err)
+		}
+		if n != int64(len(content)) {
+			t.Errorf("written bytes should be equal: (got: %d, want: %d)", n, len(content))
+		}
+		CmpStructs(t, buf.Bytes(), content)
+	})
+}
+
 func TestWriteTree(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		tmdDir := t.TempDir()
@@ -252,75 +310,27 @@ func TestParseTree(t *testing.T) {
 			})
 		}
 	})
-}
 
-func TestGetWorkingTree(t *testing.T) {
-	t.Run("success", func(t *testing.T) {
-		oids := issueAllOids(t, "./test/workingtree")
+	t.Run("fail", func(t *testing.T) {
 		tests := []struct {
-			desc     string
-			rootPath string
-			want     data.Tree
+			desc    string
+			path    string
+			wantErr error
 		}{
 			{
-				desc:     "01_all set",
-				rootPath: "./test/workingtree",
-				want: data.Tree{
-					"file_01": &data.TreeElem{
-						ObjType: data.ObjTypeBlob,
-						Oid:     oids[0],
-						Name:    "file_01",
-						Child:   nil,
-					},
-					"file_02": &data.TreeElem{
-						ObjType: data.ObjTypeBlob,
-						Oid:     oids[1],
-						Name:    "file_02",
-						Child:   nil,
-					},
-					"file_03": &data.TreeElem{
-						ObjType: data.ObjTypeBlob,
-						Oid:     oids[2],
-						Name:    "file_03",
-						Child:   nil,
-					},
-				},
+				desc:    "01_malformed line",
+				path:    "./test/parsetree/malformed",
+				wantErr: data.ErrMalformedTree,
 			},
 		}
 		for _, tt := range tests {
 			t.Run(tt.desc, func(t *testing.T) {
-				got, err := data.GetWorkingTree(tt.rootPath)
+				_, err := data.ParseTreeFile(tt.path)
 
-				if err != nil {
-					t.Errorf("should be nil: \n{ error: %s }\n", err)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error should be equal:\n{ got: %v, want: %v }\n", err, tt.wantErr)
 				}
-				CmpStructs(t, got, tt.want)
 			})
 		}
 	})
 }
-
-func issueAllOids(t *testing.T, dirPath string) (oids []string) {
-	t.Helper()
-	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if path == dirPath {
-			return nil
-		}
-		if d.IsDir() {
-			return filepath.SkipDir
-		}
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		oids = append(oids, data.IssueObjID(content))
-		return nil
-	})
-	if err != nil {
-		t.Fatalf("issueAllOids: %s", err)
-	}
-	return
-}
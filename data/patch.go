@@ -0,0 +1,314 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FilePatch is the change of a single file in a patch, parsed from either a git-style patch
+// ("diff --git" with its extended headers) or a plain unified diff ("---" and "+++").
+type FilePatch struct {
+	OldPath string //slash-separated path of the old side, empty for a new file
+	NewPath string //slash-separated path of the new side, empty for a deleted file
+	OldMode string //empty if the patch does not tell
+	NewMode string
+	Status  DiffStatus
+	Score   int    //similarity of a rename or a copy
+	OldOid  string //the (maybe abbreviated) oids of the "index" line, empty if there is none
+	NewOid  string
+	Hunks   []*Hunk
+
+	Binary bool //the file is binary, so that Hunks are empty
+	//the whole new and old contents are in NewContent and OldContent (= a binary patch by --binary)
+	BinaryData bool
+	NewContent []byte
+	OldContent []byte
+}
+
+// returns the path the patch is reported with, which is the new path unless the file is deleted
+func (p *FilePatch) Path() string {
+	if p.NewPath != "" {
+		return p.NewPath
+	}
+	return p.OldPath
+}
+
+// Reverse swaps the sides of the patch, so that applying it undoes the original patch.
+// A copy is reversed into the deletion of the copied file.
+func (p *FilePatch) Reverse() {
+	p.OldPath, p.NewPath = p.NewPath, p.OldPath
+	p.OldMode, p.NewMode = p.NewMode, p.OldMode
+	p.OldOid, p.NewOid = p.NewOid, p.OldOid
+	p.OldContent, p.NewContent = p.NewContent, p.OldContent
+	switch p.Status {
+	case StatusAdded:
+		p.Status = StatusDeleted
+	case StatusDeleted:
+		p.Status = StatusAdded
+	case StatusCopied:
+		//the copy is deleted whatever its content is
+		p.Status, p.NewPath, p.Hunks = StatusDeleted, "", nil
+	}
+	for _, h := range p.Hunks {
+		h.OldStart, h.NewStart = h.NewStart, h.OldStart
+		h.OldLines, h.NewLines = h.NewLines, h.OldLines
+		for i, line := range h.Lines {
+			switch line[0] {
+			case LineDelete:
+				h.Lines[i] = string(LineInsert) + line[1:]
+			case LineInsert:
+				h.Lines[i] = string(LineDelete) + line[1:]
+			}
+		}
+	}
+}
+
+var ErrMalformedPatch = errors.New("malformed patch")
+
+// "@@ -1,2 +1,3 @@", where the counts may be omitted for 1
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch parses the file patches in the content. Lines outside of file patches, such as the
+// headers and the message of a mail, are skipped.
+func ParsePatch(content []byte) ([]*FilePatch, error) {
+	pp := &patchParser{lines: splitLines(string(content))}
+	for pp.i < len(pp.lines) {
+		line := pp.peek()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			if err := pp.parseGitPatch(); err != nil {
+				return nil, fmt.Errorf("ParsePatch: %w", err)
+			}
+		case strings.HasPrefix(line, "--- ") && strings.HasPrefix(pp.peekAt(1), "+++ "):
+			p := &FilePatch{Status: StatusModified}
+			pp.patches = append(pp.patches, p)
+			if err := pp.parseBody(p); err != nil {
+				return nil, fmt.Errorf("ParsePatch: %w", err)
+			}
+		default:
+			pp.i++
+		}
+	}
+	return pp.patches, nil
+}
+
+type patchParser struct {
+	lines   []string
+	i       int //the line to parse next
+	patches []*FilePatch
+}
+
+// returns the next line without its "\n", or an empty string at the end
+func (pp *patchParser) peek() string {
+	return pp.peekAt(0)
+}
+
+func (pp *patchParser) peekAt(n int) string {
+	if pp.i+n >= len(pp.lines) {
+		return ""
+	}
+	return strings.TrimSuffix(pp.lines[pp.i+n], "\n")
+}
+
+// parses "diff --git a/old b/new" and the extended headers, followed by the body
+func (pp *patchParser) parseGitPatch() error {
+	line := strings.TrimPrefix(pp.peek(), "diff --git ")
+	p := &FilePatch{Status: StatusModified}
+	if k := strings.LastIndex(line, " b/"); k >= 0 {
+		p.OldPath = strings.TrimPrefix(line[:k], "a/")
+		p.NewPath = line[k+len(" b/"):]
+	}
+	pp.patches = append(pp.patches, p)
+	pp.i++
+	for pp.i < len(pp.lines) {
+		line := pp.peek()
+		key, value := cutHeader(line)
+		switch key {
+		case "old mode":
+			p.OldMode = value
+		case "new mode":
+			p.NewMode = value
+		case "deleted file mode":
+			p.Status, p.OldMode, p.NewPath = StatusDeleted, value, ""
+		case "new file mode":
+			p.Status, p.NewMode, p.OldPath = StatusAdded, value, ""
+		case "similarity index":
+			p.Score, _ = strconv.Atoi(strings.TrimSuffix(value, "%"))
+		case "rename from":
+			p.Status, p.OldPath = StatusRenamed, value
+		case "rename to":
+			p.Status, p.NewPath = StatusRenamed, value
+		case "copy from":
+			p.Status, p.OldPath = StatusCopied, value
+		case "copy to":
+			p.Status, p.NewPath = StatusCopied, value
+		case "index":
+			oids, mode, _ := strings.Cut(value, " ")
+			p.OldOid, p.NewOid, _ = strings.Cut(oids, "..")
+			if mode != "" && p.OldMode == "" && p.NewMode == "" {
+				p.OldMode, p.NewMode = mode, mode
+			}
+		case "dissimilarity index":
+		default:
+			return pp.parseBody(p)
+		}
+		pp.i++
+	}
+	return nil
+}
+
+// the keys of the extended header lines of a git-style patch
+var patchHeaderKeys = []string{
+	"old mode", "new mode", "deleted file mode", "new file mode", "similarity index", "dissimilarity index",
+	"rename from", "rename to", "copy from", "copy to", "index",
+}
+
+// splits an extended header line into its key and value, or returns empty strings for other lines
+func cutHeader(line string) (key string, value string) {
+	for _, k := range patchHeaderKeys {
+		if v, ok := strings.CutPrefix(line, k+" "); ok {
+			return k, v
+		}
+	}
+	return "", ""
+}
+
+// parses the content of a file patch, which is either "---" and "+++" followed by hunks,
+// a binary patch, or nothing (e.g. a rename without changes)
+func (pp *patchParser) parseBody(p *FilePatch) error {
+	line := pp.peek()
+	switch {
+	case strings.HasPrefix(line, "Binary files "):
+		p.Binary = true
+		pp.i++
+		return nil
+	case line == BinaryPatchHeader:
+		pp.i++
+		return pp.parseBinary(p)
+	case !strings.HasPrefix(line, "--- "):
+		return nil
+	}
+	if oldPath, ok := patchFileName(strings.TrimPrefix(line, "--- ")); ok {
+		p.OldPath = oldPath
+	} else {
+		p.Status, p.OldPath = StatusAdded, ""
+	}
+	pp.i++
+	line = pp.peek()
+	if !strings.HasPrefix(line, "+++ ") {
+		return fmt.Errorf("%w: \"+++\" should follow \"---\": %q", ErrMalformedPatch, line)
+	}
+	if newPath, ok := patchFileName(strings.TrimPrefix(line, "+++ ")); ok {
+		p.NewPath = newPath
+	} else {
+		p.Status, p.NewPath = StatusDeleted, ""
+	}
+	pp.i++
+	for strings.HasPrefix(pp.peek(), "@@ ") {
+		h, err := pp.parseHunk()
+		if err != nil {
+			return err
+		}
+		p.Hunks = append(p.Hunks, h)
+	}
+	return nil
+}
+
+// returns the path in a "---" or "+++" line without its leading directory (e.g. "a/"),
+// or false for /dev/null. Anything after a tab, such as a timestamp, is dropped.
+func patchFileName(name string) (string, bool) {
+	name, _, _ = strings.Cut(name, "\t")
+	if name == DevNull {
+		return "", false
+	}
+	if _, rest, ok := strings.Cut(name, "/"); ok {
+		return rest, true
+	}
+	return name, true
+}
+
+// parses a hunk with as many lines as its header tells
+func (pp *patchParser) parseHunk() (*Hunk, error) {
+	header := pp.peek()
+	m := hunkHeaderRegex.FindStringSubmatch(header)
+	if m == nil {
+		return nil, fmt.Errorf("%w: %q", ErrMalformedPatch, header)
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	h := &Hunk{OldLines: count(m[2]), NewLines: count(m[4])}
+	h.OldStart, _ = strconv.Atoi(m[1])
+	h.NewStart, _ = strconv.Atoi(m[3])
+	pp.i++
+	oldLeft, newLeft := h.OldLines, h.NewLines
+	for oldLeft > 0 || newLeft > 0 {
+		if pp.i >= len(pp.lines) {
+			return nil, fmt.Errorf("%w: hunk %q ends too early", ErrMalformedPatch, header)
+		}
+		line := pp.lines[pp.i]
+		if line == "\n" {
+			//an empty context line whose space was stripped on the way
+			line = " \n"
+		}
+		switch line[0] {
+		case LineContext:
+			oldLeft--
+			newLeft--
+		case LineDelete:
+			oldLeft--
+		case LineInsert:
+			newLeft--
+		case '\\':
+			//the marker of no newline at the end of the file, following the line of the old side
+			if len(h.Lines) > 0 {
+				last := len(h.Lines) - 1
+				h.Lines[last] = strings.TrimSuffix(h.Lines[last], "\n")
+			}
+			pp.i++
+			continue
+		default:
+			return nil, fmt.Errorf("%w: unexpected line in hunk %q: %q", ErrMalformedPatch, header, line)
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return nil, fmt.Errorf("%w: hunk %q has too many lines", ErrMalformedPatch, header)
+		}
+		h.Lines = append(h.Lines, line)
+		pp.i++
+	}
+	//the marker following the last line
+	if strings.HasPrefix(pp.peek(), `\`) && len(h.Lines) > 0 {
+		last := len(h.Lines) - 1
+		h.Lines[last] = strings.TrimSuffix(h.Lines[last], "\n")
+		pp.i++
+	}
+	return h, nil
+}
+
+// parses the two literals of a binary patch following its header
+func (pp *patchParser) parseBinary(p *FilePatch) error {
+	var lines []string
+	blanks := 0
+	for pp.i < len(pp.lines) && blanks < 2 {
+		line := pp.peek()
+		if line == "" {
+			blanks++
+		}
+		lines = append(lines, line)
+		pp.i++
+	}
+	to, from, err := ParseBinaryPatch(lines)
+	if err != nil {
+		return err
+	}
+	p.Binary, p.BinaryData = true, true
+	p.NewContent, p.OldContent = to, from
+	return nil
+}
@@ -0,0 +1,183 @@
+package data
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// treeCache reads the trees of commits, each of which only once.
+type treeCache map[string]Tree
+
+// returns the tree of the commit in the history
+func (tc treeCache) commitTree(h *History, oid string) (Tree, error) {
+	treeOid := h.Commits[oid].TreeOid
+	if t, ok := tc[treeOid]; ok {
+		return t, nil
+	}
+	t, err := ParseTreeFile(filepath.Join(PgitDirBase, ObjDirBase, treeOid))
+	if err != nil {
+		return nil, fmt.Errorf("commitTree: %w", err)
+	}
+	tc[treeOid] = t
+	return t, nil
+}
+
+// reports whether any file matched by the pathspecs differs between the trees
+func pathsChanged(from Tree, to Tree, paths []string) bool {
+	for _, e := range DiffTreeEntries(from, to) {
+		if MatchPathspecs(e.Path, paths) {
+			return true
+		}
+	}
+	return false
+}
+
+// simplify limits the history to the commits changing the paths from all of their parents, or adding
+// them as root commits. A merge whose paths are the same as those of a parent is regarded as that parent,
+// so that the other parents are not followed. The commits left are linked to their nearest ancestors left.
+func (h *History) simplify(tips []string, paths []string) error {
+	trees := make(treeCache)
+	parents := make(map[string][]string, len(h.order))
+	shown := make(map[string]bool, len(h.order))
+	for _, oid := range h.order {
+		tree, err := trees.commitTree(h, oid)
+		if err != nil {
+			return fmt.Errorf("simplify: %w", err)
+		}
+		parents[oid] = h.Parents(oid)
+		same := false
+		for _, p := range parents[oid] {
+			ptree, err := trees.commitTree(h, p)
+			if err != nil {
+				return fmt.Errorf("simplify: %w", err)
+			}
+			if !pathsChanged(ptree, tree, paths) {
+				parents[oid], same = []string{p}, true
+				break
+			}
+		}
+		switch {
+		case same:
+		case len(parents[oid]) == 0:
+			shown[oid] = pathsChanged(Tree{}, tree, paths)
+		default:
+			shown[oid] = true
+		}
+	}
+	h.parents = parents
+
+	//drops the commits only reachable through the parents left out
+	reachable := make(map[string]bool, len(h.order))
+	queue := append([]string{}, tips...)
+	for len(queue) > 0 {
+		oid := queue[0]
+		queue = queue[1:]
+		if _, ok := h.Commits[oid]; !ok || reachable[oid] {
+			continue
+		}
+		reachable[oid] = true
+		queue = append(queue, parents[oid]...)
+	}
+	order := h.order[:0]
+	for _, oid := range h.order {
+		if reachable[oid] {
+			order = append(order, oid)
+		} else {
+			delete(h.Commits, oid)
+		}
+	}
+	h.order = order
+
+	//the nearest ancestors left of each commit, found from the oldest commits
+	ancestors := make(map[string][]string, len(h.order))
+	sorted := h.Sorted()
+	slices.Reverse(sorted)
+	for _, oid := range sorted {
+		var anc []string
+		for _, p := range parents[oid] {
+			if shown[p] {
+				anc = append(anc, p)
+			} else {
+				anc = append(anc, ancestors[p]...)
+			}
+		}
+		ancestors[oid] = uniqueStrings(anc)
+	}
+	order = h.order[:0]
+	for _, oid := range h.order {
+		if !shown[oid] {
+			delete(h.Commits, oid)
+			continue
+		}
+		order = append(order, oid)
+		parents[oid] = ancestors[oid]
+	}
+	h.order = order
+	return nil
+}
+
+// returns the strings without the duplicates, keeping the first ones in order
+func uniqueStrings(ss []string) []string {
+	seen := make(map[string]bool, len(ss))
+	unique := ss[:0]
+	for _, s := range ss {
+		if !seen[s] {
+			seen[s] = true
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
+
+// follow keeps only the commits changing the file in the path, in the order of the oids where every
+// commit comes before its parents. When the file is added by a rename, the file it is renamed from is
+// followed after that. The path of the file in each kept commit is recorded in FollowedPaths.
+func (h *History) follow(oids []string, path string) ([]string, error) {
+	trees := make(treeCache)
+	opts := NewDiffOptions()
+	h.FollowedPaths = make(map[string]string)
+	var kept []string
+	for _, oid := range oids {
+		tree, err := trees.commitTree(h, oid)
+		if err != nil {
+			return nil, fmt.Errorf("follow: %w", err)
+		}
+		ptree := Tree{}
+		if parents := h.Parents(oid); len(parents) > 0 {
+			if _, ok := h.Commits[parents[0]]; ok {
+				ptree, err = trees.commitTree(h, parents[0])
+				if err != nil {
+					return nil, fmt.Errorf("follow: %w", err)
+				}
+			}
+		}
+		var change *DiffEntry
+		for _, e := range DiffTreeEntries(ptree, tree) {
+			if e.Path == path {
+				change = e
+				break
+			}
+		}
+		if change == nil {
+			continue
+		}
+		kept = append(kept, oid)
+		h.FollowedPaths[oid] = path
+		if change.Status != StatusAdded {
+			continue
+		}
+		//the whole trees are compared only for the commit adding the file, to find where it comes from
+		entries, err := opts.DiffTreeEntries(ptree, tree, filepath.Join(PgitDirBase, ObjDirBase))
+		if err != nil {
+			return nil, fmt.Errorf("follow: %w", err)
+		}
+		for _, e := range entries {
+			if e.Path == path && e.Status == StatusRenamed {
+				path = e.OldPath
+				break
+			}
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,146 @@
+package data_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+// testCommit is a commit written by writeTestTrees, whose tree has the files { key: file name, value: blob oid }
+type testCommit struct {
+	oid     string
+	parents []string
+	files   map[string]string
+}
+
+// writes the commits with the files in their trees, made an hour apart in the order of the slice,
+// and the blobs { key: oid, value: content }.
+func writeTestTrees(t *testing.T, blobs map[string]string, commits []testCommit) {
+	t.Helper()
+
+	tmpDir := filepath.Join(data.PgitDirBase, data.ObjDirBase)
+	if err := os.MkdirAll(tmpDir, os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(tmpDir)) })
+	for oid, content := range blobs {
+		if err := data.WriteFile(filepath.Join(tmpDir, oid), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Unix(1700000000, 0)
+	for i, c := range commits {
+		var tree strings.Builder
+		for name, oid := range c.files {
+			fmt.Fprintf(&tree, "%s %s %s\n", data.ObjTypeBlob, oid, name)
+		}
+		treeOid := "tree_" + c.oid
+		if err := data.WriteFile(filepath.Join(tmpDir, treeOid), []byte(tree.String())); err != nil {
+			t.Fatal(err)
+		}
+		sig := data.NewSignature("Taro", "taro@example.com", start.Add(time.Duration(i)*time.Hour))
+		commit := &data.Commit{TreeOid: treeOid, Author: sig, Committer: sig, Msg: c.oid}
+		if len(c.parents) > 0 {
+			commit.Parent, commit.MergeParents = c.parents[0], c.parents[1:]
+		}
+		if err := data.WriteFile(filepath.Join(tmpDir, c.oid), commit.Encode()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRevWalkPaths(t *testing.T) {
+	// *   m     changes b from both parents
+	// |\
+	// | * b1    changes b
+	// * | a2    changes b
+	// * | a1    changes a
+	// |/
+	// * base    adds a and b
+	writeTestTrees(t, nil, []testCommit{
+		{oid: "base", files: map[string]string{"a": "a_1", "b": "b_1"}},
+		{oid: "a1", parents: []string{"base"}, files: map[string]string{"a": "a_2", "b": "b_1"}},
+		{oid: "a2", parents: []string{"a1"}, files: map[string]string{"a": "a_2", "b": "b_2"}},
+		{oid: "b1", parents: []string{"base"}, files: map[string]string{"a": "a_1", "b": "b_3"}},
+		{oid: "m", parents: []string{"a2", "b1"}, files: map[string]string{"a": "a_2", "b": "b_4"}},
+	})
+	tests := []struct {
+		desc        string
+		paths       []string
+		want        []string
+		wantParents map[string][]string
+	}{
+		{
+			desc:        "01_merge the same as a parent",
+			paths:       []string{"a"},
+			want:        []string{"a1", "base"},
+			wantParents: map[string][]string{"a1": {"base"}, "base": nil},
+		},
+		{
+			desc:        "02_merge changing the path",
+			paths:       []string{"b"},
+			want:        []string{"m", "b1", "a2", "base"},
+			wantParents: map[string][]string{"m": {"a2", "b1"}, "b1": {"base"}, "a2": {"base"}, "base": nil},
+		},
+		{
+			desc:        "03_path changed nowhere",
+			paths:       []string{"c"},
+			want:        []string{},
+			wantParents: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			w := data.NewRevWalk()
+			w.Include = []string{"m"}
+			w.Paths = tt.paths
+
+			got, h, err := w.Walk()
+			if err != nil {
+				t.Fatalf("error should be nil: (error: %s)", err)
+			}
+			CmpStructs(t, got, tt.want)
+			gotParents := make(map[string][]string, len(got))
+			for _, oid := range got {
+				gotParents[oid] = h.Parents(oid)
+			}
+			CmpStructs(t, gotParents, tt.wantParents)
+		})
+	}
+}
+
+func TestRevWalkFollow(t *testing.T) {
+	lines := strings.Repeat("line\n", 10)
+	writeTestTrees(t, map[string]string{
+		"old_1": lines + "1\n",
+		"old_2": lines + "2\n",
+		"new_3": lines + "3\n",
+	}, []testCommit{
+		{oid: "add", files: map[string]string{"old": "old_1"}},
+		{oid: "modify", parents: []string{"add"}, files: map[string]string{"old": "old_2"}},
+		{oid: "other", parents: []string{"modify"}, files: map[string]string{"old": "old_2", "other": "old_1"}},
+		{oid: "rename", parents: []string{"other"}, files: map[string]string{"new": "old_2", "other": "old_1"}},
+		{oid: "modify_new", parents: []string{"rename"}, files: map[string]string{"new": "new_3", "other": "old_1"}},
+	})
+	w := data.NewRevWalk()
+	w.Include = []string{"modify_new"}
+	w.Paths = []string{"new"}
+	w.Follow = true
+
+	got, h, err := w.Walk()
+	if err != nil {
+		t.Fatalf("error should be nil: (error: %s)", err)
+	}
+	CmpStructs(t, got, []string{"modify_new", "rename", "modify", "add"})
+	CmpStructs(t, h.FollowedPaths, map[string]string{"modify_new": "new", "rename": "new", "modify": "old", "add": "old"})
+
+	w.Paths = []string{"new", "other"}
+	if _, _, err := w.Walk(); err == nil {
+		t.Error("error should not be nil with two paths")
+	}
+}
@@ -0,0 +1,192 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// the layout of the dates in logs, like "Mon Jan 2 15:04:05 2006 -0700"
+const LogDateLayout = "Mon Jan 2 15:04:05 2006 -0700"
+
+// the built-in formats of log entries
+const (
+	FormatMedium  = "medium"  //the commit line, the author, the date and the indented message (default)
+	FormatOneline = "oneline" //the abbreviated oid and the subject in a line
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// LogEntry is a commit to print in a log, together with the refs pointing to it.
+type LogEntry struct {
+	Oid       string
+	Commit    *Commit
+	Refs      []string //decorations like "HEAD -> master" and "tag: v1.0", empty if not decorated
+	Signature string   //the status of the signature (see SignatureStatus), printed before the commit is described
+}
+
+// returns the decoration like " (HEAD -> master, tag: v1.0)", or an empty string without refs
+func (e *LogEntry) Decoration() string {
+	if len(e.Refs) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(e.Refs, ", ") + ")"
+}
+
+// Write writes the entry in the format, which is either a built-in format (see FormatMedium)
+// or a string with placeholders (see Expand) optionally prefixed with "format:" or "tformat:".
+// Every entry ends with a newline.
+func (e *LogEntry) Write(buf *bytes.Buffer, format string, color bool) error {
+	paint := func(text string) string {
+		if !color {
+			return text
+		}
+		return Paint(ColorCommit, text)
+	}
+	switch format {
+	case "", FormatMedium:
+		buf.WriteString(paint("commit "+e.Oid+e.Decoration()) + "\n")
+		if e.Signature != "" {
+			buf.WriteString(e.Signature + "\n")
+		}
+		//commits made before the authors were recorded have neither of them
+		if e.Commit.Author.Name != "" {
+			fmt.Fprintf(buf, "Author: %s\n", e.Commit.Author.Ident())
+			fmt.Fprintf(buf, "Date:   %s\n", formatDate(e.Commit.Author))
+		}
+		buf.WriteString("\n")
+		for _, line := range strings.Split(e.Commit.Msg, "\n") {
+			buf.WriteString("    " + line + "\n")
+		}
+		return nil
+	case FormatOneline:
+		if e.Signature != "" {
+			buf.WriteString(e.Signature + "\n")
+		}
+		buf.WriteString(paint(abbrevOid(e.Oid)+e.Decoration()) + " " + e.Commit.Subject() + "\n")
+		return nil
+	}
+	if s, ok := strings.CutPrefix(format, "format:"); ok {
+		format = s
+	} else if s, ok := strings.CutPrefix(format, "tformat:"); ok {
+		format = s
+	} else if !strings.Contains(format, "%") {
+		return fmt.Errorf("Write: %w: %s", ErrUnknownFormat, format)
+	}
+	buf.WriteString(e.Expand(format) + "\n")
+	return nil
+}
+
+// Expand replaces the placeholders in the format with the values of the entry:
+// -----------------
+// %H  commit oid          %h  abbreviated commit oid
+// %T  tree oid            %t  abbreviated tree oid
+// %P  parent oid          %p  abbreviated parent oid
+// %an author name         %ae author email          %ad author date
+// %cn committer name      %ce committer email       %cd committer date
+// %s  subject             %b  body
+// %d  decoration          %D  decoration without the parentheses
+// %n  newline             %%  "%"
+// -----------------
+// Unknown placeholders are left as they are.
+func (e *LogEntry) Expand(format string) string {
+	c := e.Commit
+	values := map[string]string{
+		"H": e.Oid, "h": abbrevOid(e.Oid),
+		"T": c.TreeOid, "t": abbrevOid(c.TreeOid),
+		"P": c.Parent, "p": abbrevOid(c.Parent),
+		"an": c.Author.Name, "ae": c.Author.Email, "ad": formatDate(c.Author),
+		"cn": c.Committer.Name, "ce": c.Committer.Email, "cd": formatDate(c.Committer),
+		"s": c.Subject(), "b": c.Body(),
+		"d": e.Decoration(), "D": strings.Join(e.Refs, ", "),
+		"n": "\n", "%": "%",
+	}
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		//two-letter placeholders first, so that %an is not taken as %a followed by "n"
+		if i+3 <= len(format) {
+			if v, ok := values[format[i+1:i+3]]; ok {
+				b.WriteString(v)
+				i += 2
+				continue
+			}
+		}
+		if v, ok := values[format[i+1:i+2]]; ok {
+			b.WriteString(v)
+			i++
+			continue
+		}
+		b.WriteByte(format[i])
+	}
+	return b.String()
+}
+
+// returns the date of the signature, or an empty string for a commit recorded without it
+func formatDate(s Signature) string {
+	if s.When.IsZero() {
+		return ""
+	}
+	return s.When.Format(LogDateLayout)
+}
+
+// Decorations returns the refs pointing to each commit, like "HEAD -> master", "dev" and "tag: v1.0".
+// HEAD comes first, and the others are sorted by name.
+// { key: commit oid, value: decorations }
+func Decorations() (map[string][]string, error) {
+	refs, err := ListRefs()
+	if err != nil {
+		return nil, fmt.Errorf("Decorations: %w", err)
+	}
+	head, err := NewRef(RefHEADPath)
+	if err != nil {
+		return nil, fmt.Errorf("Decorations: %w", err)
+	}
+	//the branch HEAD points to, like "refs/heads/master"
+	var headBranch string
+	if head != nil && head.IsSymbolic {
+		rel, err := filepath.Rel(PgitDirBase, head.Next)
+		if err != nil {
+			return nil, fmt.Errorf("Decorations: %w", err)
+		}
+		headBranch = filepath.ToSlash(rel)
+	}
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	decorations := make(map[string][]string)
+	if head != nil && !head.IsSymbolic && head.Oid != "" {
+		decorations[head.Oid] = []string{HEAD}
+	}
+	if oid, ok := refs[headBranch]; ok {
+		decorations[oid] = []string{HEAD + " -> " + strings.TrimPrefix(headBranch, "refs/heads/")}
+	}
+	for _, name := range names {
+		if name == headBranch {
+			continue
+		}
+		oid := refs[name]
+		switch {
+		case strings.HasPrefix(name, "refs/heads/"):
+			decorations[oid] = append(decorations[oid], strings.TrimPrefix(name, "refs/heads/"))
+		case strings.HasPrefix(name, "refs/tags/"):
+			//an annotated tag decorates the commit it points to
+			if oid, _, err = PeelTag(oid); err != nil {
+				return nil, fmt.Errorf("Decorations: %w", err)
+			}
+			decorations[oid] = append(decorations[oid], "tag: "+strings.TrimPrefix(name, "refs/tags/"))
+		default:
+			decorations[oid] = append(decorations[oid], strings.TrimPrefix(name, "refs/"))
+		}
+	}
+	return decorations, nil
+}
@@ -0,0 +1,49 @@
+package data_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestLogEntryWrite(t *testing.T) {
+	when := time.Unix(1700000000, 0).In(time.FixedZone("", 9*60*60))
+	e := &data.LogEntry{
+		Oid: "0123456789012345678901234567890123456789",
+		Commit: &data.Commit{
+			TreeOid: "abcdefabcdefabcdefabcdefabcdefabcdefabcd",
+			Author:  data.NewSignature("Taro Pgit", "taro@example.com", when),
+			Msg:     "subject\n\nbody",
+		},
+		Refs: []string{"HEAD -> master", "tag: v1"},
+	}
+	tests := []struct {
+		desc   string
+		format string
+		want   string
+	}{
+		{
+			desc:   "01_medium",
+			format: data.FormatMedium,
+			want: "commit 0123456789012345678901234567890123456789 (HEAD -> master, tag: v1)\n" +
+				"Author: Taro Pgit <taro@example.com>\nDate:   Wed Nov 15 07:13:20 2023 +0900\n\n    subject\n    \n    body\n",
+		},
+		{desc: "02_oneline", format: data.FormatOneline, want: "0123456 (HEAD -> master, tag: v1) subject\n"},
+		{desc: "03_placeholders", format: "%h %t %an <%ae> %ad%n%s/%b/%D", want: "0123456 abcdefa Taro Pgit <taro@example.com> Wed Nov 15 07:13:20 2023 +0900\nsubject/body/HEAD -> master, tag: v1\n"},
+		{desc: "04_unknown placeholders", format: "format:%x %% %", want: "%x % %\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := e.Write(&buf, tt.format, false)
+
+			if err != nil {
+				t.Errorf("error should be nil:\nerror:%s\n", err)
+			}
+			CmpStructs(t, buf.String(), tt.want)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 )
@@ -111,6 +112,61 @@ func (r *Ref) ResolveSymbolic(next string) (*Ref, error) {
 	return resolved, nil
 }
 
+// Reads the ref in the path and returns the oid it finally points to.
+// An empty oid is returned if there is no such a ref or the ref points to nothing yet.
+func ResolveRefOid(path string) (oid string, err error) {
+	ref, err := NewRef(path)
+	if err != nil {
+		return "", fmt.Errorf("ResolveRefOid: %w", err)
+	}
+	if ref == nil {
+		return "", nil
+	}
+	if ref.IsSymbolic {
+		ref, err = ref.ResolveSymbolic(ref.Next)
+		if err != nil {
+			return "", fmt.Errorf("ResolveRefOid: %w", err)
+		}
+		if ref == nil {
+			return "", nil
+		}
+	}
+	return ref.Oid, nil
+}
+
+// Walks through all the refs under the ref directory (= .pgit/refs) and returns the oids they point to.
+// Refs pointing to nothing yet are left out.
+// { key: ref name like "refs/heads/master", value: oid }
+func ListRefs() (map[string]string, error) {
+	refs := make(map[string]string)
+	root := filepath.Join(PgitDirBase, RefDirBase)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		oid, err := ResolveRefOid(path)
+		if err != nil {
+			return err
+		}
+		if oid == "" {
+			return nil
+		}
+		rel, err := filepath.Rel(PgitDirBase, path)
+		if err != nil {
+			return err
+		}
+		refs[filepath.ToSlash(rel)] = oid
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListRefs: %w", err)
+	}
+	return refs, nil
+}
+
 func isSymbolic(b []byte) bool {
 	return bytes.HasPrefix(b, []byte("ref:"))
 }
@@ -0,0 +1,233 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultRenameThreshold is the default similarity (in percent) above which a deleted file and an
+// added file are regarded as a rename.
+const DefaultRenameThreshold = 50
+
+const (
+	ConfigDiffRenames         = "diff.renames" //"true", "false" or "copies"
+	ConfigDiffRenameThreshold = "diff.renameThreshold"
+)
+
+// renameCandidate is a pair of a source and an added file that may be a rename or a copy
+type renameCandidate struct {
+	src   *DiffEntry
+	dst   *DiffEntry
+	score int
+}
+
+// pairs deleted files with added ones as renames, and optionally finds the sources of the rest of the
+// added files among the deleted and modified ones as copies. Files with the same oid are paired
+// first, and then the pairs of the highest similarity at or above the threshold.
+func (o *DiffOptions) detectRenames(entries []*DiffEntry, srcDir string) ([]*DiffEntry, error) {
+	var added, deleted, modified []*DiffEntry
+	for _, e := range entries {
+		switch e.Status {
+		case StatusAdded:
+			added = append(added, e)
+		case StatusDeleted:
+			deleted = append(deleted, e)
+		case StatusModified:
+			modified = append(modified, e)
+		}
+	}
+	if len(added) == 0 || (len(deleted) == 0 && !o.Copies) {
+		return entries, nil
+	}
+	contents := make(map[string][]byte)
+	renamed, err := o.pairFiles(added, deleted, srcDir, contents)
+	if err != nil {
+		return nil, fmt.Errorf("detectRenames: %w", err)
+	}
+	//the sources of the renames are dropped from the result
+	dropped := make(map[*DiffEntry]bool, len(renamed))
+	for _, c := range renamed {
+		dropped[c.src] = true
+		toRenamed(c, StatusRenamed)
+	}
+	if o.Copies {
+		var rest []*DiffEntry
+		for _, e := range added {
+			if e.Status == StatusAdded {
+				rest = append(rest, e)
+			}
+		}
+		sources := append(append([]*DiffEntry{}, deleted...), modified...)
+		copied, err := o.findCopies(rest, sources, srcDir, contents)
+		if err != nil {
+			return nil, fmt.Errorf("detectRenames: %w", err)
+		}
+		for _, c := range copied {
+			toRenamed(c, StatusCopied)
+		}
+	}
+	result := make([]*DiffEntry, 0, len(entries))
+	for _, e := range entries {
+		if dropped[e] {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// turns the added file of the candidate into a rename (or a copy) of the source
+func toRenamed(c *renameCandidate, status DiffStatus) {
+	c.dst.Status = status
+	c.dst.OldPath = c.src.SourcePath()
+	c.dst.OldOid = c.src.OldOid
+	c.dst.OldMode = c.src.OldMode
+	c.dst.Score = c.score
+}
+
+// pairs each of the added files with at most one of the deleted files
+func (o *DiffOptions) pairFiles(added []*DiffEntry, deleted []*DiffEntry, srcDir string, contents map[string][]byte) ([]*renameCandidate, error) {
+	var pairs []*renameCandidate
+	usedSrc := make(map[*DiffEntry]bool)
+	usedDst := make(map[*DiffEntry]bool)
+	for _, dst := range added {
+		for _, src := range deleted {
+			if !usedSrc[src] && src.OldOid == dst.NewOid {
+				pairs = append(pairs, &renameCandidate{src: src, dst: dst, score: 100})
+				usedSrc[src], usedDst[dst] = true, true
+				break
+			}
+		}
+	}
+	var candidates []*renameCandidate
+	for _, dst := range added {
+		if usedDst[dst] {
+			continue
+		}
+		for _, src := range deleted {
+			if usedSrc[src] {
+				continue
+			}
+			score, err := o.similarity(src, dst, srcDir, contents)
+			if err != nil {
+				return nil, err
+			}
+			if score >= o.RenameThreshold {
+				candidates = append(candidates, &renameCandidate{src: src, dst: dst, score: score})
+			}
+		}
+	}
+	sortCandidates(candidates)
+	for _, c := range candidates {
+		if usedSrc[c.src] || usedDst[c.dst] {
+			continue
+		}
+		pairs = append(pairs, c)
+		usedSrc[c.src], usedDst[c.dst] = true, true
+	}
+	return pairs, nil
+}
+
+// finds the most similar source of each of the added files. A source may be copied many times.
+func (o *DiffOptions) findCopies(added []*DiffEntry, sources []*DiffEntry, srcDir string, contents map[string][]byte) ([]*renameCandidate, error) {
+	var copies []*renameCandidate
+	for _, dst := range added {
+		var best *renameCandidate
+		for _, src := range sources {
+			score := 100
+			if src.OldOid != dst.NewOid {
+				var err error
+				score, err = o.similarity(src, dst, srcDir, contents)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if score >= o.RenameThreshold && (best == nil || score > best.score) {
+				best = &renameCandidate{src: src, dst: dst, score: score}
+			}
+		}
+		if best != nil {
+			copies = append(copies, best)
+		}
+	}
+	return copies, nil
+}
+
+// highest scores first, and then by paths for a stable result
+func sortCandidates(candidates []*renameCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if ci.score != cj.score {
+			return ci.score > cj.score
+		}
+		if ci.dst.Path != cj.dst.Path {
+			return ci.dst.Path < cj.dst.Path
+		}
+		return ci.src.Path < cj.src.Path
+	})
+}
+
+// scores the similarity of the old side of src and the new side of dst. Files larger than
+// BigFileThreshold are never regarded as similar, so that they are not loaded into memory.
+func (o *DiffOptions) similarity(src *DiffEntry, dst *DiffEntry, srcDir string, contents map[string][]byte) (int, error) {
+	srcPath, _ := o.entryFilePaths(src, srcDir)
+	_, dstPath := o.entryFilePaths(dst, srcDir)
+	big, err := isBigFile(srcPath, dstPath)
+	if err != nil || big {
+		return 0, err
+	}
+	a, err := readCached(srcPath, contents)
+	if err != nil {
+		return 0, err
+	}
+	b, err := readCached(dstPath, contents)
+	if err != nil {
+		return 0, err
+	}
+	return Similarity(a, b), nil
+}
+
+func readCached(path string, contents map[string][]byte) ([]byte, error) {
+	if c, ok := contents[path]; ok {
+		return c, nil
+	}
+	c, err := readFileOrEmpty(path)
+	if err != nil {
+		return nil, err
+	}
+	contents[path] = c
+	return c, nil
+}
+
+// Similarity estimates how much of the contents are the same, from 0 to 100, as the bytes of the
+// lines found in both over the size of the larger one. Empty contents are similar to nothing.
+func Similarity(a []byte, b []byte) int {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	counts := make(map[string]int)
+	for _, line := range splitLines(string(a)) {
+		counts[line]++
+	}
+	common := 0
+	for _, line := range splitLines(string(b)) {
+		if counts[line] > 0 {
+			counts[line]--
+			common += len(line)
+		}
+	}
+	return common * 100 / max(len(a), len(b))
+}
+
+// parses the value of diff.renames into the options: "copies" finds copies as well as renames
+func (o *DiffOptions) SetRenames(value string) {
+	switch strings.ToLower(value) {
+	case "false", "no", "off":
+		o.Renames, o.Copies = false, false
+	case "copies", "copy":
+		o.Renames, o.Copies = true, true
+	default:
+		o.Renames = true
+	}
+}
@@ -0,0 +1,105 @@
+package data_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestDiffTreeEntriesRenames(t *testing.T) {
+	srcDir := t.TempDir()
+	lines := func(from, to int) string {
+		var b strings.Builder
+		for i := from; i <= to; i++ {
+			b.WriteString(strings.Repeat("x", i) + "\n")
+		}
+		return b.String()
+	}
+	contents := map[string]string{
+		"oid_a":  lines(1, 10),
+		"oid_a2": lines(1, 9) + "changed\n",
+		"oid_b":  lines(11, 20),
+		"oid_c":  "something else\n",
+	}
+	for oid, content := range contents {
+		if err := data.WriteFile(filepath.Join(srcDir, oid), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blob := func(name string, oid string) *data.TreeElem {
+		return &data.TreeElem{ObjType: data.ObjTypeBlob, Oid: oid, Name: name}
+	}
+	tests := []struct {
+		desc   string
+		from   data.Tree
+		to     data.Tree
+		copies bool
+		want   []*data.DiffEntry
+	}{
+		{
+			desc: "01_exact rename",
+			from: data.Tree{"a": blob("a", "oid_a")},
+			to:   data.Tree{"moved": blob("moved", "oid_a")},
+			want: []*data.DiffEntry{
+				{Path: "moved", OldOid: "oid_a", NewOid: "oid_a", OldMode: data.ModeBlob, NewMode: data.ModeBlob, Status: data.StatusRenamed, OldPath: "a", Score: 100},
+			},
+		},
+		{
+			desc: "02_similar rename",
+			from: data.Tree{"a": blob("a", "oid_a"), "c": blob("c", "oid_c")},
+			to:   data.Tree{"a2": blob("a2", "oid_a2"), "b": blob("b", "oid_b")},
+			want: []*data.DiffEntry{
+				{Path: "a2", OldOid: "oid_a", NewOid: "oid_a2", OldMode: data.ModeBlob, NewMode: data.ModeBlob, Status: data.StatusRenamed, OldPath: "a", Score: 83},
+				{Path: "b", NewOid: "oid_b", NewMode: data.ModeBlob, Status: data.StatusAdded},
+				{Path: "c", OldOid: "oid_c", OldMode: data.ModeBlob, Status: data.StatusDeleted},
+			},
+		},
+		{
+			desc:   "03_copy of a modified file",
+			from:   data.Tree{"a": blob("a", "oid_a")},
+			to:     data.Tree{"a": blob("a", "oid_a2"), "copy": blob("copy", "oid_a")},
+			copies: true,
+			want: []*data.DiffEntry{
+				{Path: "a", OldOid: "oid_a", NewOid: "oid_a2", OldMode: data.ModeBlob, NewMode: data.ModeBlob, Status: data.StatusModified},
+				{Path: "copy", OldOid: "oid_a", NewOid: "oid_a", OldMode: data.ModeBlob, NewMode: data.ModeBlob, Status: data.StatusCopied, OldPath: "a", Score: 100},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			opts := data.NewDiffOptions()
+			opts.Copies = tt.copies
+
+			got, err := opts.DiffTreeEntries(tt.from, tt.to, srcDir)
+
+			if err != nil {
+				t.Errorf("error should be nil:\nerror:%s\n", err)
+			}
+			CmpStructs(t, got, tt.want)
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		desc string
+		a    string
+		b    string
+		want int
+	}{
+		{desc: "01_same", a: "a\nb\n", b: "a\nb\n", want: 100},
+		{desc: "02_half", a: "a\nb\n", b: "a\nc\n", want: 50},
+		{desc: "03_empty", a: "", b: "", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := data.Similarity([]byte(tt.a), []byte(tt.b))
+
+			if got != tt.want {
+				t.Errorf("should be equal: { got: %d, want: %d }", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package data
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// History is the commits reachable from some commits, loaded at once to be sorted and filtered.
+type History struct {
+	Commits     map[string]*Commit  //{ key: commit oid, value: commit }
+	order       []string            //the oids in the order they are found, which breaks ties in sorting
+	firstParent bool                //only the first parents are followed
+	parents     map[string][]string //the parents replaced by the history simplification, if set
+
+	FollowedPaths map[string]string //{ key: commit oid, value: the path of the file followed in it } (see RevWalk.Follow)
+}
+
+// LoadHistory reads all the commits reachable from the oids, following every parent.
+func LoadHistory(oids ...string) (*History, error) {
+	h, err := loadHistory(oids, false)
+	if err != nil {
+		return nil, fmt.Errorf("LoadHistory: %w", err)
+	}
+	return h, nil
+}
+
+// reads the commits reachable from the oids, following only the first parents if firstParent is true
+func loadHistory(oids []string, firstParent bool) (*History, error) {
+	h := &History{Commits: make(map[string]*Commit), firstParent: firstParent}
+	queue := append([]string{}, oids...)
+	for len(queue) > 0 {
+		oid := queue[0]
+		queue = queue[1:]
+		if _, ok := h.Commits[oid]; ok || oid == "" {
+			continue
+		}
+		c, err := GetCommit(oid)
+		if err != nil {
+			return nil, err
+		}
+		h.Commits[oid] = c
+		h.order = append(h.order, oid)
+		queue = append(queue, h.Parents(oid)...)
+	}
+	return h, nil
+}
+
+// returns the parents of the commit followed in the history, which are only the first parent
+// if the history follows the first parents, or the nearest ancestors left if the history is limited to paths
+func (h *History) Parents(oid string) []string {
+	if h.parents != nil {
+		return h.parents[oid]
+	}
+	parents := h.Commits[oid].Parents()
+	if h.firstParent && len(parents) > 1 {
+		return parents[:1]
+	}
+	return parents
+}
+
+// Sorted returns the oids of all the commits, where every commit comes before its parents.
+// Among the commits whose children have all come, the newest one (by the committer date) comes next.
+func (h *History) Sorted() []string {
+	ready := &commitQueue{history: h, rank: make(map[string]int, len(h.order))}
+	children := make(map[string]int, len(h.order))
+	for i, oid := range h.order {
+		ready.rank[oid] = i
+		for _, p := range h.Parents(oid) {
+			children[p]++
+		}
+	}
+	for _, oid := range h.order {
+		if children[oid] == 0 {
+			heap.Push(ready, oid)
+		}
+	}
+	sorted := make([]string, 0, len(h.order))
+	for ready.Len() > 0 {
+		oid := heap.Pop(ready).(string)
+		sorted = append(sorted, oid)
+		for _, p := range h.Parents(oid) {
+			if _, ok := h.Commits[p]; !ok {
+				continue
+			}
+			children[p]--
+			if children[p] == 0 {
+				heap.Push(ready, p)
+			}
+		}
+	}
+	return sorted
+}
+
+// TopoSorted returns the oids of all the commits, where every commit comes before its parents,
+// and the commits of a line of history come together instead of being mixed up with the others by date.
+func (h *History) TopoSorted() []string {
+	children := make(map[string]int, len(h.order))
+	for _, oid := range h.order {
+		for _, p := range h.Parents(oid) {
+			children[p]++
+		}
+	}
+	//the tips in the order of Sorted, pushed so that the newest one is popped first
+	var stack []string
+	for _, oid := range h.Sorted() {
+		if children[oid] == 0 {
+			stack = append(stack, oid)
+		}
+	}
+	slices.Reverse(stack)
+	sorted := make([]string, 0, len(h.order))
+	for len(stack) > 0 {
+		oid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		sorted = append(sorted, oid)
+		//the first parent is pushed last, so that the line of the first parents is followed first
+		parents := h.Parents(oid)
+		for i := len(parents) - 1; i >= 0; i-- {
+			p := parents[i]
+			if _, ok := h.Commits[p]; !ok {
+				continue
+			}
+			children[p]--
+			if children[p] == 0 {
+				stack = append(stack, p)
+			}
+		}
+	}
+	return sorted
+}
+
+// commitQueue is a heap of commit oids, the newest commit first. Commits of the same date come
+// in the order they are found.
+type commitQueue struct {
+	history *History
+	rank    map[string]int
+	oids    []string
+}
+
+func (q *commitQueue) Len() int { return len(q.oids) }
+
+func (q *commitQueue) Less(i, j int) bool {
+	a, b := q.history.Commits[q.oids[i]].Committer.When, q.history.Commits[q.oids[j]].Committer.When
+	if !a.Equal(b) {
+		return a.After(b)
+	}
+	return q.rank[q.oids[i]] < q.rank[q.oids[j]]
+}
+
+func (q *commitQueue) Swap(i, j int) { q.oids[i], q.oids[j] = q.oids[j], q.oids[i] }
+
+func (q *commitQueue) Push(x any) { q.oids = append(q.oids, x.(string)) }
+
+func (q *commitQueue) Pop() any {
+	last := q.oids[len(q.oids)-1]
+	q.oids = q.oids[:len(q.oids)-1]
+	return last
+}
+
+// the orders of the commits listed by RevWalk
+const (
+	OrderDate = "date" //the newest first, but never before the children (see History.Sorted)
+	OrderTopo = "topo" //the lines of history together (see History.TopoSorted)
+)
+
+var (
+	ErrUnknownOrder = errors.New("unknown order")
+	ErrFollowPath   = errors.New("--follow requires exactly one path")
+)
+
+// RevWalk lists the commits reachable from some commits but not from others, like "rev-list A ^B",
+// and filters them.
+type RevWalk struct {
+	Include     []string //the commits to walk from
+	Exclude     []string //the commits whose ancestors (and themselves) are left out
+	FirstParent bool     //follows only the first parent of merge commits
+
+	Author *regexp.Regexp //matches "{name} <{email}>" of the author, if set
+	Grep   *regexp.Regexp //matches the message, if set
+	Since  time.Time      //the committer date is at or after it, if set
+	Until  time.Time      //the committer date is at or before it, if set
+	//the number of parents is between them, where a negative MaxParents means no limit
+	//(e.g. 2 and -1 for --merges, 0 and 1 for --no-merges)
+	MinParents int
+	MaxParents int
+
+	Order    string //OrderDate (default) or OrderTopo
+	Reverse  bool   //lists the oldest first, after MaxCount is applied
+	MaxCount int    //lists at most this number of commits, where a negative value means no limit
+
+	Paths []string //only the commits changing the files matched by any of the pathspecs, if set
+	//follows the file in the only path of Paths across renames, instead of limiting the history to the paths
+	Follow bool
+}
+
+func NewRevWalk() *RevWalk {
+	return &RevWalk{MaxParents: -1, Order: OrderDate, MaxCount: -1}
+}
+
+// Walk returns the oids of the commits in the order, and the history they are read from.
+// With Paths, the history is simplified to the commits changing the paths, whose parents are replaced
+// with their nearest ancestors left (see History.Parents).
+func (w *RevWalk) Walk() ([]string, *History, error) {
+	if w.Follow && len(w.Paths) != 1 {
+		return nil, nil, fmt.Errorf("Walk: %w", ErrFollowPath)
+	}
+	h, err := loadHistory(w.Include, w.FirstParent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Walk: %w", err)
+	}
+	if len(w.Paths) > 0 && !w.Follow {
+		if err := h.simplify(w.Include, w.Paths); err != nil {
+			return nil, nil, fmt.Errorf("Walk: %w", err)
+		}
+	}
+	excluded, err := loadHistory(w.Exclude, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Walk: %w", err)
+	}
+	var sorted []string
+	switch w.Order {
+	case "", OrderDate:
+		sorted = h.Sorted()
+	case OrderTopo:
+		sorted = h.TopoSorted()
+	default:
+		return nil, nil, fmt.Errorf("Walk: %w: %s", ErrUnknownOrder, w.Order)
+	}
+	if w.Follow {
+		if sorted, err = h.follow(sorted, w.Paths[0]); err != nil {
+			return nil, nil, fmt.Errorf("Walk: %w", err)
+		}
+	}
+	oids := make([]string, 0, len(sorted))
+	for _, oid := range sorted {
+		if w.MaxCount >= 0 && len(oids) >= w.MaxCount {
+			break
+		}
+		if _, ok := excluded.Commits[oid]; ok || !w.match(h.Commits[oid]) {
+			continue
+		}
+		oids = append(oids, oid)
+	}
+	if w.Reverse {
+		slices.Reverse(oids)
+	}
+	return oids, h, nil
+}
+
+// reports whether the commit passes all the filters
+func (w *RevWalk) match(c *Commit) bool {
+	n := len(c.Parents())
+	switch {
+	case n < w.MinParents, w.MaxParents >= 0 && n > w.MaxParents:
+		return false
+	case w.Author != nil && !w.Author.MatchString(c.Author.Ident()):
+		return false
+	case w.Grep != nil && !w.Grep.MatchString(c.Msg):
+		return false
+	case !w.Since.IsZero() && c.Committer.When.Before(w.Since):
+		return false
+	case !w.Until.IsZero() && c.Committer.When.After(w.Until):
+		return false
+	}
+	return true
+}
+
+// ParseDate converts a date of --since and --until into a time relative to now. Accepted formats are
+// the ones of ParseExpire (e.g. "2.weeks.ago", "72h" and "2025-01-31"), "{n} {unit} ago",
+// "2025-01-31 15:04:05" and RFC 3339.
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateTime, s, now.Location()); err == nil {
+		return t, nil
+	}
+	if fields := strings.Fields(s); len(fields) == 3 && fields[2] == "ago" {
+		s = strings.Join(fields, ".")
+	}
+	if s == "never" || s == "all" {
+		return time.Time{}, fmt.Errorf("ParseDate: invalid date: %s", s)
+	}
+	t, err := ParseExpire(s, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ParseDate: %w", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,114 @@
+package data_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+// writes the commits of this history, where each is made an hour after the previous one:
+// -----------------
+// *   m     merge side
+// |\
+// | * b1    side (by Hanako)
+// * | a2
+// * | a1
+// |/
+// * base
+// -----------------
+func writeTestHistory(t *testing.T) {
+	t.Helper()
+
+	tmpDir := filepath.Join(data.PgitDirBase, data.ObjDirBase)
+	if err := os.MkdirAll(tmpDir, os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(tmpDir)) })
+	start := time.Unix(1700000000, 0)
+	taro := func(h int) data.Signature {
+		return data.NewSignature("Taro", "taro@example.com", start.Add(time.Duration(h)*time.Hour))
+	}
+	hanako := data.NewSignature("Hanako", "hanako@example.com", start.Add(3*time.Hour))
+	commits := map[string]*data.Commit{
+		"base": {TreeOid: "t", Author: taro(0), Committer: taro(0), Msg: "base"},
+		"a1":   {TreeOid: "t", Parent: "base", Author: taro(1), Committer: taro(1), Msg: "a1"},
+		"a2":   {TreeOid: "t", Parent: "a1", Author: taro(2), Committer: taro(2), Msg: "a2"},
+		"b1":   {TreeOid: "t", Parent: "base", Author: hanako, Committer: hanako, Msg: "side"},
+		"m":    {TreeOid: "t", Parent: "a2", MergeParents: []string{"b1"}, Author: taro(4), Committer: taro(4), Msg: "merge side"},
+	}
+	for oid, c := range commits {
+		if err := data.WriteFile(filepath.Join(tmpDir, oid), c.Encode()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRevWalk(t *testing.T) {
+	writeTestHistory(t)
+	tests := []struct {
+		desc string
+		set  func(w *data.RevWalk)
+		want []string
+	}{
+		{desc: "01_date order", set: func(w *data.RevWalk) {}, want: []string{"m", "b1", "a2", "a1", "base"}},
+		{desc: "02_topo order", set: func(w *data.RevWalk) { w.Order = data.OrderTopo }, want: []string{"m", "a2", "a1", "b1", "base"}},
+		{desc: "03_first parent", set: func(w *data.RevWalk) { w.FirstParent = true }, want: []string{"m", "a2", "a1", "base"}},
+		{desc: "04_exclude", set: func(w *data.RevWalk) { w.Exclude = []string{"a1"} }, want: []string{"m", "b1", "a2"}},
+		{desc: "05_merges", set: func(w *data.RevWalk) { w.MinParents = 2 }, want: []string{"m"}},
+		{desc: "06_no merges", set: func(w *data.RevWalk) { w.MaxParents = 1 }, want: []string{"b1", "a2", "a1", "base"}},
+		{desc: "07_reverse after max count", set: func(w *data.RevWalk) { w.Reverse, w.MaxCount = true, 2 }, want: []string{"b1", "m"}},
+		{desc: "08_author", set: func(w *data.RevWalk) { w.Author = regexp.MustCompile("hanako@") }, want: []string{"b1"}},
+		{desc: "09_grep", set: func(w *data.RevWalk) { w.Grep = regexp.MustCompile("^a") }, want: []string{"a2", "a1"}},
+		{
+			desc: "10_since and until",
+			set: func(w *data.RevWalk) {
+				w.Since, w.Until = time.Unix(1700000000, 0).Add(2*time.Hour), time.Unix(1700000000, 0).Add(3*time.Hour)
+			},
+			want: []string{"b1", "a2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			w := data.NewRevWalk()
+			w.Include = []string{"m"}
+			tt.set(w)
+
+			got, _, err := w.Walk()
+
+			if err != nil {
+				t.Errorf("error should be nil:\nerror:%s\n", err)
+			}
+			CmpStructs(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{value: "2 weeks ago", want: now.Add(-14 * 24 * time.Hour)},
+		{value: "3.days.ago", want: now.Add(-3 * 24 * time.Hour)},
+		{value: "2025-01-01", want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2025-01-01 10:20:30", want: time.Date(2025, 1, 1, 10, 20, 30, 0, time.UTC)},
+		{value: "2025-01-01T10:20:30Z", want: time.Date(2025, 1, 1, 10, 20, 30, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := data.ParseDate(tt.value, now)
+
+			if err != nil {
+				t.Errorf("error should be nil:\nerror:%s\n", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("should be equal: { got: %s, want: %s }", got, tt.want)
+			}
+		})
+	}
+}
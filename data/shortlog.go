@@ -0,0 +1,64 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// ShortlogOptions controls how the commits are grouped by author.
+type ShortlogOptions struct {
+	Email    bool //groups by "{name} <{email}>" instead of the name alone
+	Numbered bool //sorts the authors by the number of their commits instead of their names
+}
+
+// ShortlogGroup is the subjects of the commits by an author.
+type ShortlogGroup struct {
+	Author   string
+	Subjects []string
+}
+
+// Shortlog groups the subjects of the commits by their authors mapped by the mailmap,
+// keeping the order of the commits in each group.
+func Shortlog(commits []*Commit, mailmap Mailmap, opts *ShortlogOptions) []*ShortlogGroup {
+	byAuthor := make(map[string]*ShortlogGroup)
+	var groups []*ShortlogGroup
+	for _, c := range commits {
+		author := mailmap.Map(c.Author)
+		key := author.Name
+		if opts.Email {
+			key = author.Ident()
+		}
+		g, ok := byAuthor[key]
+		if !ok {
+			g = &ShortlogGroup{Author: key}
+			byAuthor[key] = g
+			groups = append(groups, g)
+		}
+		g.Subjects = append(g.Subjects, c.Subject())
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if opts.Numbered && len(groups[i].Subjects) != len(groups[j].Subjects) {
+			return len(groups[i].Subjects) > len(groups[j].Subjects)
+		}
+		return groups[i].Author < groups[j].Author
+	})
+	return groups
+}
+
+// WriteShortlog writes each author with the number of the commits, followed by their subjects
+// indented by six spaces and a blank line. With summary, only a line of the number and the author
+// is written for each author.
+func WriteShortlog(buf *bytes.Buffer, groups []*ShortlogGroup, summary bool) {
+	for _, g := range groups {
+		if summary {
+			fmt.Fprintf(buf, "%6d\t%s\n", len(g.Subjects), g.Author)
+			continue
+		}
+		fmt.Fprintf(buf, "%s (%d):\n", g.Author, len(g.Subjects))
+		for _, s := range g.Subjects {
+			fmt.Fprintf(buf, "      %s\n", s)
+		}
+		buf.WriteString("\n")
+	}
+}
@@ -0,0 +1,43 @@
+package data_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestShortlog(t *testing.T) {
+	when := time.Unix(1700000000, 0)
+	commit := func(name, email, msg string) *data.Commit {
+		return &data.Commit{Author: data.NewSignature(name, email, when), Msg: msg}
+	}
+	commits := []*data.Commit{
+		commit("Hanako", "hanako@example.com", "first\n\nbody"),
+		commit("Taro", "taro@example.com", "second"),
+		commit("taro", "taro@old.example.com", "third"),
+	}
+	mailmap := data.Mailmap{{Name: "Taro", Email: "taro@example.com", CommitEmail: "taro@old.example.com"}}
+	tests := []struct {
+		desc    string
+		opts    *data.ShortlogOptions
+		summary bool
+		want    string
+	}{
+		{desc: "01_by name", opts: &data.ShortlogOptions{}, want: "Hanako (1):\n      first\n\nTaro (2):\n      second\n      third\n\n"},
+		{desc: "02_numbered summary", opts: &data.ShortlogOptions{Numbered: true}, summary: true, want: "     2\tTaro\n     1\tHanako\n"},
+		{desc: "03_email", opts: &data.ShortlogOptions{Email: true}, summary: true, want: "     1\tHanako <hanako@example.com>\n     2\tTaro <taro@example.com>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			data.WriteShortlog(&buf, data.Shortlog(commits, mailmap, tt.opts), tt.summary)
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("shortlog should be equal: (got: %q, want: %q)", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package data_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+// returns the ed25519 private key made from the seed byte in PEM, and the keyring line trusting it for the principal
+func newTestKey(t *testing.T, seed byte, principal string) (pemKey []byte, keyringLine string) {
+	t.Helper()
+
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return pemKey, fmt.Sprintf("%s ed25519 %s\n", principal, base64.StdEncoding.EncodeToString(pub))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	pemKey, line := newTestKey(t, 1, "taro@example.com")
+	key, err := data.ParseSigningKey(pemKey)
+	if err != nil {
+		t.Fatalf("error should be nil: (error: %s)", err)
+	}
+	payload := []byte("object 0000\n\nmessage\n")
+	sig, err := data.Sign(key, payload)
+	if err != nil {
+		t.Fatalf("error should be nil: (error: %s)", err)
+	}
+	_, otherLine := newTestKey(t, 2, "hanako@example.com")
+
+	tests := []struct {
+		desc    string
+		keyring string
+		payload []byte
+		sig     string
+		want    string
+		wantErr error
+	}{
+		{desc: "01_good", keyring: "# comment\n" + otherLine + line, payload: payload, sig: sig, want: "taro@example.com"},
+		{desc: "02_unknown signer", keyring: otherLine, payload: payload, sig: sig, wantErr: data.ErrUnknownSigner},
+		{desc: "03_bad signature", keyring: line, payload: []byte("object 0000\n\ntampered\n"), sig: sig, wantErr: data.ErrBadSignature},
+		{desc: "04_no signature", keyring: line, payload: payload, wantErr: data.ErrNoSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			keyring, err := data.ParseKeyring([]byte(tt.keyring))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			check, err := keyring.Verify(tt.payload, tt.sig)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error should be %v: (got: %v)", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && check.Principal != tt.want {
+				t.Errorf("principal should be equal: (got: %s, want: %s)", check.Principal, tt.want)
+			}
+			if check != nil && check.Fingerprint != data.Fingerprint(key.Public().(ed25519.PublicKey)) {
+				t.Errorf("fingerprint should be of the signing key: (got: %s)", check.Fingerprint)
+			}
+		})
+	}
+	t.Run("05_ssh keys", func(t *testing.T) {
+		seed := bytes.Repeat([]byte{3}, ed25519.SeedSize)
+		sshKey := ed25519.NewKeyFromSeed(seed)
+		pub := sshWire([]byte("ssh-ed25519"), sshKey.Public().(ed25519.PublicKey))
+		//an unencrypted OpenSSH private key, whose padding makes the private section a multiple of 8 bytes
+		private := sshWire([]byte("ssh-ed25519"), sshKey.Public().(ed25519.PublicKey), sshKey, []byte("taro@example.com"))
+		private = append(bytes.Repeat([]byte{0, 0, 0, 7}, 2), private...)
+		for i := byte(1); len(private)%8 != 0; i++ {
+			private = append(private, i)
+		}
+		body := append([]byte("openssh-key-v1\x00"), sshWire([]byte("none"), []byte("none"), nil)...)
+		body = append(body, 0, 0, 0, 1)
+		body = append(body, sshWire(pub, private)...)
+		pemKey := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: body})
+		keyring, err := data.ParseKeyring(fmt.Appendf(nil, "taro@example.com namespaces=\"git\" ssh-ed25519 %s comment\n", base64.StdEncoding.EncodeToString(pub)))
+		if err != nil {
+			t.Fatalf("error should be nil: (error: %s)", err)
+		}
+
+		key, err := data.ParseSigningKey(pemKey)
+
+		if err != nil {
+			t.Fatalf("error should be nil: (error: %s)", err)
+		}
+		sig, err := data.Sign(key, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if check, err := keyring.Verify(payload, sig); err != nil || check.Principal != "taro@example.com" {
+			t.Errorf("signature should be good: (check: %+v, error: %v)", check, err)
+		}
+	})
+	t.Run("06_malformed keyring", func(t *testing.T) {
+		if _, err := data.ParseKeyring([]byte("taro@example.com rsa AAAA\n")); !errors.Is(err, data.ErrMalformedKey) {
+			t.Errorf("error should be ErrMalformedKey: (error: %v)", err)
+		}
+	})
+}
+
+// returns the strings in the SSH wire format (= uint32 of the length and the bytes for each)
+func sshWire(strs ...[]byte) []byte {
+	var b []byte
+	for _, s := range strs {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+		b = append(b, s...)
+	}
+	return b
+}
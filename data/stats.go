@@ -0,0 +1,335 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type ObjectCount struct {
+	Count       int   //number of objects
+	Size        int64 //bytes of objects
+	Garbage     int   //number of temporary files left by interrupted writes
+	GarbageSize int64 //bytes of garbage
+	LFSCount    int   //number of files in the large file store
+	LFSSize     int64 //bytes of the large file store
+}
+
+// Counts the objects in the storage and the files in the large file store next to it.
+func CountObjects(objDirPath string) (*ObjectCount, error) {
+	count := &ObjectCount{}
+	ents, err := os.ReadDir(objDirPath)
+	if err != nil {
+		return nil, fmt.Errorf("CountObjects: %w", err)
+	}
+	for _, ent := range ents {
+		if ent.IsDir() {
+			continue
+		}
+		fi, err := ent.Info()
+		if err != nil {
+			return nil, fmt.Errorf("CountObjects: %w", err)
+		}
+		if strings.HasPrefix(ent.Name(), "tmp_") {
+			count.Garbage++
+			count.GarbageSize += fi.Size()
+			continue
+		}
+		count.Count++
+		count.Size += fi.Size()
+	}
+	lfsEnts, err := os.ReadDir(lfsStoreDir(objDirPath))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("CountObjects: %w", err)
+	}
+	for _, ent := range lfsEnts {
+		fi, err := ent.Info()
+		if err != nil || ent.IsDir() {
+			continue
+		}
+		count.LFSCount++
+		count.LFSSize += fi.Size()
+	}
+	return count, nil
+}
+
+type TypeStat struct {
+	Count int
+	Bytes int64
+}
+
+type BlobStat struct {
+	Oid    string
+	Size   int64
+	Path   string //the path the blob was introduced with
+	Commit string //the commit that introduced the blob
+}
+
+type RepoStats struct {
+	ByType         map[string]*TypeStat //{ key: commit, tree, blob, tag }
+	Unreachable    TypeStat
+	LargestBlobs   []*BlobStat
+	BranchCommits  map[string]int //{ key: branch name, value: number of reachable commits }
+	MaxTreeDepth   int
+	MaxTreeFanOut  int
+	MeanTreeFanOut float64
+}
+
+type treeSummary struct {
+	depth int
+	blobs map[string]string //{ key: slash-separated path, value: blob oid }
+}
+
+type statsCollector struct {
+	objDirPath string
+	stats      *RepoStats
+	sizes      map[string]int64
+	trees      map[string]*treeSummary
+	typed      map[string]string //{ key: oid, value: objType }
+	fanOutSum  int
+}
+
+// Collects statistics of all the objects reachable from the refs together with unreachable ones.
+// refs is { key: ref name like "refs/heads/master", value: commit oid, or the oid of an annotated tag },
+// and top is the number of largest blobs to report.
+func CollectStats(objDirPath string, refs map[string]string, top int) (*RepoStats, error) {
+	c := &statsCollector{
+		objDirPath: objDirPath,
+		stats: &RepoStats{
+			ByType:        map[string]*TypeStat{ObjTypeCommit: {}, ObjTypeTree: {}, ObjTypeBlob: {}, ObjTypeTag: {}},
+			BranchCommits: make(map[string]int),
+		},
+		sizes: make(map[string]int64),
+		trees: make(map[string]*treeSummary),
+		typed: make(map[string]string),
+	}
+	if err := c.readSizes(); err != nil {
+		return nil, fmt.Errorf("CollectStats: %w", err)
+	}
+	peeled := make(map[string]string, len(refs))
+	roots := make([]string, 0, len(refs))
+	for name, oid := range refs {
+		oid, objType, err := c.peelTag(oid)
+		if err != nil {
+			return nil, fmt.Errorf("CollectStats: %w", err)
+		}
+		switch objType {
+		case "", ObjTypeCommit:
+			peeled[name] = oid
+			roots = append(roots, oid)
+		case ObjTypeTree:
+			if _, err := c.summarizeTree(oid); err != nil {
+				return nil, fmt.Errorf("CollectStats: %w", err)
+			}
+		default:
+			c.typed[oid] = objType
+		}
+	}
+	hist, err := c.readHistory(roots)
+	if err != nil {
+		return nil, fmt.Errorf("CollectStats: %w", err)
+	}
+	for name, oid := range peeled {
+		if branch, ok := strings.CutPrefix(name, RefDirBase+"/"+HeadDirBase+"/"); ok {
+			c.stats.BranchCommits[branch] = hist.countReachable(oid)
+		}
+	}
+	introduced := make(map[string]*BlobStat)
+	for _, oid := range hist.order {
+		summary, err := c.summarizeTree(hist.trees[oid])
+		if err != nil {
+			return nil, fmt.Errorf("CollectStats: %w", err)
+		}
+		for path, blobOid := range summary.blobs {
+			b, ok := introduced[blobOid]
+			if ok && (hist.gens[b.Commit] < hist.gens[oid] || (hist.gens[b.Commit] == hist.gens[oid] && b.Path <= path)) {
+				continue
+			}
+			introduced[blobOid] = &BlobStat{Oid: blobOid, Size: c.sizes[blobOid], Path: path, Commit: oid}
+		}
+	}
+	for oid, size := range c.sizes {
+		objType, ok := c.typed[oid]
+		if !ok {
+			c.stats.Unreachable.Count++
+			c.stats.Unreachable.Bytes += size
+			continue
+		}
+		c.stats.ByType[objType].Count++
+		c.stats.ByType[objType].Bytes += size
+	}
+	if n := c.stats.ByType[ObjTypeTree].Count; n > 0 {
+		c.stats.MeanTreeFanOut = float64(c.fanOutSum) / float64(n)
+	}
+	for _, b := range introduced {
+		c.stats.LargestBlobs = append(c.stats.LargestBlobs, b)
+	}
+	sort.Slice(c.stats.LargestBlobs, func(i, j int) bool {
+		bi, bj := c.stats.LargestBlobs[i], c.stats.LargestBlobs[j]
+		if bi.Size != bj.Size {
+			return bi.Size > bj.Size
+		}
+		return bi.Path < bj.Path
+	})
+	if len(c.stats.LargestBlobs) > top {
+		c.stats.LargestBlobs = c.stats.LargestBlobs[:top]
+	}
+	return c.stats, nil
+}
+
+func (c *statsCollector) readSizes() error {
+	ents, err := os.ReadDir(c.objDirPath)
+	if err != nil {
+		return fmt.Errorf("readSizes: %w", err)
+	}
+	for _, ent := range ents {
+		if ent.IsDir() || strings.HasPrefix(ent.Name(), "tmp_") {
+			continue
+		}
+		fi, err := ent.Info()
+		if err != nil {
+			return fmt.Errorf("readSizes: %w", err)
+		}
+		c.sizes[ent.Name()] = fi.Size()
+	}
+	return nil
+}
+
+// follows the annotated tags from the oid, recording them as tags, and returns the first object which is
+// not a tag with its type recorded in the tag (empty if the oid is not a tag)
+func (c *statsCollector) peelTag(oid string) (peeled string, objType string, err error) {
+	seen := make(map[string]bool)
+	for !seen[oid] {
+		seen[oid] = true
+		content, err := ReadAllFileContent(filepath.Join(c.objDirPath, oid))
+		if err != nil {
+			return "", "", fmt.Errorf("peelTag: %w", err)
+		}
+		if !isTagContent(content) {
+			return oid, objType, nil
+		}
+		t, err := ParseTag(content)
+		if err != nil {
+			return "", "", fmt.Errorf("peelTag: %w", err)
+		}
+		c.typed[oid] = ObjTypeTag
+		oid, objType = t.Object, t.Type
+	}
+	return "", "", fmt.Errorf("peelTag: tag loop at %s", oid)
+}
+
+type history struct {
+	order   []string            //all the reachable commits
+	parents map[string][]string //{ key: commit oid, value: parent oids }
+	trees   map[string]string   //{ key: commit oid, value: tree oid }
+	gens    map[string]int      //{ key: commit oid, value: the number of commits on the longest path to a root commit }
+}
+
+// walks the history from the roots, reading the header of each commit once
+func (c *statsCollector) readHistory(roots []string) (*history, error) {
+	h := &history{
+		parents: make(map[string][]string),
+		trees:   make(map[string]string),
+		gens:    make(map[string]int),
+	}
+	queue := append([]string{}, roots...)
+	for len(queue) > 0 {
+		oid := queue[0]
+		queue = queue[1:]
+		if _, ok := h.parents[oid]; ok || oid == "" {
+			continue
+		}
+		content, err := ReadAllFileContent(filepath.Join(c.objDirPath, oid))
+		if err != nil {
+			return nil, fmt.Errorf("readHistory: %w", err)
+		}
+		treeOid, ps, ok := parseCommitHeader(content)
+		if !ok {
+			return nil, fmt.Errorf("readHistory: %s is not a commit", oid)
+		}
+		h.parents[oid] = ps
+		h.trees[oid] = treeOid
+		c.typed[oid] = ObjTypeCommit
+		h.order = append(h.order, oid)
+		queue = append(queue, ps...)
+	}
+	var gen func(oid string) int
+	gen = func(oid string) int {
+		if g, ok := h.gens[oid]; ok {
+			return g
+		}
+		g := 0
+		for _, p := range h.parents[oid] {
+			g = max(g, gen(p)+1)
+		}
+		h.gens[oid] = g
+		return g
+	}
+	for _, oid := range h.order {
+		gen(oid)
+	}
+	return h, nil
+}
+
+// counts the commits reachable from the oid, including itself
+func (h *history) countReachable(oid string) int {
+	seen := make(map[string]bool)
+	queue := []string{oid}
+	for len(queue) > 0 {
+		oid := queue[0]
+		queue = queue[1:]
+		if seen[oid] {
+			continue
+		}
+		seen[oid] = true
+		queue = append(queue, h.parents[oid]...)
+	}
+	return len(seen)
+}
+
+// reads the tree recursively once, recording depth, fan-out and the blobs in it
+func (c *statsCollector) summarizeTree(oid string) (*treeSummary, error) {
+	if s, ok := c.trees[oid]; ok {
+		return s, nil
+	}
+	content, err := ReadAllFileContent(filepath.Join(c.objDirPath, oid))
+	if err != nil {
+		return nil, fmt.Errorf("summarizeTree: %w", err)
+	}
+	s := &treeSummary{depth: 1, blobs: make(map[string]string)}
+	c.typed[oid] = ObjTypeTree
+	fanOut := 0
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Split(bufio.ScanLines)
+	for sc.Scan() {
+		objType, entryOid, name, err := parseTreeLine(sc.Text())
+		if err != nil {
+			return nil, fmt.Errorf("summarizeTree: %w", err)
+		}
+		fanOut++
+		if objType == ObjTypeBlob {
+			s.blobs[name] = entryOid
+			if _, ok := c.typed[entryOid]; !ok {
+				c.typed[entryOid] = ObjTypeBlob
+			}
+			continue
+		}
+		child, err := c.summarizeTree(entryOid)
+		if err != nil {
+			return nil, err
+		}
+		s.depth = max(s.depth, child.depth+1)
+		for path, blobOid := range child.blobs {
+			s.blobs[name+"/"+path] = blobOid
+		}
+	}
+	c.trees[oid] = s
+	c.fanOutSum += fanOut
+	c.stats.MaxTreeFanOut = max(c.stats.MaxTreeFanOut, fanOut)
+	c.stats.MaxTreeDepth = max(c.stats.MaxTreeDepth, s.depth)
+	return s, nil
+}
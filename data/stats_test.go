@@ -0,0 +1,99 @@
+package data_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestCountObjects(t *testing.T) {
+	pgitDir, _, _, _ := newFsckRepo(t)
+	objDir := filepath.Join(pgitDir, data.ObjDirBase)
+	if err := data.WriteFile(filepath.Join(objDir, "tmp_obj_garbage"), []byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := data.CountObjects(objDir)
+
+	if err != nil {
+		t.Errorf("should be nil: \n{ error: %s }", err)
+	}
+	if got.Count != 3 || got.Garbage != 1 || got.GarbageSize != 7 {
+		t.Errorf("count should be equal: (got: %+v)", got)
+	}
+}
+
+func TestCollectStats(t *testing.T) {
+	pgitDir, commitOid, _, blobOid := newFsckRepo(t)
+	objDir := filepath.Join(pgitDir, data.ObjDirBase)
+	big := strings.Repeat("big content\n", 10)
+	bigOid, err := data.SaveBlobObj(objDir, []byte(big))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subTree, err := data.SaveBlobObj(objDir, fmt.Appendf(nil, "blob %s big.txt\n", bigOid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTree, err := data.SaveBlobObj(objDir, fmt.Appendf(nil, "blob %s file_01\ntree %s sub\n", blobOid, subTree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := data.SaveBlobObj(objDir, fmt.Appendf(nil, "tree %s\nparent %s\n\nsecond commit", rootTree, commitOid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.SaveBlobObj(objDir, []byte("unreachable")); err != nil {
+		t.Fatal(err)
+	}
+	refs := map[string]string{
+		"refs/heads/master": second,
+		"refs/heads/old":    commitOid,
+	}
+
+	got, err := data.CollectStats(objDir, refs, 1)
+
+	if err != nil {
+		t.Fatalf("should be nil: \n{ error: %s }", err)
+	}
+	CmpStructs(t, got.BranchCommits, map[string]int{"master": 2, "old": 1})
+	if got.ByType[data.ObjTypeCommit].Count != 2 || got.ByType[data.ObjTypeTree].Count != 3 || got.ByType[data.ObjTypeBlob].Count != 2 {
+		t.Errorf("counts by type should be equal: (commit: %+v, tree: %+v, blob: %+v)",
+			got.ByType[data.ObjTypeCommit], got.ByType[data.ObjTypeTree], got.ByType[data.ObjTypeBlob])
+	}
+	CmpStructs(t, got.Unreachable, data.TypeStat{Count: 1, Bytes: int64(len("unreachable"))})
+	CmpStructs(t, got.LargestBlobs, []*data.BlobStat{{Oid: bigOid, Size: int64(len(big)), Path: "sub/big.txt", Commit: second}})
+	if got.MaxTreeDepth != 2 || got.MaxTreeFanOut != 2 {
+		t.Errorf("tree shape should be equal: (depth: %d, fan-out: %d)", got.MaxTreeDepth, got.MaxTreeFanOut)
+	}
+}
+
+func TestCollectStatsWithTag(t *testing.T) {
+	pgitDir, commitOid, _, _ := newFsckRepo(t)
+	objDir := filepath.Join(pgitDir, data.ObjDirBase)
+	tag := &data.Tag{Object: commitOid, Type: data.ObjTypeCommit, Name: "v1", Tagger: data.NewSignature("Taro", "taro@example.com", time.Unix(1700000000, 0)), Msg: "release"}
+	tagOid, err := data.SaveBlobObj(objDir, tag.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := map[string]string{
+		"refs/heads/master": commitOid,
+		"refs/tags/v1":      tagOid,
+		"refs/tags/latest":  tagOid,
+	}
+
+	got, err := data.CollectStats(objDir, refs, 1)
+
+	if err != nil {
+		t.Fatalf("should be nil: \n{ error: %s }", err)
+	}
+	CmpStructs(t, got.BranchCommits, map[string]int{"master": 1})
+	CmpStructs(t, got.ByType[data.ObjTypeTag], &data.TypeStat{Count: 1, Bytes: int64(len(tag.Encode()))})
+	if got.ByType[data.ObjTypeCommit].Count != 1 || got.Unreachable.Count != 0 {
+		t.Errorf("counts should be equal: (commit: %+v, unreachable: %+v)", got.ByType[data.ObjTypeCommit], got.Unreachable)
+	}
+}
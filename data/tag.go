@@ -0,0 +1,150 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// the type of an annotated tag, which is saved in the same way as commits
+const ObjTypeTag = "tag"
+
+var ErrNotTag = errors.New("not a tag object")
+
+// Tag is an annotated tag object, written like this:
+// -----------------
+// object {oid}
+// type commit
+// tag v1.0
+// tagger Taro Pgit <taro@example.com> 1700000000 +0900
+//
+// message
+// -----------------
+// A signed tag has the signature block (see Sign) after the message.
+type Tag struct {
+	Object    string //the oid of the tagged object
+	Type      string //the type of the tagged object, like "commit"
+	Name      string
+	Tagger    Signature
+	Msg       string
+	Signature string //the signature block, empty if not signed
+}
+
+// NewTag makes the tag of the object, recording its type guessed from the content.
+func NewTag(object string, name string, tagger Signature, msg string) (*Tag, error) {
+	objType, err := guessObjType(filepath.Join(PgitDirBase, ObjDirBase, object))
+	if err != nil {
+		return nil, fmt.Errorf("NewTag: %w", err)
+	}
+	return &Tag{Object: object, Type: objType, Name: name, Tagger: tagger, Msg: msg}, nil
+}
+
+// Payload returns the content of the tag without the signature, which is what the signature is made for.
+func (t *Tag) Payload() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "object %s\n", t.Object)
+	fmt.Fprintf(&buf, "type %s\n", t.Type)
+	fmt.Fprintf(&buf, "%s %s\n", ObjTypeTag, t.Name)
+	fmt.Fprintf(&buf, "tagger %s\n", t.Tagger)
+	buf.WriteString("\n")
+	buf.WriteString(strings.TrimRight(t.Msg, "\n") + "\n")
+	return buf.Bytes()
+}
+
+// Encode returns the content of the tag object.
+func (t *Tag) Encode() []byte {
+	return append(t.Payload(), t.Signature...)
+}
+
+// Parses the content of a tag object.
+func ParseTag(content []byte) (*Tag, error) {
+	if !isTagContent(content) {
+		return nil, fmt.Errorf("ParseTag: %w", ErrNotTag)
+	}
+	t := &Tag{}
+	header, msg, _ := strings.Cut(string(content), "\n\n")
+	for _, line := range strings.Split(header, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "object":
+			t.Object = value
+		case "type":
+			t.Type = value
+		case ObjTypeTag:
+			t.Name = value
+		case "tagger":
+			sig, err := ParseSignature(value)
+			if err != nil {
+				return nil, fmt.Errorf("ParseTag: %w", err)
+			}
+			t.Tagger = sig
+		}
+	}
+	msg, t.Signature = SplitSignature(msg)
+	t.Msg = strings.TrimRight(msg, "\n")
+	return t, nil
+}
+
+// Read a content of a file (= .pgit/objects/{oid}), and convert it to Tag struct.
+func GetTag(oid string) (*Tag, error) {
+	b, err := ReadAllFileContent(filepath.Join(PgitDirBase, ObjDirBase, oid))
+	if err != nil {
+		return nil, fmt.Errorf("GetTag: %w", err)
+	}
+	t, err := ParseTag(b)
+	if err != nil {
+		return nil, fmt.Errorf("GetTag: %s: %w", oid, err)
+	}
+	return t, nil
+}
+
+// PeelTag follows the tag objects from the oid, and returns the first object which is not a tag,
+// with its type recorded in the tag. If the oid is not a tag, it is returned as it is with an empty type.
+func PeelTag(oid string) (peeled string, objType string, err error) {
+	seen := make(map[string]bool)
+	for !seen[oid] {
+		seen[oid] = true
+		ok, err := IsTagObject(oid)
+		if err != nil {
+			return "", "", fmt.Errorf("PeelTag: %w", err)
+		}
+		if !ok {
+			return oid, objType, nil
+		}
+		t, err := GetTag(oid)
+		if err != nil {
+			return "", "", fmt.Errorf("PeelTag: %w", err)
+		}
+		oid, objType = t.Object, t.Type
+	}
+	return "", "", fmt.Errorf("PeelTag: tag loop at %s", oid)
+}
+
+// IsTagObject reports whether the object (= .pgit/objects/{oid}) is a tag, reading only its first line.
+func IsTagObject(oid string) (bool, error) {
+	f, err := os.Open(filepath.Join(PgitDirBase, ObjDirBase, oid))
+	if err != nil {
+		return false, fmt.Errorf("IsTagObject: %w", err)
+	}
+	defer f.Close()
+	head := make([]byte, len("object ")+41)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("IsTagObject: %w", err)
+	}
+	return isTagContent(head[:n]), nil
+}
+
+// reports whether the content begins with "object {oid}\n"
+func isTagContent(content []byte) bool {
+	line, _, ok := bytes.Cut(content, []byte("\n"))
+	if !ok {
+		return false
+	}
+	oid, ok := bytes.CutPrefix(line, []byte("object "))
+	return ok && isOid(string(oid))
+}
@@ -0,0 +1,73 @@
+package data_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestTag(t *testing.T) {
+	tagger := data.NewSignature("Taro", "taro@example.com", time.Unix(1700000000, 0).In(time.FixedZone("", 9*60*60)))
+	t.Run("01_encode and parse", func(t *testing.T) {
+		pemKey, _ := newTestKey(t, 1, "taro@example.com")
+		key, err := data.ParseSigningKey(pemKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, signed := range []bool{false, true} {
+			tag := &data.Tag{Object: "1111111111111111111111111111111111111111", Type: data.ObjTypeCommit, Name: "v1.0", Tagger: tagger, Msg: "release 1.0\n\ndetails"}
+			if signed {
+				if tag.Signature, err = data.Sign(key, tag.Payload()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := data.ParseTag(tag.Encode())
+
+			if err != nil {
+				t.Fatalf("error should be nil: (error: %s)", err)
+			}
+			CmpStructs(t, got, tag)
+		}
+	})
+	t.Run("02_peel", func(t *testing.T) {
+		writeTestHistory(t)
+		objDir := filepath.Join(data.PgitDirBase, data.ObjDirBase)
+		commit := &data.Commit{TreeOid: "t", Author: tagger, Committer: tagger, Msg: "tagged"}
+		commitOid, err := data.SaveBlobObj(objDir, commit.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		inner := &data.Tag{Object: commitOid, Type: data.ObjTypeCommit, Name: "v1", Tagger: tagger, Msg: "v1"}
+		innerOid, err := data.SaveBlobObj(objDir, inner.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		outer := &data.Tag{Object: innerOid, Type: data.ObjTypeTag, Name: "v1-again", Tagger: tagger, Msg: "again"}
+		outerOid, err := data.SaveBlobObj(objDir, outer.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			desc     string
+			oid      string
+			want     string
+			wantType string
+		}{
+			{desc: "nested tags", oid: outerOid, want: commitOid, wantType: data.ObjTypeCommit},
+			{desc: "not a tag", oid: "a1", want: "a1", wantType: ""},
+		}
+		for _, tt := range tests {
+			got, gotType, err := data.PeelTag(tt.oid)
+
+			if err != nil {
+				t.Fatalf("%s: error should be nil: (error: %s)", tt.desc, err)
+			}
+			if got != tt.want || gotType != tt.wantType {
+				t.Errorf("%s: peeled should be equal: (got: %s %q, want: %s %q)", tt.desc, got, gotType, tt.want, tt.wantType)
+			}
+		}
+	})
+}
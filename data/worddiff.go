@@ -0,0 +1,168 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// WordDiffMode is the way changed words are marked in a word diff.
+type WordDiffMode string
+
+const (
+	//no word diff, but a line diff
+	WordDiffNone WordDiffMode = ""
+	//deleted words as "[-word-]" and inserted ones as "{+word+}"
+	WordDiffPlain WordDiffMode = "plain"
+	//deleted words in red and inserted ones in green, without markers
+	WordDiffColor WordDiffMode = "color"
+	//a line for each run of words, beginning with " ", "-" or "+", and "~" for each newline
+	WordDiffPorcelain WordDiffMode = "porcelain"
+)
+
+// the config key of the regular expression of words
+const ConfigDiffWordRegex = "diff.wordRegex"
+
+// a word is a run of non-whitespace characters unless a regular expression is given
+var defaultWordRegex = regexp.MustCompile(`\S+`)
+
+var ErrUnknownWordDiffMode = errors.New("unknown word diff mode")
+
+// Parses the word diff mode, where "none" means no word diff.
+func ParseWordDiffMode(s string) (WordDiffMode, error) {
+	switch m := WordDiffMode(strings.ToLower(s)); m {
+	case WordDiffPlain, WordDiffColor, WordDiffPorcelain:
+		return m, nil
+	case "none":
+		return WordDiffNone, nil
+	}
+	return "", fmt.Errorf("ParseWordDiffMode: %w: %s", ErrUnknownWordDiffMode, s)
+}
+
+// the position of a word in a text, text[start:end]
+type word struct {
+	start int
+	end   int
+}
+
+// returns the words in the text matched by o.WordRegex (or by the default), leaving out empty matches.
+// Anything between the words is regarded as whitespace.
+func (o *DiffOptions) splitWords(text string) []word {
+	re := o.WordRegex
+	if re == nil {
+		re = defaultWordRegex
+	}
+	var words []word
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		if loc[0] < loc[1] {
+			words = append(words, word{loc[0], loc[1]})
+		}
+	}
+	return words
+}
+
+// writes the hunks as a word diff in o.WordDiff mode. Each hunk is written with its header, followed
+// by the text of its new side in which the changed words are marked.
+func (o *DiffOptions) writeWordDiff(buf *bytes.Buffer, hunks []*Hunk) {
+	for _, h := range hunks {
+		buf.WriteString(o.Paint(ColorFrag, h.Header()) + "\n")
+		var from, to strings.Builder
+		for _, line := range h.Lines {
+			if line[0] != LineInsert {
+				from.WriteString(line[1:])
+			}
+			if line[0] != LineDelete {
+				to.WriteString(line[1:])
+			}
+		}
+		o.writeWords(buf, from.String(), to.String())
+		if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+			buf.WriteString("\n")
+		}
+	}
+}
+
+// compares the texts word by word, and writes the new text with the deleted and inserted words marked.
+// The whitespace between words is taken from the new text. A deletion alone is written right after
+// the word before it.
+func (o *DiffOptions) writeWords(buf *bytes.Buffer, from string, to string) {
+	aWords, bWords := o.splitWords(from), o.splitWords(to)
+	ids := make(map[string]int)
+	intern := func(text string, words []word) []int {
+		seq := make([]int, len(words))
+		for i, w := range words {
+			s := text[w.start:w.end]
+			id, ok := ids[s]
+			if !ok {
+				id = len(ids)
+				ids[s] = id
+			}
+			seq[i] = id
+		}
+		return seq
+	}
+	a, b := intern(from, aWords), intern(to, bWords)
+
+	cur, i, j := 0, 0, 0
+	for _, m := range o.matchSequences(a, b) {
+		if i < m.i || j < m.j {
+			var begin, end int
+			switch {
+			case j < m.j:
+				begin, end = bWords[j].start, bWords[m.j-1].end
+			case j > 0:
+				begin, end = bWords[j-1].end, bWords[j-1].end
+			}
+			o.writeWordRun(buf, LineContext, to[cur:begin])
+			if i < m.i {
+				o.writeWordRun(buf, LineDelete, from[aWords[i].start:aWords[m.i-1].end])
+			}
+			o.writeWordRun(buf, LineInsert, to[begin:end])
+			cur = end
+		}
+		i, j = m.i+1, m.j+1
+	}
+	o.writeWordRun(buf, LineContext, to[cur:])
+}
+
+// writes the text of unchanged (LineContext), deleted (LineDelete) or inserted (LineInsert) words
+// in o.WordDiff mode. Markers and colors are put on each line of the text separately.
+func (o *DiffOptions) writeWordRun(buf *bytes.Buffer, op byte, text string) {
+	if text == "" {
+		return
+	}
+	if o.WordDiff == WordDiffPorcelain {
+		for k, part := range strings.Split(text, "\n") {
+			if k > 0 {
+				buf.WriteString("~\n")
+			}
+			if part != "" {
+				buf.WriteString(string(op) + part + "\n")
+			}
+		}
+		return
+	}
+	if op == LineContext {
+		buf.WriteString(text)
+		return
+	}
+	color, openMark, closeMark := ColorOld, "[-", "-]"
+	if op == LineInsert {
+		color, openMark, closeMark = ColorNew, "{+", "+}"
+	}
+	for k, part := range strings.Split(text, "\n") {
+		if k > 0 {
+			buf.WriteString("\n")
+		}
+		if part == "" {
+			continue
+		}
+		if o.WordDiff == WordDiffColor {
+			buf.WriteString(Paint(color, part))
+			continue
+		}
+		buf.WriteString(o.Paint(color, openMark+part+closeMark))
+	}
+}
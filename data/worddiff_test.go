@@ -0,0 +1,71 @@
+package data_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/taimats/pgit/data"
+)
+
+func TestWordDiff(t *testing.T) {
+	const (
+		from = "the quick brown fox\njumps over\nthe lazy dog\n"
+		to   = "the slow brown fox\njumps  over\nthe dog!\nnew line\n"
+	)
+	tests := []struct {
+		desc  string
+		mode  data.WordDiffMode
+		regex string
+		want  string
+	}{
+		{
+			desc: "01_plain",
+			mode: data.WordDiffPlain,
+			want: "@@ -1,3 +1,4 @@\nthe [-quick-]{+slow+} brown fox\njumps  over\nthe [-lazy dog-]{+dog!+}\n{+new line+}\n",
+		},
+		{
+			desc: "02_color",
+			mode: data.WordDiffColor,
+			want: "@@ -1,3 +1,4 @@\nthe \x1b[31mquick\x1b[m\x1b[32mslow\x1b[m brown fox\njumps  over\n" +
+				"the \x1b[31mlazy dog\x1b[m\x1b[32mdog!\x1b[m\n\x1b[32mnew line\x1b[m\n",
+		},
+		{
+			desc: "03_porcelain",
+			mode: data.WordDiffPorcelain,
+			want: "@@ -1,3 +1,4 @@\n the \n-quick\n+slow\n  brown fox\n~\n jumps  over\n~\n" +
+				" the \n-lazy dog\n+dog!\n~\n+new line\n~\n",
+		},
+		{
+			desc:  "04_word regex",
+			mode:  data.WordDiffPlain,
+			regex: `[a-z]+|[^[:space:]]`,
+			want:  "@@ -1,3 +1,4 @@\nthe [-quick-]{+slow+} brown fox\njumps  over\nthe[-lazy-] dog{+!+}\n{+new line+}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			opts := data.NewDiffOptions()
+			opts.WordDiff = tt.mode
+			if tt.regex != "" {
+				opts.WordRegex = regexp.MustCompile(tt.regex)
+			}
+
+			var buf bytes.Buffer
+			opts.WriteHunks(&buf, opts.DiffHunks([]byte(from), []byte(to)))
+
+			CmpStructs(t, buf.String(), tt.want)
+		})
+	}
+}
+
+func TestWriteHunksColor(t *testing.T) {
+	opts := data.NewDiffOptions()
+	opts.Color = true
+
+	var buf bytes.Buffer
+	opts.WriteHunks(&buf, opts.DiffHunks([]byte("a\nb\n"), []byte("a\nc\n")))
+
+	want := "\x1b[36m@@ -1,2 +1,2 @@\x1b[m\n a\n\x1b[31m-b\x1b[m\n\x1b[32m+c\x1b[m\n"
+	CmpStructs(t, buf.String(), want)
+}